@@ -1,12 +1,24 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"reflect"
+	"syscall"
+	"time"
 
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/middlewares"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/utils"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/jobs"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/migrations"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/websocket"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -18,10 +30,112 @@ type HttpAppConfig struct {
 	AppPort          string                                        // App port number config in string, default is port 1234
 	APIBasePrefixUrl string                                        // Custom base api prefix, default /api
 	Routes           func(*echo.Group, *externals.AllAppExternals) // Collection of echo.Echo routes
+	SyncIndexes      bool                                          // Create indexes declared by models and resource repos on boot, default is false
+	AutoMigrate      bool                                          // Apply pending migrations on boot, default is false
+	MigrationsDir    string                                        // Directory for `migrate create`, default is migrations
+	MigrationWait    time.Duration                                 // How long AutoMigrate waits for migrations running on another instance before failing boot, default is 10 minutes
+	FixturesDir      string                                        // Directory of JSON/YAML fixtures loaded by `seed`, default is fixtures
+	Tasks            []jobs.Task                                   // Scheduled tasks run by the jobs.Queue external, see jobs.Schedule
 }
 
 // Initialize http web server using echo.Echo
 func InitHttpApp(config *HttpAppConfig) *echo.Echo {
+	if config.AutoMigrate {
+		if err := runMigrations(config); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
+	e := newHttpApp(config)
+
+	if config.SyncIndexes {
+		if err := repo.SyncAllIndexes(); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
+	for _, task := range config.Tasks {
+		jobs.Schedule(task)
+	}
+
+	// Start job workers and scheduled tasks when queue is registered as external
+	if queue, err := externals.GetExternal[*jobs.Queue](config.Externals); err == nil {
+		if err := queue.Start(config.Externals); err != nil {
+			log.Fatalf("%v", err)
+		}
+	} else if jobs.HasScheduledTasks() {
+		log.Fatalf("scheduled tasks need a jobs.Queue external")
+	}
+
+	// Printing routes
+	utils.PrintRoutes(e)
+
+	port := config.AppPort
+	if port == "" {
+		port = "1234"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err := e.Start(fmt.Sprintf(":%s", port)); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdown(e, config)
+
+	return e
+}
+
+// Gracefully stop serving, websocket clients are disconnected first as hijacked connections are not tracked by the server
+func shutdown(e *echo.Echo, config *HttpAppConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	log.Println("Shutting down...")
+
+	if err := externals.ShutdownExternals(ctx, config.Externals); err != nil {
+		log.Printf("%v", err)
+	}
+
+	if err := e.Shutdown(ctx); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// Apply pending migrations, replica not holding the lock waits for the one migrating so it never serves an outdated schema
+func runMigrations(config *HttpAppConfig) error {
+	migrator, err := migrations.NewMigrator(config.Externals)
+
+	if err != nil {
+		return err
+	}
+
+	wait := config.MigrationWait
+	if wait <= 0 {
+		wait = 10 * time.Minute
+	}
+
+	applied, err := migrator.Up(context.Background())
+
+	if errors.Is(err, migrations.ErrLocked) {
+		log.Println("Migrations are running on another instance, waiting.")
+		applied, err = migrator.UpWhenUnlocked(context.Background(), wait)
+	}
+
+	for _, migration := range applied {
+		log.Printf("Migrated %d_%s", migration.Version, migration.Name)
+	}
+
+	return err
+}
+
+// Setup echo.Echo with middlewares and app routes registered, without serving
+func newHttpApp(config *HttpAppConfig) *echo.Echo {
 	e := echo.New()
 
 	e.HideBanner = true
@@ -49,9 +163,29 @@ func InitHttpApp(config *HttpAppConfig) *echo.Echo {
 	// Update enhanced error handler
 	e.HTTPErrorHandler = middlewares.CustomHTTPErrorHandler
 
-	// WIP enable websocket
-	// e.GET("/ws", websocket.HandleWebSocket)
-	// e.GET("/ws/:namespace", websocket.HandleWebSocket)
+	// Enable websocket when hub is registered as external
+	if hub, err := externals.GetExternal[*websocket.Hub](config.Externals); err == nil {
+		var wsMiddlewares []echo.MiddlewareFunc
+
+		if hub.RequireAuth() {
+			wsMiddlewares = append(wsMiddlewares, middlewares.Auth(config.Externals))
+		}
+
+		hub.Validator = e.Validator
+		hub.ErrorHandler = middlewares.WSErrorHandler
+
+		e.GET("/ws", hub.Handle, wsMiddlewares...)
+		e.GET("/ws/:namespace", hub.Handle, wsMiddlewares...)
+		e.GET("/ws/:namespace/members", hub.HandleMembers, wsMiddlewares...)
+		e.GET("/ws/:namespace/rooms/:room/members", hub.HandleMembers, wsMiddlewares...)
+		e.GET("/events", hub.HandleEvents, wsMiddlewares...)
+
+		if hub.SocketIOEnabled() {
+			// Preflight requests carry no credentials
+			e.OPTIONS("/socket.io/", hub.HandleSocketIO)
+			e.Match([]string{http.MethodGet, http.MethodPost}, "/socket.io/", hub.HandleSocketIO, wsMiddlewares...)
+		}
+	}
 
 	var router *echo.Group
 
@@ -65,14 +199,5 @@ func InitHttpApp(config *HttpAppConfig) *echo.Echo {
 	// Registing app routes
 	config.Routes(router, config.Externals)
 
-	// Printing routes
-	utils.PrintRoutes(e)
-
-	if config.AppPort != "" {
-		e.Logger.Fatal(e.Start(fmt.Sprintf(":%s", config.AppPort)))
-	} else {
-		e.Logger.Fatal(e.Start(":1234"))
-	}
-
 	return e
 }
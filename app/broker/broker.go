@@ -0,0 +1,223 @@
+package broker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+)
+
+var ErrAlreadySettled = errors.New("delivery is already acked or nacked")
+
+// Header carrying Message.ID where brokers lack a message id field
+const messageIDHeader = "x-message-id"
+
+type Message struct {
+	ID          string            `json:"id"`                // Set by Publish when empty
+	Topic       string            `json:"topic"`             // Set by Publish
+	Key         string            `json:"key,omitempty"`     // Messages sharing it keep their order on Kafka (partition key), routing key on RabbitMQ
+	Headers     map[string]string `json:"headers,omitempty"` // Metadata e.g: trace id, content type
+	Body        []byte            `json:"body"`
+	PublishedAt time.Time         `json:"publishedAt"` // Set by Publish
+}
+
+// Message received by a consumer group, settled once with Ack or Nack
+type Delivery struct {
+	Message
+	Group       string
+	Redelivered bool // Nacked with requeue or left unacked by a crashed consumer before
+	ack         func() error
+	nack        func(requeue bool) error
+	settled     bool
+	mutex       sync.Mutex
+}
+
+// Handler of delivery, acked when it returns nil and nacked with requeue on error unless settled by the handler
+type Handler func(ctx context.Context, delivery *Delivery) error
+
+// Message broker for communication between services, every consumer group receives each message, consumers of a group share them.
+// Groups receive messages published after their first Subscribe, register implementations with externals.RegisterExternals.
+type Broker interface {
+	externals.BaseExternal
+	externals.ShutdownExternal
+	Publish(ctx context.Context, topic string, message Message) error
+	Subscribe(ctx context.Context, topic string, group string, handler Handler) error // Start delivering messages of topic to handler one at a time until ctx is done, subscribe again for concurrent consumers
+}
+
+// Registered broker external e.g: broker.FromExternals(appExternals) within routes
+func FromExternals(appExternals *externals.AllAppExternals) (Broker, error) {
+	return externals.GetExternal[Broker](appExternals)
+}
+
+func (d *Delivery) Ack() error {
+	return d.settle(d.ack)
+}
+
+// Nack delivery, redelivered to the group when requeue is true, otherwise dropped
+func (d *Delivery) Nack(requeue bool) error {
+	return d.settle(func() error {
+		return d.nack(requeue)
+	})
+}
+
+func (d *Delivery) settle(fn func() error) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.settled {
+		return ErrAlreadySettled
+	}
+
+	d.settled = true
+
+	return fn()
+}
+
+// Decode JSON body into v
+func (d *Delivery) Bind(v any) error {
+	return json.Unmarshal(d.Body, v)
+}
+
+// Publish payload encoded as JSON
+func PublishJSON(ctx context.Context, broker Broker, topic string, payload any, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	return broker.Publish(ctx, topic, Message{Headers: headers, Body: body})
+}
+
+// Handler of message with JSON body decoded into T e.g: broker.Typed(func(ctx context.Context, delivery *broker.Delivery, payload UserCreated) error {...})
+func Typed[T any](handler func(ctx context.Context, delivery *Delivery, payload T) error) Handler {
+	return func(ctx context.Context, delivery *Delivery) error {
+		var payload T
+
+		if err := delivery.Bind(&payload); err != nil {
+			// Redelivering cannot fix the body
+			nackErr := delivery.Nack(false)
+
+			return errors.Join(fmt.Errorf("invalid body of message %s: %w", delivery.ID, err), nackErr)
+		}
+
+		return handler(ctx, delivery, payload)
+	}
+}
+
+// Fill fields set by Publish
+func prepare(topic string, message Message) Message {
+	if message.ID == "" {
+		message.ID = randomID()
+	}
+
+	message.Topic = topic
+	message.PublishedAt = time.Now()
+
+	return message
+}
+
+func randomID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+
+	return hex.EncodeToString(bytes)
+}
+
+// Consumers of a broker, stopped together on shutdown
+type consumers struct {
+	stopCtx        context.Context
+	stop           context.CancelFunc // Stops receiving
+	handlersCtx    context.Context
+	cancelHandlers context.CancelFunc // Cancels running handlers when shutdown times out
+	running        sync.WaitGroup
+}
+
+func newConsumers() *consumers {
+	c := &consumers{}
+
+	c.stopCtx, c.stop = context.WithCancel(context.Background())
+	c.handlersCtx, c.cancelHandlers = context.WithCancel(context.Background())
+
+	return c
+}
+
+// Run consumer loop until ctx is done or consumers are stopped
+func (c *consumers) start(ctx context.Context, consume func(ctx context.Context)) error {
+	if c.stopCtx.Err() != nil {
+		return errors.New("broker is shut down")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stopAfter := context.AfterFunc(c.stopCtx, cancel)
+
+	c.running.Add(1)
+
+	go func() {
+		defer c.running.Done()
+		defer cancel()
+		defer stopAfter()
+
+		consume(ctx)
+	}()
+
+	return nil
+}
+
+// Run handler and settle delivery it left unsettled
+func (c *consumers) handle(handler Handler, delivery *Delivery) {
+	err := runHandler(handler, c.handlersCtx, delivery)
+
+	if err != nil {
+		log.Printf("Message %s of topic %s failed in group %s: %v\n", delivery.ID, delivery.Topic, delivery.Group, err)
+	}
+
+	var settleErr error
+
+	if err == nil {
+		settleErr = delivery.Ack()
+	} else {
+		settleErr = delivery.Nack(true)
+	}
+
+	if settleErr != nil && !errors.Is(settleErr, ErrAlreadySettled) {
+		log.Printf("Message %s of topic %s not settled: %v\n", delivery.ID, delivery.Topic, settleErr)
+	}
+}
+
+func runHandler(handler Handler, ctx context.Context, delivery *Delivery) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("handler of topic %s panicked: %v", delivery.Topic, recovered)
+		}
+	}()
+
+	return handler(ctx, delivery)
+}
+
+// Stop receiving and wait for running handlers, handlers still running when ctx is done are cancelled
+func (c *consumers) shutdown(ctx context.Context) error {
+	c.stop()
+
+	finished := make(chan struct{})
+
+	go func() {
+		c.running.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		c.cancelHandlers()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,195 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/utils"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Broker over Apache Kafka, topics map to Kafka topics and groups to Kafka consumer groups.
+// Nack with requeue redelivers the message before the next one of its partition, keeping their order.
+type KafkaBroker struct {
+	Client       *kafka.Client
+	Brokers      []string      // Bootstrap brokers e.g: localhost:9092
+	RetryBackoff time.Duration // Delay before redelivering nacked message, default is 1s
+	writer       *kafka.Writer
+	consumers    *consumers
+}
+
+// Using comma separated KAFKA_BROKERS env when brokers are left empty
+func NewKafkaBroker(brokers ...string) *KafkaBroker {
+	return &KafkaBroker{Brokers: brokers, RetryBackoff: time.Second, consumers: newConsumers()}
+}
+
+func (b *KafkaBroker) Connect() (*kafka.Client, error) {
+	if len(b.Brokers) == 0 && utils.GetAppConfig("KAFKA_BROKERS") != "" {
+		b.Brokers = strings.Split(utils.GetAppConfig("KAFKA_BROKERS"), ",")
+	}
+
+	if len(b.Brokers) == 0 {
+		return nil, fmt.Errorf("KAFKA_BROKERS is missing or unset")
+	}
+
+	b.Client = &kafka.Client{Addr: kafka.TCP(b.Brokers...), Timeout: 10 * time.Second}
+	b.writer = &kafka.Writer{
+		Addr:                   kafka.TCP(b.Brokers...),
+		Balancer:               &kafka.Hash{}, // Same key, same partition
+		RequiredAcks:           kafka.RequireAll,
+		BatchTimeout:           10 * time.Millisecond,
+		AllowAutoTopicCreation: true,
+	}
+
+	return b.Client, nil
+}
+
+func (b *KafkaBroker) ConnectRaw() error {
+	_, err := b.Connect()
+
+	return err
+}
+
+func (b *KafkaBroker) Healthcheck() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := b.Client.Metadata(ctx, &kafka.MetadataRequest{})
+
+	return err
+}
+
+func (b *KafkaBroker) SuccessMessage() string {
+	return "Kafka connected."
+}
+
+func (b *KafkaBroker) Shutdown(ctx context.Context) error {
+	err := b.consumers.shutdown(ctx)
+
+	// Flushes pending writes
+	if b.writer != nil {
+		err = errors.Join(err, b.writer.Close())
+	}
+
+	if err != nil {
+		return fmt.Errorf("kafka broker shutdown: %w", err)
+	}
+
+	return nil
+}
+
+func (b *KafkaBroker) Publish(ctx context.Context, topic string, message Message) error {
+	message = prepare(topic, message)
+
+	headers := []kafka.Header{{Key: messageIDHeader, Value: []byte(message.ID)}}
+	for name, value := range message.Headers {
+		headers = append(headers, kafka.Header{Key: name, Value: []byte(value)})
+	}
+
+	return b.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   topic,
+		Key:     []byte(message.Key),
+		Value:   message.Body,
+		Headers: headers,
+		Time:    message.PublishedAt,
+	})
+}
+
+func (b *KafkaBroker) Subscribe(ctx context.Context, topic string, group string, handler Handler) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     b.Brokers,
+		GroupID:     group,
+		Topic:       topic,
+		StartOffset: kafka.LastOffset, // New group receives messages published from now on
+		MaxWait:     time.Second,
+	})
+
+	return b.consumers.start(ctx, func(ctx context.Context) {
+		defer reader.Close()
+
+		for {
+			received, err := reader.FetchMessage(ctx)
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				log.Printf("Kafka topic %s read error: %v\n", topic, err)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+
+				continue
+			}
+
+			b.deliver(ctx, reader, group, received, handler)
+		}
+	})
+}
+
+// Handle message until it is acked or nacked without requeue, offset is committed then
+func (b *KafkaBroker) deliver(ctx context.Context, reader *kafka.Reader, group string, received kafka.Message, handler Handler) {
+	message := Message{
+		Topic:       received.Topic,
+		Key:         string(received.Key),
+		Headers:     map[string]string{},
+		Body:        received.Value,
+		PublishedAt: received.Time,
+	}
+
+	for _, header := range received.Headers {
+		if header.Key == messageIDHeader {
+			message.ID = string(header.Value)
+		} else {
+			message.Headers[header.Key] = string(header.Value)
+		}
+	}
+
+	commit := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		return reader.CommitMessages(ctx, received)
+	}
+
+	for redelivered := false; ; redelivered = true {
+		requeued := false
+
+		delivery := &Delivery{Message: message, Group: group, Redelivered: redelivered}
+		delivery.ack = commit
+		delivery.nack = func(requeue bool) error {
+			if requeue {
+				requeued = true
+				return nil
+			}
+
+			return commit()
+		}
+
+		b.consumers.handle(handler, delivery)
+
+		// Left uncommitted on shutdown, delivered again to the group
+		if !requeued {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.RetryBackoff):
+		}
+	}
+}
+
+var _ Broker = (*KafkaBroker)(nil)
+var _ externals.External[*kafka.Client] = (*KafkaBroker)(nil)
@@ -0,0 +1,143 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+)
+
+// In-process broker, messages are lost on restart, lets services and tests run without a broker installed
+type MemoryBroker struct {
+	groups    map[string]map[string]*memoryGroup // Consumer groups by topic
+	mutex     sync.Mutex
+	consumers *consumers
+}
+
+type memoryGroup struct {
+	queue  []memoryEntry
+	wakeup chan struct{} // Closed when a message is queued
+}
+
+type memoryEntry struct {
+	message     Message
+	redelivered bool
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{groups: make(map[string]map[string]*memoryGroup), consumers: newConsumers()}
+}
+
+func (b *MemoryBroker) Connect() (*MemoryBroker, error) {
+	return b, nil
+}
+
+func (b *MemoryBroker) ConnectRaw() error {
+	_, err := b.Connect()
+
+	return err
+}
+
+func (b *MemoryBroker) Healthcheck() error {
+	return nil
+}
+
+func (b *MemoryBroker) SuccessMessage() string {
+	return "In-memory message broker ready."
+}
+
+func (b *MemoryBroker) Shutdown(ctx context.Context) error {
+	if err := b.consumers.shutdown(ctx); err != nil {
+		return fmt.Errorf("memory broker shutdown: %w", err)
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, message Message) error {
+	message = prepare(topic, message)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, group := range b.groups[topic] {
+		group.push(memoryEntry{message: message})
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, topic string, group string, handler Handler) error {
+	b.mutex.Lock()
+
+	if b.groups[topic] == nil {
+		b.groups[topic] = make(map[string]*memoryGroup)
+	}
+
+	g, ok := b.groups[topic][group]
+
+	if !ok {
+		g = &memoryGroup{wakeup: make(chan struct{})}
+		b.groups[topic][group] = g
+	}
+
+	b.mutex.Unlock()
+
+	return b.consumers.start(ctx, func(ctx context.Context) {
+		for {
+			entry, wakeup, ok := b.pop(g)
+
+			if !ok {
+				select {
+				case <-ctx.Done():
+					return
+				case <-wakeup:
+					continue
+				}
+			}
+
+			delivery := &Delivery{Message: entry.message, Group: group, Redelivered: entry.redelivered}
+			delivery.ack = func() error {
+				return nil
+			}
+			delivery.nack = func(requeue bool) error {
+				if requeue {
+					b.mutex.Lock()
+					g.push(memoryEntry{message: entry.message, redelivered: true})
+					b.mutex.Unlock()
+				}
+
+				return nil
+			}
+
+			b.consumers.handle(handler, delivery)
+		}
+	})
+}
+
+// Oldest queued message of group, otherwise channel closed once one is queued
+func (b *MemoryBroker) pop(g *memoryGroup) (memoryEntry, chan struct{}, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(g.queue) == 0 {
+		return memoryEntry{}, g.wakeup, false
+	}
+
+	entry := g.queue[0]
+	g.queue = g.queue[1:]
+
+	return entry, nil, true
+}
+
+// Queue message and wake consumers of group, called with broker mutex held
+func (g *memoryGroup) push(entry memoryEntry) {
+	g.queue = append(g.queue, entry)
+
+	close(g.wakeup)
+	g.wakeup = make(chan struct{})
+}
+
+var _ Broker = (*MemoryBroker)(nil)
+var _ externals.External[*MemoryBroker] = (*MemoryBroker)(nil)
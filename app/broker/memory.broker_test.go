@@ -0,0 +1,312 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testTimeout = 2 * time.Second
+
+type received struct {
+	group       string
+	body        string
+	redelivered bool
+}
+
+// Subscribe handler of group sending what it receives, stopped with the test
+func subscribeTest(t *testing.T, b *MemoryBroker, topic string, group string, handler Handler) <-chan received {
+	t.Helper()
+
+	deliveries := make(chan received, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	err := b.Subscribe(ctx, topic, group, func(ctx context.Context, delivery *Delivery) error {
+		deliveries <- received{group: delivery.Group, body: string(delivery.Body), redelivered: delivery.Redelivered}
+
+		if handler == nil {
+			return nil
+		}
+
+		return handler(ctx, delivery)
+	})
+
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	return deliveries
+}
+
+func publishTest(t *testing.T, b *MemoryBroker, topic string, bodies ...string) {
+	t.Helper()
+
+	for _, body := range bodies {
+		if err := b.Publish(context.Background(), topic, Message{Body: []byte(body)}); err != nil {
+			t.Fatalf("publish %s: %v", body, err)
+		}
+	}
+}
+
+// Wait for count deliveries
+func receive(t *testing.T, deliveries <-chan received, count int) []received {
+	t.Helper()
+
+	var all []received
+
+	for len(all) < count {
+		select {
+		case delivery := <-deliveries:
+			all = append(all, delivery)
+		case <-time.After(testTimeout):
+			t.Fatalf("got %d deliveries, want %d: %v", len(all), count, all)
+		}
+	}
+
+	return all
+}
+
+// Receive deliveries until the one of body
+func receiveUntil(t *testing.T, deliveries <-chan received, body string) []received {
+	t.Helper()
+
+	var all []received
+
+	for {
+		all = append(all, receive(t, deliveries, 1)...)
+
+		if all[len(all)-1].body == body {
+			return all
+		}
+	}
+}
+
+func bodies(deliveries []received) []string {
+	var all []string
+
+	for _, delivery := range deliveries {
+		all = append(all, delivery.body)
+	}
+
+	return all
+}
+
+func TestMemoryBrokerGroups(t *testing.T) {
+	b := NewMemoryBroker()
+	defer b.Shutdown(context.Background())
+
+	publishTest(t, b, "orders", "before")
+
+	billing := subscribeTest(t, b, "orders", "billing", nil)
+	shipping := subscribeTest(t, b, "orders", "shipping", nil)
+	others := subscribeTest(t, b, "users", "billing", nil)
+
+	publishTest(t, b, "orders", "a", "b")
+
+	for _, deliveries := range []<-chan received{billing, shipping} {
+		// Groups only receive messages published after their first subscribe
+		if got := bodies(receive(t, deliveries, 2)); !slices.Equal(got, []string{"a", "b"}) {
+			t.Errorf("got %v, want [a b]", got)
+		}
+	}
+
+	publishTest(t, b, "users", "u")
+
+	if got := bodies(receive(t, others, 1)); !slices.Equal(got, []string{"u"}) {
+		t.Errorf("other topic: got %v, want [u]", got)
+	}
+}
+
+func TestMemoryBrokerSharedGroup(t *testing.T) {
+	b := NewMemoryBroker()
+	defer b.Shutdown(context.Background())
+
+	var (
+		mutex sync.Mutex
+		seen  []string
+	)
+
+	consumer := func(ctx context.Context, delivery *Delivery) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		seen = append(seen, string(delivery.Body))
+		return nil
+	}
+
+	first := subscribeTest(t, b, "orders", "billing", consumer)
+	second := subscribeTest(t, b, "orders", "billing", consumer)
+
+	want := []string{"1", "2", "3", "4", "5", "6"}
+	publishTest(t, b, "orders", want...)
+
+	deadline := time.After(testTimeout)
+
+	for count := 0; count < len(want); count++ {
+		select {
+		case <-first:
+		case <-second:
+		case <-deadline:
+			t.Fatalf("got %d deliveries, want %d", count, len(want))
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// Every message is handled by exactly one consumer of the group
+	sort.Strings(seen)
+
+	if !slices.Equal(seen, want) {
+		t.Errorf("got %v, want %v", seen, want)
+	}
+}
+
+func TestMemoryBrokerSettle(t *testing.T) {
+	tests := []struct {
+		name      string
+		handle    func(delivery *Delivery) error
+		want      []string
+		wantRetry bool
+	}{
+		{"acked on success", func(delivery *Delivery) error { return nil }, []string{"a", "sentinel", "end"}, false},
+		{"requeued on error", func(delivery *Delivery) error { return errors.New("boom") }, []string{"a", "sentinel", "a", "end"}, true},
+		{"requeued on panic", func(delivery *Delivery) error { panic("boom") }, []string{"a", "sentinel", "a", "end"}, true},
+		{"acked by handler despite error", func(delivery *Delivery) error {
+			return errors.Join(delivery.Ack(), errors.New("boom"))
+		}, []string{"a", "sentinel", "end"}, false},
+		{"nacked without requeue", func(delivery *Delivery) error {
+			return errors.Join(delivery.Nack(false), errors.New("boom"))
+		}, []string{"a", "sentinel", "end"}, false},
+		{"nacked with requeue", func(delivery *Delivery) error { return delivery.Nack(true) }, []string{"a", "sentinel", "a", "end"}, true},
+		{"invalid body of typed handler is dropped", func(delivery *Delivery) error {
+			return Typed(func(ctx context.Context, delivery *Delivery, payload map[string]any) error { return nil })(context.Background(), delivery)
+		}, []string{"a", "sentinel", "end"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := NewMemoryBroker()
+			defer b.Shutdown(context.Background())
+
+			deliveries := subscribeTest(t, b, "orders", "billing", func(ctx context.Context, delivery *Delivery) error {
+				switch {
+				case string(delivery.Body) == "sentinel":
+					// Queued after "a" when it was requeued, so "end" is the last delivery either way
+					return b.Publish(ctx, "orders", Message{Body: []byte("end")})
+				case string(delivery.Body) == "a" && !delivery.Redelivered:
+					return test.handle(delivery)
+				default:
+					return nil
+				}
+			})
+
+			publishTest(t, b, "orders", "a", "sentinel")
+
+			all := receiveUntil(t, deliveries, "end")
+
+			if got := bodies(all); !slices.Equal(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+
+			if test.wantRetry && !all[2].redelivered {
+				t.Errorf("requeued delivery is not marked redelivered")
+			}
+		})
+	}
+}
+
+func TestDeliverySettledOnce(t *testing.T) {
+	acks, nacks := 0, 0
+
+	delivery := &Delivery{
+		ack:  func() error { acks++; return nil },
+		nack: func(requeue bool) error { nacks++; return nil },
+	}
+
+	if err := delivery.Ack(); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+
+	if err := delivery.Ack(); !errors.Is(err, ErrAlreadySettled) {
+		t.Errorf("second ack: got %v, want %v", err, ErrAlreadySettled)
+	}
+
+	if err := delivery.Nack(true); !errors.Is(err, ErrAlreadySettled) {
+		t.Errorf("nack after ack: got %v, want %v", err, ErrAlreadySettled)
+	}
+
+	if acks != 1 || nacks != 0 {
+		t.Errorf("got %d acks and %d nacks, want 1 and 0", acks, nacks)
+	}
+}
+
+func TestMemoryBrokerJSON(t *testing.T) {
+	type orderCreated struct {
+		OrderID string `json:"orderId"`
+		Total   int    `json:"total"`
+	}
+
+	b := NewMemoryBroker()
+	defer b.Shutdown(context.Background())
+
+	messages := make(chan *Delivery, 1)
+	payloads := make(chan orderCreated, 1)
+
+	subscribeTest(t, b, "orders", "billing", Typed(func(ctx context.Context, delivery *Delivery, payload orderCreated) error {
+		messages <- delivery
+		payloads <- payload
+		return nil
+	}))
+
+	before := time.Now()
+
+	if err := PublishJSON(context.Background(), b, "orders", orderCreated{OrderID: "o1", Total: 10}, map[string]string{"trace": "t1"}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	select {
+	case delivery := <-messages:
+		if payload := <-payloads; payload != (orderCreated{OrderID: "o1", Total: 10}) {
+			t.Errorf("got payload %+v", payload)
+		}
+
+		if delivery.ID == "" || delivery.Topic != "orders" || delivery.Group != "billing" || delivery.PublishedAt.Before(before) {
+			t.Errorf("got message %+v, want id, topic, group and publish time set", delivery.Message)
+		}
+
+		if delivery.Headers["trace"] != "t1" {
+			t.Errorf("got headers %v, want trace t1", delivery.Headers)
+		}
+	case <-time.After(testTimeout):
+		t.Fatalf("message not delivered")
+	}
+}
+
+func TestMemoryBrokerShutdown(t *testing.T) {
+	b := NewMemoryBroker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := b.Subscribe(ctx, "orders", "billing", func(ctx context.Context, delivery *Delivery) error { return nil }); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), testTimeout)
+	defer shutdownCancel()
+
+	if err := b.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if err := b.Subscribe(ctx, "orders", "billing", func(ctx context.Context, delivery *Delivery) error { return nil }); err == nil {
+		t.Errorf("subscribe after shutdown succeeded")
+	}
+}
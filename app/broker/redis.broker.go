@@ -0,0 +1,274 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Broker over Redis Streams, a stream per topic and a Redis consumer group per group
+type RedisStreamsBroker struct {
+	Client    *redis.Client
+	URL       string        // Redis url e.g: redis://localhost:6379/0
+	MaxLen    int64         // Streams are trimmed to about this many messages, default is 100000
+	ClaimIdle time.Duration // Message left unacked by a crashed consumer is redelivered after it, default is 5 minutes
+	replica   string        // Prefix of Redis consumer names of this replica
+	consumers *consumers
+}
+
+// Using REDIS_URL env when url is left empty
+func NewRedisStreamsBroker(url string) *RedisStreamsBroker {
+	hostname, _ := os.Hostname()
+
+	return &RedisStreamsBroker{
+		URL:       url,
+		MaxLen:    100000,
+		ClaimIdle: 5 * time.Minute,
+		replica:   fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		consumers: newConsumers(),
+	}
+}
+
+func (b *RedisStreamsBroker) Connect() (*redis.Client, error) {
+	if b.URL == "" {
+		b.URL = utils.GetAppConfig("REDIS_URL")
+	}
+
+	if b.URL == "" {
+		return nil, fmt.Errorf("REDIS_URL is missing or unset")
+	}
+
+	options, err := redis.ParseURL(b.URL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b.Client = redis.NewClient(options)
+
+	return b.Client, nil
+}
+
+func (b *RedisStreamsBroker) ConnectRaw() error {
+	_, err := b.Connect()
+
+	return err
+}
+
+func (b *RedisStreamsBroker) Healthcheck() error {
+	return b.Client.Ping(context.Background()).Err()
+}
+
+func (b *RedisStreamsBroker) SuccessMessage() string {
+	return "Redis Streams broker connected."
+}
+
+func (b *RedisStreamsBroker) Shutdown(ctx context.Context) error {
+	err := b.consumers.shutdown(ctx)
+
+	if b.Client != nil {
+		err = errors.Join(err, b.Client.Close())
+	}
+
+	if err != nil {
+		return fmt.Errorf("redis streams broker shutdown: %w", err)
+	}
+
+	return nil
+}
+
+func (b *RedisStreamsBroker) Publish(ctx context.Context, topic string, message Message) error {
+	message = prepare(topic, message)
+
+	headers, err := json.Marshal(message.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	values := map[string]any{
+		"id":          message.ID,
+		"key":         message.Key,
+		"headers":     headers,
+		"body":        message.Body,
+		"publishedAt": message.PublishedAt.Format(time.RFC3339Nano),
+	}
+
+	return b.Client.XAdd(ctx, &redis.XAddArgs{Stream: message.Topic, MaxLen: b.MaxLen, Approx: true, Values: values}).Err()
+}
+
+func (b *RedisStreamsBroker) Subscribe(ctx context.Context, topic string, group string, handler Handler) error {
+	if err := b.createGroup(ctx, topic, group); err != nil {
+		return err
+	}
+
+	// Consumer per subscription, its pending messages are only the ones it nacked with requeue
+	consumer := b.replica + "-" + randomID()[:8]
+
+	return b.consumers.start(ctx, func(ctx context.Context) {
+		defer b.removeConsumer(topic, group, consumer)
+
+		for ctx.Err() == nil {
+			// Nacked messages, then pending messages of crashed consumers
+			if streams, err := b.Client.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    group,
+				Consumer: consumer,
+				Streams:  []string{topic, "0"},
+				Count:    10,
+			}).Result(); err == nil {
+				for _, stream := range streams {
+					for _, entry := range stream.Messages {
+						b.deliver(topic, group, entry, true, handler)
+					}
+				}
+			}
+
+			if claimed, _, err := b.Client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   topic,
+				Group:    group,
+				MinIdle:  b.ClaimIdle,
+				Start:    "0-0",
+				Count:    10,
+				Consumer: consumer,
+			}).Result(); err == nil {
+				for _, entry := range claimed {
+					b.deliver(topic, group, entry, true, handler)
+				}
+			}
+
+			streams, err := b.Client.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    group,
+				Consumer: consumer,
+				Streams:  []string{topic, ">"},
+				Count:    10,
+				Block:    2 * time.Second,
+			}).Result()
+
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+
+			if err != nil {
+				log.Printf("Redis stream %s read error: %v\n", topic, err)
+
+				// Stream was deleted along with its groups
+				if strings.HasPrefix(err.Error(), "NOGROUP") {
+					b.createGroup(ctx, topic, group)
+				}
+
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+
+				continue
+			}
+
+			for _, stream := range streams {
+				for _, entry := range stream.Messages {
+					b.deliver(topic, group, entry, false, handler)
+				}
+			}
+		}
+	})
+}
+
+// Create group of topic stream receiving messages added from now on, existing group is kept
+func (b *RedisStreamsBroker) createGroup(ctx context.Context, topic string, group string) error {
+	err := b.Client.XGroupCreateMkStream(ctx, topic, group, "$").Err()
+
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	return nil
+}
+
+// Remove consumer of ended subscription unless messages are pending for it, those are claimed by another consumer after ClaimIdle
+func (b *RedisStreamsBroker) removeConsumer(topic string, group string, consumer string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pending, err := b.Client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   topic,
+		Group:    group,
+		Start:    "-",
+		End:      "+",
+		Count:    1,
+		Consumer: consumer,
+	}).Result()
+
+	if err == nil && len(pending) == 0 {
+		b.Client.XGroupDelConsumer(ctx, topic, group, consumer)
+	}
+}
+
+func (b *RedisStreamsBroker) deliver(topic string, group string, entry redis.XMessage, redelivered bool, handler Handler) {
+	ack := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		return b.Client.XAck(ctx, topic, group, entry.ID).Err()
+	}
+
+	message, err := decodeStreamEntry(topic, entry)
+
+	// Trimmed while pending or not added by a broker
+	if err != nil {
+		log.Printf("Invalid message %s of redis stream %s dropped: %v\n", entry.ID, topic, err)
+		ack()
+		return
+	}
+
+	delivery := &Delivery{Message: message, Group: group, Redelivered: redelivered}
+	delivery.ack = ack
+	delivery.nack = func(requeue bool) error {
+		// Left pending, redelivered by the next read of the consumer
+		if requeue {
+			return nil
+		}
+
+		return ack()
+	}
+
+	b.consumers.handle(handler, delivery)
+}
+
+func decodeStreamEntry(topic string, entry redis.XMessage) (Message, error) {
+	message := Message{Topic: topic}
+
+	body, ok := entry.Values["body"].(string)
+
+	if !ok {
+		return message, errors.New("body is missing")
+	}
+
+	message.Body = []byte(body)
+	message.ID, _ = entry.Values["id"].(string)
+	message.Key, _ = entry.Values["key"].(string)
+
+	if headers, ok := entry.Values["headers"].(string); ok {
+		if err := json.Unmarshal([]byte(headers), &message.Headers); err != nil {
+			return message, err
+		}
+	}
+
+	if publishedAt, ok := entry.Values["publishedAt"].(string); ok {
+		message.PublishedAt, _ = time.Parse(time.RFC3339Nano, publishedAt)
+	}
+
+	return message, nil
+}
+
+var _ Broker = (*RedisStreamsBroker)(nil)
+var _ externals.External[*redis.Client] = (*RedisStreamsBroker)(nil)
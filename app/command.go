@@ -0,0 +1,211 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/migrations"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/seeders"
+)
+
+type Command struct {
+	Usage       string                                           // Command usage e.g: indexes sync
+	Description string                                           // Short description printed in help
+	Run         func(config *HttpAppConfig, args []string) error // Command logic, args exclude the command name
+}
+
+var commands = map[string]Command{
+	"serve": {
+		Usage:       "serve",
+		Description: "Start http web server (default when no command is given)",
+		Run: func(config *HttpAppConfig, args []string) error {
+			InitHttpApp(config)
+			return nil
+		},
+	},
+	"indexes": {
+		Usage:       "indexes sync",
+		Description: "Create indexes declared by models and resource repos",
+		Run: func(config *HttpAppConfig, args []string) error {
+			if len(args) == 0 || args[0] != "sync" {
+				return fmt.Errorf("usage: indexes sync")
+			}
+
+			// Resource repos are registered while routes are built
+			newHttpApp(config)
+
+			if err := repo.SyncAllIndexes(); err != nil {
+				return err
+			}
+
+			log.Println("Indexes synced.")
+
+			return nil
+		},
+	},
+	"migrate": {
+		Usage:       "migrate up|down [steps]|status|create <name>",
+		Description: "Apply, revert, list or generate database migrations",
+		Run:         runMigrateCommand,
+	},
+	"seed": {
+		Usage:       "seed [--force] [seeder...]",
+		Description: "Load fixtures and run seeders, refused unless APP_ENV is development, test or local or --force is given, always refused in production",
+		Run: func(config *HttpAppConfig, args []string) error {
+			force := slices.Contains(args, "--force")
+			args = slices.DeleteFunc(args, func(arg string) bool {
+				return arg == "--force"
+			})
+
+			dir := config.FixturesDir
+			if dir == "" {
+				dir = "fixtures"
+			}
+
+			// Fixtures only load on full seed, naming seeders runs just those
+			if len(args) > 0 {
+				dir = ""
+			}
+
+			if err := seeders.Run(context.Background(), config.Externals, dir, force, args...); err != nil {
+				return err
+			}
+
+			log.Println("Seeding done.")
+
+			return nil
+		},
+	},
+}
+
+func runMigrateCommand(config *HttpAppConfig, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status|create <name>")
+	}
+
+	if args[0] == "create" {
+		if len(args) < 2 {
+			return fmt.Errorf("usage: migrate create <name>")
+		}
+
+		dir := config.MigrationsDir
+		if dir == "" {
+			dir = "migrations"
+		}
+
+		path, err := migrations.Create(dir, strings.Join(args[1:], "_"))
+
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Created %s", path)
+
+		return nil
+	}
+
+	migrator, err := migrations.NewMigrator(config.Externals)
+
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+
+		for _, migration := range applied {
+			log.Printf("Migrated %d_%s", migration.Version, migration.Name)
+		}
+
+		if err == nil && len(applied) == 0 {
+			log.Println("Nothing to migrate.")
+		}
+
+		return err
+	case "down":
+		steps := 1
+
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid steps %q", args[1])
+			}
+		}
+
+		reverted, err := migrator.Down(ctx, steps)
+
+		for _, migration := range reverted {
+			log.Printf("Reverted %d_%s", migration.Version, migration.Name)
+		}
+
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%d_%-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("usage: migrate up|down [steps]|status|create <name>")
+}
+
+// Register custom command, replacing existing command with the same name
+func RegisterCommand(name string, command Command) {
+	commands[name] = command
+}
+
+// Run command given through os.Args e.g: `go run . indexes sync`, serves http app when no command is given
+func Execute(config *HttpAppConfig) {
+	args := os.Args[1:]
+
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+
+	command, ok := commands[args[0]]
+
+	if !ok {
+		printUsage()
+		os.Exit(1)
+	}
+
+	if err := command.Run(config, args[1:]); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("Available commands:")
+
+	for _, name := range names {
+		fmt.Printf("  %-32s %s\n", commands[name].Usage, commands[name].Description)
+	}
+}
@@ -2,10 +2,13 @@ package externals
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+
 	"golang.org/x/sync/errgroup"
 )
 
@@ -24,6 +27,35 @@ type External[T any] interface {
 	Connect() (T, error) // Implement connection logic here for external dependency
 }
 
+// External supporting unit of work across repositories, refer MongoDBExternal, SQLExternal and MemoryExternal
+type TransactionalExternal interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// External releasing resources on app shutdown, refer websocket.Hub
+type ShutdownExternal interface {
+	Shutdown(ctx context.Context) error
+}
+
+// Shutdown every external supporting it, in reverse order of registration
+func ShutdownExternals(ctx context.Context, externals *AllAppExternals) error {
+	var errs []error
+
+	if externals == nil {
+		return nil
+	}
+
+	for i := len(externals.All) - 1; i >= 0; i-- {
+		if ext, ok := externals.All[i].(ShutdownExternal); ok {
+			if err := ext.Shutdown(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // Register all external dependencies
 func RegisterExternals(allExternals []BaseExternal) (*AllAppExternals, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -57,6 +89,10 @@ func RegisterExternals(allExternals []BaseExternal) (*AllAppExternals, error) {
 // Pick one external from list of registered externals by type pointer
 func GetExternal[T BaseExternal](externals *AllAppExternals) (T, error) {
 	var zero T
+	if externals == nil {
+		return zero, fmt.Errorf("external of type %T not found", zero)
+	}
+
 	for _, ext := range externals.All {
 		if t, ok := ext.(T); ok {
 			return t, nil
@@ -64,3 +100,23 @@ func GetExternal[T BaseExternal](externals *AllAppExternals) (T, error) {
 	}
 	return zero, fmt.Errorf("external of type %T not found", zero)
 }
+
+// Database handles of registered MongoDB, SQL and memory externals, used to build resource repos with repo.NewResourceRepo
+func ResourceDB(externals *AllAppExternals) repo.ResourceDB {
+	var db repo.ResourceDB
+
+	if mongoExt, err := GetExternal[*MongoDBExternal](externals); err == nil {
+		db.MongoDB = mongoExt.DB
+	}
+
+	if sqlExt, err := GetExternal[*SQLExternal](externals); err == nil {
+		db.SQL = sqlExt.DB
+		db.SQLDriver = sqlExt.Driver
+	}
+
+	if memoryExt, err := GetExternal[*MemoryExternal](externals); err == nil {
+		db.Memory = memoryExt.Store
+	}
+
+	return db
+}
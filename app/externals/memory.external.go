@@ -0,0 +1,47 @@
+package externals
+
+import (
+	"context"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+)
+
+// In-memory database external, resources and repos use repo.MemoryRepo backed by Store, useful for tests
+type MemoryExternal struct {
+	Store *repo.MemoryStore
+}
+
+func NewMemoryExternal() *MemoryExternal {
+	return &MemoryExternal{}
+}
+
+func (me *MemoryExternal) Connect() (*repo.MemoryStore, error) {
+	if me.Store == nil {
+		me.Store = repo.NewMemoryStore()
+	}
+
+	return me.Store, nil
+}
+
+func (me *MemoryExternal) ConnectRaw() error {
+	_, err := me.Connect()
+
+	return err
+}
+
+func (me *MemoryExternal) Healthcheck() error {
+	return nil
+}
+
+func (me *MemoryExternal) SuccessMessage() string {
+	return "In-memory database ready."
+}
+
+// Run fn atomically, any MemoryRepo call made with the given ctx (repo.WithContext(ctx)) joins the transaction
+func (me *MemoryExternal) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return me.Store.WithTransaction(ctx, fn, MaxTransactionAttempts)
+}
+
+var _ BaseExternal = (*MemoryExternal)(nil)
+var _ External[*repo.MemoryStore] = (*MemoryExternal)(nil)
+var _ TransactionalExternal = (*MemoryExternal)(nil)
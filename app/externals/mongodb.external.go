@@ -2,6 +2,9 @@ package externals
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/utils"
 
@@ -10,7 +13,8 @@ import (
 )
 
 type MongoDBExternal struct {
-	DB *mongo.Database
+	DB              *mongo.Database
+	AllowStandalone bool // Run WithTransaction without transaction on standalone servers instead of failing, writes are not atomic then, also set by MONGODB_ALLOW_STANDALONE=true, default is false
 }
 
 func NewMongoDBExternal() *MongoDBExternal {
@@ -26,6 +30,10 @@ func (me *MongoDBExternal) Connect() (*mongo.Client, error) {
 
 	me.DB = client.Database(utils.GetAppConfig("MONGODB_DATABASE"))
 
+	if utils.GetAppConfig("MONGODB_ALLOW_STANDALONE") == "true" {
+		me.AllowStandalone = true
+	}
+
 	return client, err
 }
 
@@ -45,3 +53,73 @@ func (me *MongoDBExternal) SuccessMessage() string {
 
 var _ BaseExternal = (*MongoDBExternal)(nil)
 var _ External[*mongo.Client] = (*MongoDBExternal)(nil)
+var _ TransactionalExternal = (*MongoDBExternal)(nil)
+
+// Maximum attempts of WithTransaction when transaction fails with transient error
+var MaxTransactionAttempts = 3
+
+// Run fn within a multi-document transaction, any BaseRepo call made with the given ctx (repo.WithContext(ctx)) joins it.
+// Nested calls join the outer transaction. Fails on standalone servers unless AllowStandalone is set.
+func (me *MongoDBExternal) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		return fn(ctx)
+	}
+
+	session, err := me.DB.Client().StartSession()
+
+	if err != nil {
+		return err
+	}
+
+	defer session.EndSession(ctx)
+
+	for attempt := 1; ; attempt++ {
+		err = runMongoTransaction(ctx, session, fn)
+
+		if err == nil {
+			return nil
+		}
+
+		// Transactions require replica set or sharded cluster (IllegalOperation)
+		var serverErr mongo.ServerError
+		if errors.As(err, &serverErr) && serverErr.HasErrorCode(20) {
+			if !me.AllowStandalone {
+				return fmt.Errorf("mongodb transactions require a replica set or sharded cluster, set AllowStandalone to run without: %w", err)
+			}
+
+			log.Println("MongoDB transactions are not supported by this deployment, running without transaction.")
+			return fn(ctx)
+		}
+
+		if attempt >= MaxTransactionAttempts || !hasErrorLabel(err, "TransientTransactionError") {
+			return err
+		}
+	}
+}
+
+func runMongoTransaction(ctx context.Context, session *mongo.Session, fn func(ctx context.Context) error) error {
+	if err := session.StartTransaction(); err != nil {
+		return err
+	}
+
+	sessionCtx := mongo.NewSessionContext(ctx, session)
+
+	if err := fn(sessionCtx); err != nil {
+		_ = session.AbortTransaction(context.Background())
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := session.CommitTransaction(sessionCtx)
+
+		if err == nil || attempt >= MaxTransactionAttempts || !hasErrorLabel(err, "UnknownTransactionCommitResult") {
+			return err
+		}
+	}
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var labeled interface{ HasErrorLabel(string) bool }
+
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(label)
+}
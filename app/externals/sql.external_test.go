@@ -0,0 +1,132 @@
+package externals
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+)
+
+func TestSQLiteDSN(t *testing.T) {
+	tests := []struct {
+		dsn          string
+		wantPrefix   string
+		wantContains []string
+	}{
+		{"file:app.db", "file:app.db?", []string{"busy_timeout%285000%29", "journal_mode%28WAL%29", "_txlock=immediate"}},
+		{"file:app.db?_pragma=busy_timeout(100)&_txlock=deferred", "file:app.db?", []string{"busy_timeout%28100%29", "journal_mode%28WAL%29", "_txlock=deferred"}},
+		{":memory:", "file:/memory-", []string{"vfs=memdb", "busy_timeout%285000%29"}},
+		{"", "file:/memory-", []string{"vfs=memdb"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.dsn, func(t *testing.T) {
+			got := sqliteDSN(test.dsn)
+
+			if !strings.HasPrefix(got, test.wantPrefix) {
+				t.Errorf("got %s, want prefix %s", got, test.wantPrefix)
+			}
+
+			for _, want := range test.wantContains {
+				if !strings.Contains(got, want) {
+					t.Errorf("got %s, want it to contain %s", got, want)
+				}
+			}
+
+			if strings.Count(got, "busy_timeout") != 1 {
+				t.Errorf("got %s, want a single busy_timeout", got)
+			}
+		})
+	}
+}
+
+func newTestSQLite(t *testing.T, dsn string) *SQLExternal {
+	t.Helper()
+
+	se := NewSQLExternal("sqlite", dsn)
+
+	if err := se.ConnectRaw(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	t.Cleanup(func() { se.DB.Close() })
+
+	if _, err := se.DB.Exec("CREATE TABLE notes (title TEXT)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	return se
+}
+
+func TestSQLiteCallsOutsideOfTransaction(t *testing.T) {
+	se := newTestSQLite(t, "file:"+filepath.Join(t.TempDir(), "app.db"))
+
+	if _, err := se.DB.Exec("INSERT INTO notes VALUES ('a')"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	done := make(chan error, 1)
+
+	err := se.WithTransaction(context.Background(), func(ctx context.Context) error {
+		if _, err := repo.SQLTxFromContext(ctx).ExecContext(ctx, "INSERT INTO notes VALUES ('b')"); err != nil {
+			return err
+		}
+
+		go func() {
+			// Read without the transaction context must not wait for the transaction to end
+			var count int
+			done <- se.DB.QueryRowContext(context.Background(), "SELECT count(*) FROM notes").Scan(&count)
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(2 * time.Second):
+			t.Fatal("read outside of transaction is blocked")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("transaction: %v", err)
+	}
+}
+
+func TestSQLiteMemoryDatabaseIsShared(t *testing.T) {
+	se := newTestSQLite(t, ":memory:")
+	ctx := context.Background()
+
+	first, err := se.DB.Conn(ctx)
+
+	if err != nil {
+		t.Fatalf("first connection: %v", err)
+	}
+
+	defer first.Close()
+
+	second, err := se.DB.Conn(ctx)
+
+	if err != nil {
+		t.Fatalf("second connection: %v", err)
+	}
+
+	defer second.Close()
+
+	if _, err := first.ExecContext(ctx, "INSERT INTO notes VALUES ('a')"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var count int
+
+	if err := second.QueryRowContext(ctx, "SELECT count(*) FROM notes").Scan(&count); err != nil || count != 1 {
+		t.Errorf("got count %d %v, want 1", count, err)
+	}
+
+	if other := newTestSQLite(t, ":memory:"); other.DB.QueryRow("SELECT count(*) FROM notes").Scan(&count) != nil || count != 0 {
+		t.Errorf("other database: got count %d, want 0", count)
+	}
+}
@@ -1,10 +1,14 @@
 package middlewares
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/utils"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/websocket"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -39,9 +43,22 @@ func CustomHTTPErrorHandler(err error, c echo.Context) {
 	}
 
 	if err != nil {
+		var conflict repo.ErrConflict
+		if errors.As(err, &conflict) {
+			code = http.StatusConflict
+			message := "Resource already exists."
+
+			if conflict.Field != "" {
+				message = fmt.Sprintf("%s already exists.", conflict.Field)
+			}
+
+			c.JSON(code, echo.Map{"message": message, "field": conflict.Field})
+			return
+		}
+
 		if errs, ok := err.(validator.ValidationErrors); ok {
 			code = http.StatusUnprocessableEntity
-			formatted := formatErrorsWithJSONTags(errs)
+			formatted := utils.FormatValidationErrors(errs)
 			c.JSON(code, echo.Map{"message": http.StatusText(code), "errors": formatted})
 			return
 		}
@@ -51,31 +68,37 @@ func CustomHTTPErrorHandler(err error, c echo.Context) {
 	c.JSON(code, echo.Map{"message": http.StatusText(code)})
 }
 
-func formatErrorsWithJSONTags(errs validator.ValidationErrors) map[string]string {
-	errors := make(map[string]string)
-	for _, err := range errs {
-		field := err.Field() // this will now return the `json` tag value
-		errors[field] = fmt.Sprintf("%s %s", field, validationMessage(err))
+// Map websocket event handler error into error event, mirrors CustomHTTPErrorHandler
+func WSErrorHandler(err error) *websocket.WSError {
+	if he, ok := err.(*echo.HTTPError); ok && he.Code < 500 {
+		var message string
+		switch m := he.Message.(type) {
+		case string:
+			message = m
+		case error:
+			message = m.Error()
+		default:
+			message = fmt.Sprintf("%v", m)
+		}
+		return &websocket.WSError{Code: he.Code, Message: message}
 	}
-	return errors
-}
 
-func validationMessage(err validator.FieldError) string {
-	switch err.Tag() {
-	case "required":
-		return "is a required field."
-	case "email":
-		return "is not a valid email."
-	case "gte":
-		return fmt.Sprintf("must be greater than or equal to %s.", err.Param())
-	case "eqfield":
-		return fmt.Sprintf("does not match with %s.", utils.NormalizeFieldName(err.Param()))
-	case "min":
-		return fmt.Sprintf("must be a minimum of %s character(s).", err.Param())
-	case "len":
-		return fmt.Sprintf("must be of %s character(s).", err.Param())
-	// Add more cases as needed
-	default:
-		return fmt.Sprintf("not valid (%s)", err.Tag())
+	var conflict repo.ErrConflict
+	if errors.As(err, &conflict) {
+		message := "Resource already exists."
+
+		if conflict.Field != "" {
+			message = fmt.Sprintf("%s already exists.", conflict.Field)
+		}
+
+		return &websocket.WSError{Code: http.StatusConflict, Message: message, Field: conflict.Field}
 	}
+
+	if errs, ok := err.(validator.ValidationErrors); ok {
+		return &websocket.WSError{Code: http.StatusUnprocessableEntity, Message: http.StatusText(http.StatusUnprocessableEntity), Errors: utils.FormatValidationErrors(errs)}
+	}
+
+	log.Println("websocket handler error:", err)
+
+	return &websocket.WSError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)}
 }
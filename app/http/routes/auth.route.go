@@ -4,6 +4,7 @@ import (
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/controllers"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/middlewares"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/services"
 
 	"github.com/labstack/echo/v4"
 )
@@ -11,6 +12,8 @@ import (
 func InitAuthRoute(router *echo.Group, externals *externals.AllAppExternals) {
 	authRoute := router.Group("/auth")
 
+	services.RegisterUserIndexes(externals)
+
 	authRoute.POST("/login", controllers.Login(externals))
 	authRoute.Use(middlewares.Auth(externals))
 	authRoute.GET("", controllers.GetAuthUser(externals))
@@ -2,6 +2,7 @@ package types
 
 import (
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	appTypes "github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
 
 	"github.com/labstack/echo/v4"
 )
@@ -14,12 +15,38 @@ type ControllerConfig struct {
 	Middlewares  []echo.MiddlewareFunc // Applied multiple middleware(s) to current route, works with overriden controller too, default is empty/not applied
 }
 
+type ParentConfig struct {
+	Resource string // Parent resource name e.g: "users", parent resource routes must be generated first
+	Field    string // Field holding parent id on this resource e.g: "userId"
+}
+
+// Filters limiting records visible to request e.g: ownership, run after route middlewares, error is returned as is
+type ScopeFunc func(c echo.Context) ([]appTypes.QueryParamsFilter, error)
+
 type GenerateResourceRoutesConfig struct {
-	Router     *echo.Group                // Base echo.Group or any extended one
-	GetAll     ControllerConfig           // Get all resource route e.g: GET /resources
-	Create     ControllerConfig           // Create a single resource route e.g: POST /resources
-	GetById    ControllerConfig           // Get single resource by id route e.g: GET /resources/:resourceId
-	UpdateById ControllerConfig           // Update single resource properties by id route e.g: PUT /resources/:resourceId
-	DeleteById ControllerConfig           // Delete single resource by id route e.g: DELETE /resources/:resourceId
-	Externals  *externals.AllAppExternals // All app external must be pass here as dependency injection
+	Router             *echo.Group                // Base echo.Group or any extended one
+	GetAll             ControllerConfig           // Get all resource route e.g: GET /resources
+	Create             ControllerConfig           // Create a single resource route e.g: POST /resources
+	GetById            ControllerConfig           // Get single resource by id route e.g: GET /resources/:resourceId
+	UpdateById         ControllerConfig           // Update single resource properties by id route e.g: PUT /resources/:resourceId
+	PatchById          ControllerConfig           // Patch single resource by id route e.g: PATCH /resources/:resourceId, accepts merge patch (application/merge-patch+json) or JSON Patch (application/json-patch+json), InputSchema is required, only its fields are patchable and it validates the patched resource, guarded by GetAll middlewares like the other routes, its Middlewares run after them
+	DeleteById         ControllerConfig           // Delete single resource by id route e.g: DELETE /resources/:resourceId
+	RestoreById        ControllerConfig           // Restore single soft deleted resource by id route e.g: POST /resources/:resourceId/restore, requires SoftDelete, guarded by GetAll middlewares like the other routes, its Middlewares run after them
+	BatchCreate        ControllerConfig           // Create multiple resources route e.g: POST /resources/batch, body is an array of resources, guarded by GetAll middlewares like the other routes, its Middlewares run after them
+	BatchUpdate        ControllerConfig           // Update multiple resources route e.g: PATCH /resources/batch, body is an array of resources each with _id, guarded by GetAll middlewares, ids outside of Scope fail with 404
+	BatchDelete        ControllerConfig           // Delete multiple resources route e.g: DELETE /resources/batch, body is an array of ids, guarded by GetAll middlewares, ids outside of Scope fail with 404
+	Aggregate          ControllerConfig           // Grouped metrics route e.g: GET /resources/aggregate?group_by=status&metrics=count,sum:amount&interval=day:createdAt, accepts GetAll filters, requires AggregateFields, guarded by GetAll middlewares like the other routes, its Middlewares run after them
+	AggregateFields    []string                   // Fields usable in group_by, metrics, interval and filters of aggregate route
+	MaxAggregateGroups int                        // Maximum groups returned by aggregate route, ?limit= may lower it, default is 100
+	MaxBatchSize       int                        // Maximum items accepted by batch routes, default is 1000
+	Versioned          bool                       // Optimistic concurrency using version field, stale updates respond 409 and ETag follows the version, default is false
+	SoftDelete         bool                       // Mark deletedAt instead of removing, soft deleted resources are hidden unless ?with_deleted=true or ?only_deleted=true, default is false
+	Parent             *ParentConfig              // Nest routes under parent resource e.g: /users/:user/apps, queries are scoped to parent id and respond 404 when parent does not exist, batch routes are not supported
+	Relations          []appTypes.Relation        // Relations loadable with ?include= on GetAll and GetById e.g: ?include=author,comments.author, model should declare a field per relation
+	MaxIncludeDepth    int                        // Maximum nesting of ?include= paths e.g: comments.author is 2, default is 2
+	SelectableFields   []string                   // Fields clients may pick with ?fields= on GetAll and GetById, intersected with OutputSchema, default allows every field
+	Search             []string                   // Searchable fields, indexed in one text index with `index:"text"` fields of the model (created on first search when indexes are not synced), GetAll accepts ?q= full-text search, ?sort=score orders by relevance
+	Scope              ScopeFunc                  // Filters applied to GetAll, Aggregate, batch updates and deletes and realtime events on top of client filters e.g: records owned by the auth user, by-id routes respond 404 outside of it
+	Realtime           bool                       // Publish created/updated/deleted/restored events to websocket namespace of resource e.g: "notes" or "users/<id>/apps", subscribe with GET /resources/subscribe guarded by GetAll middlewares and Scope, records rendered with GetAll OutputSchema, uses MongoDB change streams when available otherwise writes of generated controllers, resource namespaces are reserved to the subscribe route
+	Externals          *externals.AllAppExternals // All app external must be pass here as dependency injection
 }
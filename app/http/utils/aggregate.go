@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/types"
+	repoPkg "github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	appTypes "github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+const defaultMaxAggregateGroups = 100
+
+var aggregateUnits = []string{"hour", "day", "week", "month", "year"}
+
+func generateAggregateRoute[T any](router *echo.Group, repo repoPkg.AggregateRepository[T], config types.GenerateResourceRoutesConfig) {
+	maxGroups := config.MaxAggregateGroups
+	if maxGroups <= 0 {
+		maxGroups = defaultMaxAggregateGroups
+	}
+
+	router.GET("/aggregate", batchHandler(config.Aggregate, config.GetAll.Middlewares, func(c echo.Context) error {
+		query, err := ParseAggregateQuery(c, config.AggregateFields, maxGroups)
+
+		if err != nil {
+			return err
+		}
+
+		filtersAndSorts := ParseQueryParams(c.QueryParams())
+
+		if filtersAndSorts.Search != "" && len(config.Search) == 0 {
+			return echo.NewHTTPError(400, "Search is not enabled for this resource.")
+		}
+
+		deletedMode, deletedModeErr := ParseDeletedMode(c.QueryParams())
+
+		if deletedModeErr != nil {
+			return deletedModeErr
+		}
+
+		filtersAndSorts.DeletedMode = deletedMode
+
+		if config.Parent != nil {
+			filtersAndSorts.QueryParamsFilters = append(filtersAndSorts.QueryParamsFilters, parentFilter(c, config.Parent))
+		}
+
+		scope, err := scopeFilters(c, config)
+
+		if err != nil {
+			return err
+		}
+
+		filtersAndSorts.QueryParamsFilters = append(filtersAndSorts.QueryParamsFilters, scope...)
+
+		results, err := repo.Aggregate(filtersAndSorts, *query)
+
+		if err != nil {
+			return RepoHTTPError(err)
+		}
+
+		return c.JSON(200, echo.Map{"data": results})
+	}))
+}
+
+// Parse ?group_by=status&metrics=count,sum:amount&interval=day:createdAt&limit=10, fields must be in allowed list, metrics default to count.
+// Fields of filter.* and or.filter.* params must be in allowed list too, filters would otherwise probe fields kept out of aggregation.
+func ParseAggregateQuery(c echo.Context, allowed []string, maxGroups int) (*appTypes.AggregateQuery, error) {
+	query := &appTypes.AggregateQuery{Limit: maxGroups}
+
+	checkField := func(field string) error {
+		if field == "" || !slices.Contains(allowed, field) {
+			return echo.NewHTTPError(400, fmt.Sprintf("Field is not allowed for aggregation: %s", field))
+		}
+
+		return nil
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(c.QueryParams())) {
+		if key = strings.TrimPrefix(key, "or."); !strings.HasPrefix(key, "filter.") {
+			continue
+		}
+
+		field, _, _ := strings.Cut(key[len("filter."):], ".")
+
+		if err := checkField(field); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range splitParam(c.QueryParam("group_by")) {
+		if err := checkField(field); err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(query.GroupBy, field) {
+			query.GroupBy = append(query.GroupBy, field)
+		}
+	}
+
+	metrics := splitParam(c.QueryParam("metrics"))
+
+	if len(metrics) == 0 {
+		metrics = []string{string(appTypes.AggCount)}
+	}
+
+	for _, param := range metrics {
+		op, field, _ := strings.Cut(param, ":")
+		metric := appTypes.AggregateMetric{Op: appTypes.AggregateOp(op), Field: field}
+
+		switch metric.Op {
+		case appTypes.AggCount:
+			metric.Field = ""
+		case appTypes.AggSum, appTypes.AggAvg, appTypes.AggMin, appTypes.AggMax:
+			if err := checkField(field); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, echo.NewHTTPError(400, fmt.Sprintf("Invalid aggregate metric: %s", param))
+		}
+
+		if !slices.Contains(query.Metrics, metric) {
+			query.Metrics = append(query.Metrics, metric)
+		}
+	}
+
+	if interval := c.QueryParam("interval"); interval != "" {
+		unit, field, _ := strings.Cut(interval, ":")
+
+		if !slices.Contains(aggregateUnits, unit) {
+			return nil, echo.NewHTTPError(400, fmt.Sprintf("Invalid aggregate interval: %s", interval))
+		}
+
+		if err := checkField(field); err != nil {
+			return nil, err
+		}
+
+		query.Interval = &appTypes.AggregateInterval{Unit: unit, Field: field}
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+
+		if err != nil || parsed <= 0 {
+			return nil, echo.NewHTTPError(400, "Invalid aggregate param: limit")
+		}
+
+		query.Limit = min(parsed, maxGroups)
+	}
+
+	return query, nil
+}
+
+func splitParam(param string) []string {
+	var values []string
+
+	for _, value := range strings.Split(param, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,388 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/types"
+	repoPkg "github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	appTypes "github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const defaultMaxBatchSize = 1000
+
+type BatchItemResult struct {
+	Index   int               `json:"index"`             // Position of the item in the request body
+	Status  int               `json:"status"`            // Http status of the item as if it was requested alone
+	Data    any               `json:"data,omitempty"`    // Created / updated resource
+	Message string            `json:"message,omitempty"` // Failure message
+	Field   string            `json:"field,omitempty"`   // Conflicting field of unique violation
+	Errors  map[string]string `json:"errors,omitempty"`  // Validation errors keyed by field
+}
+
+func generateBatchRoutes[T any](router *echo.Group, repo repoPkg.BulkRepository[T], config types.GenerateResourceRoutesConfig, publish changePublisher) {
+	maxBatchSize := config.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = defaultMaxBatchSize
+	}
+
+	if config.BatchCreate.Enabled {
+		router.POST("", batchHandler(config.BatchCreate, config.GetAll.Middlewares, func(c echo.Context) error {
+			var items []map[string]any
+
+			if err := bindBatch(c, &items, maxBatchSize); err != nil {
+				return err
+			}
+
+			data := make([]any, len(items))
+			preErrs := make([]error, len(items))
+
+			for i, item := range items {
+				data[i], preErrs[i] = validateBatchItem(c, item, config.BatchCreate.InputSchema)
+			}
+
+			results, err := runBatch(len(items), preErrs, isOrdered(c), func(positions []int) ([]repoPkg.BulkResult[T], error) {
+				subset := make([]any, len(positions))
+				for j, position := range positions {
+					subset[j] = data[position]
+				}
+
+				return repo.CreateMany(subset, isOrdered(c))
+			})
+
+			if err != nil {
+				return RepoHTTPError(err)
+			}
+
+			publishBatch(publish, appTypes.ChangeCreated, results, nil)
+
+			return batchResponse(c, results, http.StatusCreated, config.BatchCreate.OutputSchema)
+		}))
+	}
+
+	if config.BatchUpdate.Enabled {
+		router.PATCH("", batchHandler(config.BatchUpdate, config.GetAll.Middlewares, func(c echo.Context) error {
+			var items []map[string]any
+
+			if err := bindBatch(c, &items, maxBatchSize); err != nil {
+				return err
+			}
+
+			updates := make([]repoPkg.BulkUpdate, len(items))
+			ids := make([]string, len(items))
+			preErrs := make([]error, len(items))
+
+			for i, item := range items {
+				id, _ := item["_id"].(string)
+
+				if _, err := bson.ObjectIDFromHex(id); err != nil {
+					preErrs[i] = echo.NewHTTPError(400, fmt.Sprintf("Invalid resource identifier: %v", item["_id"]))
+					continue
+				}
+
+				delete(item, "_id")
+
+				ids[i] = id
+				updates[i].ID = id
+				updates[i].Data, preErrs[i] = validateBatchItem(c, item, config.BatchUpdate.InputSchema)
+			}
+
+			if err := checkBatchScope(c, repo, config, ids, preErrs); err != nil {
+				return err
+			}
+
+			results, err := runBatch(len(items), preErrs, isOrdered(c), func(positions []int) ([]repoPkg.BulkResult[T], error) {
+				subset := make([]repoPkg.BulkUpdate, len(positions))
+				for j, position := range positions {
+					subset[j] = updates[position]
+				}
+
+				return repo.UpdateManyByID(subset, isOrdered(c))
+			})
+
+			if err != nil {
+				return RepoHTTPError(err)
+			}
+
+			publishBatch(publish, appTypes.ChangeUpdated, results, nil)
+
+			return batchResponse(c, results, http.StatusOK, config.BatchUpdate.OutputSchema)
+		}))
+	}
+
+	if config.BatchDelete.Enabled {
+		router.DELETE("", batchHandler(config.BatchDelete, config.GetAll.Middlewares, func(c echo.Context) error {
+			var ids []string
+
+			if err := bindBatch(c, &ids, maxBatchSize); err != nil {
+				return err
+			}
+
+			preErrs := make([]error, len(ids))
+
+			for i, id := range ids {
+				if _, err := bson.ObjectIDFromHex(id); err != nil {
+					preErrs[i] = echo.NewHTTPError(400, fmt.Sprintf("Invalid resource identifier: %s", id))
+				}
+			}
+
+			if err := checkBatchScope(c, repo, config, ids, preErrs); err != nil {
+				return err
+			}
+
+			results, err := runBatch(len(ids), preErrs, isOrdered(c), func(positions []int) ([]repoPkg.BulkResult[T], error) {
+				subset := make([]any, len(positions))
+				for j, position := range positions {
+					subset[j] = ids[position]
+				}
+
+				return repo.DeleteManyByID(subset, isOrdered(c))
+			})
+
+			if err != nil {
+				return RepoHTTPError(err)
+			}
+
+			publishBatch(publish, appTypes.ChangeDeleted, results, ids)
+
+			return batchResponse(c, results, http.StatusNoContent, nil)
+		}))
+	}
+}
+
+// Publish change of every succeeded item, ids of request are used for results without record
+func publishBatch[T any](publish changePublisher, op appTypes.ChangeOp, results []repoPkg.BulkResult[T], ids []string) {
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+
+		id := ""
+		if result.Record == nil && result.Index < len(ids) {
+			id = ids[result.Index]
+		}
+
+		publish(op, id, result.Record)
+	}
+}
+
+// Wrap batch controller with override and middlewares of the route config, guarded by middlewares of the resource e.g: GetAll ones
+func batchHandler(config types.ControllerConfig, guards []echo.MiddlewareFunc, controller echo.HandlerFunc) echo.HandlerFunc {
+	handler := controller
+
+	if config.Override != nil {
+		handler = config.Override
+	}
+
+	for _, middleware := range config.Middlewares {
+		handler = middleware(handler)
+	}
+
+	for _, middleware := range guards {
+		handler = middleware(handler)
+	}
+
+	return handler
+}
+
+// Reject items of missing records or records outside of Scope with 404 as the by-id routes do, before anything is written
+func checkBatchScope[T any](c echo.Context, repo repoPkg.Repository[T], config types.GenerateResourceRoutesConfig, ids []string, preErrs []error) error {
+	scope, err := scopeFilters(c, config)
+
+	if err != nil || len(scope) == 0 {
+		return err
+	}
+
+	for i, id := range ids {
+		if preErrs[i] != nil {
+			continue
+		}
+
+		record, err := repo.GetByID(id)
+
+		if err != nil {
+			return RepoHTTPError(err)
+		}
+
+		if record == nil {
+			preErrs[i] = repoPkg.ErrRecordNotFound
+			continue
+		}
+
+		if within, err := withinScope(record, scope); err != nil {
+			return err
+		} else if !within {
+			preErrs[i] = repoPkg.ErrRecordNotFound
+		}
+	}
+
+	return nil
+}
+
+func bindBatch(c echo.Context, items any, maxBatchSize int) error {
+	if err := c.Bind(items); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if size := reflect.ValueOf(items).Elem().Len(); size == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Batch must contain at least one item.")
+	} else if size > maxBatchSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Batch must not exceed %d items.", maxBatchSize))
+	}
+
+	return nil
+}
+
+// Ordered mode stops at the first failing item, unordered mode is enabled with ?ordered=false
+func isOrdered(c echo.Context) bool {
+	ordered, err := strconv.ParseBool(c.QueryParam("ordered"))
+
+	return err != nil || ordered
+}
+
+// Validate single batch item against a fresh instance of input schema, item is returned as is without schema
+func validateBatchItem(c echo.Context, item map[string]any, inputSchema any) (any, error) {
+	if inputSchema == nil {
+		return item, nil
+	}
+
+	input := newSchemaInstance(inputSchema)
+
+	if err := utils.BindData(item, input); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := c.Validate(input); err != nil {
+		return nil, err
+	}
+
+	return input, nil
+}
+
+// Fresh pointer to a zero value of schema type, so concurrent requests never share a schema instance
+func newSchemaInstance(schema any) any {
+	schemaType := reflect.TypeOf(schema)
+	if schemaType.Kind() == reflect.Pointer {
+		schemaType = schemaType.Elem()
+	}
+
+	return reflect.New(schemaType).Interface()
+}
+
+// Run bulk operation over items passing request level checks, rejected items keep their position in results
+func runBatch[T any](size int, preErrs []error, ordered bool, run func(positions []int) ([]repoPkg.BulkResult[T], error)) ([]repoPkg.BulkResult[T], error) {
+	results := make([]repoPkg.BulkResult[T], size)
+
+	var positions []int
+
+	for i := 0; i < size; i++ {
+		results[i] = repoPkg.BulkResult[T]{Index: i, Error: preErrs[i]}
+
+		if preErrs[i] == nil {
+			positions = append(positions, i)
+		} else if ordered {
+			for j := i + 1; j < size; j++ {
+				results[j] = repoPkg.BulkResult[T]{Index: j, Error: repoPkg.ErrBulkSkipped}
+			}
+
+			break
+		}
+	}
+
+	if len(positions) == 0 {
+		return results, nil
+	}
+
+	bulkResults, err := run(positions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for j, result := range bulkResults {
+		result.Index = positions[j]
+		results[positions[j]] = result
+	}
+
+	return results, nil
+}
+
+// Respond with per item results, 207 Multi-Status when some items failed
+func batchResponse[T any](c echo.Context, results []repoPkg.BulkResult[T], successStatus int, outputSchema any) error {
+	items := make([]BatchItemResult, len(results))
+	failed := 0
+
+	for i, result := range results {
+		if result.Error != nil {
+			items[i] = batchItemError(c, result.Error)
+			items[i].Index = result.Index
+			failed++
+			continue
+		}
+
+		items[i] = BatchItemResult{Index: result.Index, Status: successStatus}
+
+		if result.Record != nil {
+			output, err := RenderOutput(result.Record, outputSchema, nil)
+
+			if err != nil {
+				return err
+			}
+
+			items[i].Data = output
+		}
+	}
+
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusMultiStatus
+	}
+
+	return c.JSON(status, echo.Map{"data": echo.Map{
+		"results":   items,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+	}})
+}
+
+// Map batch item error into the status and message it would get as a single request
+func batchItemError(c echo.Context, err error) BatchItemResult {
+	var (
+		httpErr       *echo.HTTPError
+		conflict      repoPkg.ErrConflict
+		validationErr validator.ValidationErrors
+	)
+
+	switch {
+	case errors.As(err, &httpErr):
+		return BatchItemResult{Status: httpErr.Code, Message: fmt.Sprintf("%v", httpErr.Message)}
+	case errors.As(err, &conflict):
+		message := "Resource already exists."
+
+		if conflict.Field != "" {
+			message = fmt.Sprintf("%s already exists.", conflict.Field)
+		}
+
+		return BatchItemResult{Status: http.StatusConflict, Message: message, Field: conflict.Field}
+	case errors.As(err, &validationErr):
+		return BatchItemResult{Status: http.StatusUnprocessableEntity, Message: http.StatusText(http.StatusUnprocessableEntity), Errors: FormatValidationErrors(validationErr)}
+	case errors.Is(err, repoPkg.ErrVersionConflict):
+		return BatchItemResult{Status: http.StatusConflict, Message: "Resource has been modified by another request."}
+	case errors.Is(err, repoPkg.ErrRecordNotFound):
+		return BatchItemResult{Status: http.StatusNotFound, Message: http.StatusText(http.StatusNotFound)}
+	case errors.Is(err, repoPkg.ErrBulkSkipped):
+		return BatchItemResult{Status: http.StatusFailedDependency, Message: "Skipped after an earlier item failed."}
+	}
+
+	c.Logger().Error(err)
+
+	return BatchItemResult{Status: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)}
+}
@@ -0,0 +1,241 @@
+package utils_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/middlewares"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/types"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/utils"
+	appTypes "github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type testTask struct {
+	ID      bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Title   string        `bson:"title" json:"title"`
+	OwnerID string        `bson:"ownerId" json:"ownerId"`
+}
+
+type testTaskPatchInput struct {
+	Title string `json:"title"`
+}
+
+// Tasks with by-id, batch and aggregate routes guarded by X-User header and scoped to tasks owned by that user
+func newTestTasks(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	allExternals, err := externals.RegisterExternals([]externals.BaseExternal{externals.NewMemoryExternal()})
+
+	if err != nil {
+		t.Fatalf("register externals: %v", err)
+	}
+
+	e := echo.New()
+	e.Validator = &middlewares.CustomValidator{Validator: validator.New()}
+	e.HTTPErrorHandler = middlewares.CustomHTTPErrorHandler
+
+	auth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user := c.Request().Header.Get("X-User")
+
+			if user == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+
+			c.Set("user", user)
+
+			return next(c)
+		}
+	}
+
+	utils.GenerateResourceRoutes[testTask]("tasks", types.GenerateResourceRoutesConfig{
+		Router:          e.Group(""),
+		GetAll:          types.ControllerConfig{Middlewares: []echo.MiddlewareFunc{auth}},
+		GetById:         types.ControllerConfig{Enabled: true},
+		UpdateById:      types.ControllerConfig{Enabled: true},
+		PatchById:       types.ControllerConfig{Enabled: true, InputSchema: testTaskPatchInput{}},
+		DeleteById:      types.ControllerConfig{Enabled: true},
+		RestoreById:     types.ControllerConfig{Enabled: true},
+		SoftDelete:      true,
+		BatchCreate:     types.ControllerConfig{Enabled: true},
+		BatchUpdate:     types.ControllerConfig{Enabled: true},
+		BatchDelete:     types.ControllerConfig{Enabled: true},
+		Aggregate:       types.ControllerConfig{Enabled: true},
+		AggregateFields: []string{"ownerId"},
+		Scope: func(c echo.Context) ([]appTypes.QueryParamsFilter, error) {
+			return []appTypes.QueryParamsFilter{{Field: "ownerId", Operator: appTypes.OpEq, Value: c.Get("user")}}, nil
+		},
+		Externals: allExternals,
+	})
+
+	return e
+}
+
+// Statuses of batch item results in request order
+func batchStatuses(data map[string]any) []int {
+	results, _ := data["results"].([]any)
+	statuses := make([]int, len(results))
+
+	for i, result := range results {
+		statuses[i] = int(result.(map[string]any)["status"].(float64))
+	}
+
+	return statuses
+}
+
+func TestBatchRoutesScope(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         func(own string, other string) string
+		user         string
+		wantStatus   int
+		wantStatuses []int
+		wantTitles   map[string]string // Stored title of own and other task after the request, empty when not found
+	}{
+		{
+			name:       "create requires guard",
+			method:     http.MethodPost,
+			body:       func(own, other string) string { return `[{"title":"new","ownerId":"alice"}]` },
+			wantStatus: http.StatusUnauthorized,
+			wantTitles: map[string]string{"own": "alice task", "other": "bob task"},
+		},
+		{
+			name:       "update requires guard",
+			method:     http.MethodPatch,
+			body:       func(own, other string) string { return fmt.Sprintf(`[{"_id":%q,"title":"changed"}]`, own) },
+			wantStatus: http.StatusUnauthorized,
+			wantTitles: map[string]string{"own": "alice task", "other": "bob task"},
+		},
+		{
+			name:       "delete requires guard",
+			method:     http.MethodDelete,
+			body:       func(own, other string) string { return fmt.Sprintf(`[%q]`, own) },
+			wantStatus: http.StatusUnauthorized,
+			wantTitles: map[string]string{"own": "alice task", "other": "bob task"},
+		},
+		{
+			name:   "update outside of scope",
+			method: http.MethodPatch,
+			body: func(own, other string) string {
+				return fmt.Sprintf(`[{"_id":%q,"title":"changed"},{"_id":%q,"title":"changed"}]`, own, other)
+			},
+			user:         "alice",
+			wantStatus:   http.StatusMultiStatus,
+			wantStatuses: []int{http.StatusOK, http.StatusNotFound},
+			wantTitles:   map[string]string{"own": "changed", "other": "bob task"},
+		},
+		{
+			name:         "delete outside of scope",
+			method:       http.MethodDelete,
+			body:         func(own, other string) string { return fmt.Sprintf(`[%q,%q]`, own, other) },
+			user:         "alice",
+			wantStatus:   http.StatusMultiStatus,
+			wantStatuses: []int{http.StatusNoContent, http.StatusNotFound},
+			wantTitles:   map[string]string{"own": "", "other": "bob task"},
+		},
+		{
+			name:         "ordered batch stops at record outside of scope",
+			method:       http.MethodDelete,
+			body:         func(own, other string) string { return fmt.Sprintf(`[%q,%q]`, other, own) },
+			user:         "alice",
+			wantStatus:   http.StatusMultiStatus,
+			wantStatuses: []int{http.StatusNotFound, http.StatusFailedDependency},
+			wantTitles:   map[string]string{"own": "alice task", "other": "bob task"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestTasks(t)
+			ids := map[string]string{}
+
+			for key, owner := range map[string]string{"own": "alice", "other": "bob"} {
+				status, data := serveJSON(t, e, http.MethodPost, "/tasks/batch", echo.MIMEApplicationJSON, fmt.Sprintf(`[{"title":"%s task","ownerId":%q}]`, owner, owner), http.Header{"X-User": {owner}})
+
+				if status != http.StatusOK {
+					t.Fatalf("create %s task: got status %d, want 200", owner, status)
+				}
+
+				ids[key] = data["results"].([]any)[0].(map[string]any)["data"].(map[string]any)["_id"].(string)
+			}
+
+			header := http.Header{}
+			if test.user != "" {
+				header.Set("X-User", test.user)
+			}
+
+			status, data := serveJSON(t, e, test.method, "/tasks/batch", echo.MIMEApplicationJSON, test.body(ids["own"], ids["other"]), header)
+
+			if status != test.wantStatus {
+				t.Fatalf("got status %d, want %d", status, test.wantStatus)
+			}
+
+			if test.wantStatuses != nil {
+				if got := batchStatuses(data); fmt.Sprint(got) != fmt.Sprint(test.wantStatuses) {
+					t.Errorf("got item statuses %v, want %v", got, test.wantStatuses)
+				}
+			}
+
+			for key, want := range test.wantTitles {
+				owner := map[string]string{"own": "alice", "other": "bob"}[key]
+				status, record := serveJSON(t, e, http.MethodGet, "/tasks/"+ids[key], echo.MIMEApplicationJSON, "", http.Header{"X-User": {owner}})
+
+				got := ""
+				if status == http.StatusOK {
+					got, _ = record["title"].(string)
+				}
+
+				if got != want {
+					t.Errorf("%s task: got title %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateRouteScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		wantStatus int
+		wantGroups []any
+	}{
+		{"requires guard", "", http.StatusUnauthorized, nil},
+		{"groups within scope", "alice", http.StatusOK, []any{map[string]any{"ownerId": "alice", "count": float64(2)}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestTasks(t)
+
+			for _, owner := range []string{"alice", "alice", "bob"} {
+				if status, _ := serveJSON(t, e, http.MethodPost, "/tasks/batch", echo.MIMEApplicationJSON, fmt.Sprintf(`[{"title":"task","ownerId":%q}]`, owner), http.Header{"X-User": {owner}}); status != http.StatusOK {
+					t.Fatalf("create %s task: got status %d, want 200", owner, status)
+				}
+			}
+
+			header := http.Header{}
+			if test.user != "" {
+				header.Set("X-User", test.user)
+			}
+
+			status, response := serveJSON(t, e, http.MethodGet, "/tasks/aggregate?group_by=ownerId", echo.MIMEApplicationJSON, "", header)
+
+			if status != test.wantStatus {
+				t.Fatalf("got status %d, want %d", status, test.wantStatus)
+			}
+
+			if test.wantGroups != nil && !reflect.DeepEqual(response["data"], test.wantGroups) {
+				t.Errorf("got groups %v, want %v", response["data"], test.wantGroups)
+			}
+		})
+	}
+}
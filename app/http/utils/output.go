@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Parse ?fields=title,createdAt intersected with allowed fields, included relations are always kept, nil when every field is requested
+func ParseFields(c echo.Context, allowed []string, includes []string) []string {
+	param := c.QueryParam("fields")
+
+	if strings.TrimSpace(param) == "" {
+		return nil
+	}
+
+	fields := []string{"_id"}
+
+	for _, field := range strings.Split(param, ",") {
+		if field = strings.TrimSpace(field); field == "" || slices.Contains(fields, field) {
+			continue
+		}
+
+		if len(allowed) == 0 || slices.Contains(allowed, field) {
+			fields = append(fields, field)
+		}
+	}
+
+	for _, include := range includes {
+		if name, _, _ := strings.Cut(include, "."); !slices.Contains(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+// Bind resource into fresh instance of output schema (plain json value without schema), keeping only fields when given
+func RenderOutput(resource any, outputSchema any, fields []string) (any, error) {
+	var output any
+
+	if outputSchema != nil {
+		output = newSchemaInstance(outputSchema)
+	}
+
+	if err := utils.BindData(resource, &output); err != nil {
+		return nil, err
+	}
+
+	if fields == nil {
+		return output, nil
+	}
+
+	var trimmed map[string]any
+
+	if err := utils.BindData(output, &trimmed); err != nil {
+		return nil, err
+	}
+
+	for key := range trimmed {
+		if !slices.Contains(fields, key) {
+			delete(trimmed, key)
+		}
+	}
+
+	return trimmed, nil
+}
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/types"
+	repoPkg "github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	appTypes "github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Apply merge patch or JSON Patch request body onto resource, the patched resource is validated before saving
+func patchResource[T any](c echo.Context, repo repoPkg.PatchRepository[T], id string, config types.GenerateResourceRoutesConfig, publish changePublisher) error {
+	body, err := io.ReadAll(c.Request().Body)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	version, preconditionErr := checkIfMatch(c, repo, id, config.Versioned)
+
+	if preconditionErr != nil {
+		return preconditionErr
+	}
+
+	current, err := repo.GetByID(id)
+
+	if err != nil {
+		return echo.NewHTTPError(500, err)
+	}
+
+	if current == nil {
+		return echo.NewHTTPError(404)
+	}
+
+	var doc map[string]any
+
+	if err := utils.BindData(current, &doc); err != nil {
+		return err
+	}
+
+	// Patch is computed against the version just read, a concurrent update in between conflicts instead of being overwritten
+	if currentVersion, ok := doc["version"].(float64); ok && config.Versioned && version == nil {
+		pinned := int64(currentVersion)
+		version = &pinned
+	}
+
+	var (
+		patched  map[string]any
+		update   appTypes.PatchUpdate
+		patchErr error
+	)
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+
+	switch mediaType {
+	case "application/json-patch+json":
+		var operations []repoPkg.PatchOperation
+
+		if err := json.Unmarshal(body, &operations); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Request body must be an array of JSON Patch operations.")
+		}
+
+		patched, update, patchErr = repoPkg.JSONPatch(doc, operations)
+	case "application/merge-patch+json", echo.MIMEApplicationJSON, "":
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+
+		var patch map[string]any
+
+		if err := decoder.Decode(&patch); err != nil || patch == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Request body must be a JSON object.")
+		}
+
+		patched, update, patchErr = repoPkg.MergePatch(doc, repoPkg.NormalizeJSONNumbers(patch).(map[string]any))
+	default:
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported patch content type: %s", mediaType))
+	}
+
+	if patchErr != nil {
+		return RepoHTTPError(patchErr)
+	}
+
+	// Nested resources stay under the parent of the route, like PUT scoping inputs to it
+	if config.Parent != nil && slices.Contains(patchedFields(update), config.Parent.Field) {
+		if belongs, err := belongsToParent(c, config.Parent, patched); err != nil {
+			return err
+		} else if !belongs {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("%s can not be patched", config.Parent.Field))
+		}
+	}
+
+	if field := unknownPatchField(config.PatchById.InputSchema, patchedFields(update)); field != "" {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown field: %s", field))
+	}
+
+	input := newSchemaInstance(config.PatchById.InputSchema)
+
+	if err := utils.BindData(patched, input); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	updated, err := repo.PatchByID(id, version, update)
+
+	if err != nil {
+		return RepoHTTPError(err)
+	}
+
+	if updated == nil {
+		return echo.NewHTTPError(404)
+	}
+
+	publish(appTypes.ChangeUpdated, id, updated)
+
+	if etag, etagErr := ResourceETag(updated, config.Versioned); etagErr == nil {
+		c.Response().Header().Set("ETag", etag)
+	}
+
+	output, err := RenderOutput(updated, config.PatchById.OutputSchema, nil)
+
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(200, echo.Map{"data": output})
+}
+
+// First field which is not declared by the schema, empty when all are known
+func unknownPatchField(schema any, fields []string) string {
+	schemaType := reflect.TypeOf(schema)
+	if schemaType.Kind() == reflect.Pointer {
+		schemaType = schemaType.Elem()
+	}
+
+	if schemaType.Kind() != reflect.Struct {
+		return ""
+	}
+
+	known := map[string]bool{}
+	jsonFieldNames(schemaType, known)
+
+	for _, field := range fields {
+		if !known[field] {
+			return field
+		}
+	}
+
+	return ""
+}
+
+// Collect json names of struct fields, fields of embedded structs are promoted like encoding/json does
+func jsonFieldNames(structType reflect.Type, known map[string]bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			jsonFieldNames(fieldType, known)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		known[name] = true
+	}
+}
+
+// Sorted top level fields touched by update
+func patchedFields(update appTypes.PatchUpdate) []string {
+	var paths []string
+
+	for path := range update.Set {
+		paths = append(paths, path)
+	}
+
+	for path := range update.Push {
+		paths = append(paths, path)
+	}
+
+	for path := range update.Pull {
+		paths = append(paths, path)
+	}
+
+	paths = append(paths, update.Unset...)
+
+	var fields []string
+
+	for _, path := range paths {
+		if field := strings.Split(path, ".")[0]; !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
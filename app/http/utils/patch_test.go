@@ -0,0 +1,173 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/middlewares"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/types"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type testUser struct {
+	ID   bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Name string        `bson:"name" json:"name"`
+}
+
+type testApp struct {
+	ID      bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Name    string        `bson:"name" json:"name"`
+	Tags    []string      `bson:"tags,omitempty" json:"tags,omitempty"`
+	UserID  bson.ObjectID `bson:"userId" json:"userId"`
+	Version int64         `bson:"version,omitempty" json:"version,omitempty"`
+	secret  string
+}
+
+type testAppPatchInput struct {
+	Name   string   `json:"name" validate:"required"`
+	Tags   []string `json:"tags" validate:"max=3"`
+	UserID string   `json:"userId"`
+}
+
+// Users and their apps nested under /users/:user/apps served from memory
+func newTestApps(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	allExternals, err := externals.RegisterExternals([]externals.BaseExternal{externals.NewMemoryExternal()})
+
+	if err != nil {
+		t.Fatalf("register externals: %v", err)
+	}
+
+	e := echo.New()
+
+	validatorInstance := validator.New()
+	validatorInstance.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("json")
+	})
+
+	e.Validator = &middlewares.CustomValidator{Validator: validatorInstance}
+	e.HTTPErrorHandler = middlewares.CustomHTTPErrorHandler
+
+	utils.GenerateResourceRoutes[testUser]("users", types.GenerateResourceRoutesConfig{
+		Router:    e.Group(""),
+		Create:    types.ControllerConfig{Enabled: true},
+		Externals: allExternals,
+	})
+
+	utils.GenerateResourceRoutes[testApp]("apps", types.GenerateResourceRoutesConfig{
+		Router:    e.Group(""),
+		Create:    types.ControllerConfig{Enabled: true},
+		GetById:   types.ControllerConfig{Enabled: true},
+		PatchById: types.ControllerConfig{Enabled: true, InputSchema: testAppPatchInput{}},
+		Versioned: true,
+		Parent:    &types.ParentConfig{Resource: "users", Field: "userId"},
+		Externals: allExternals,
+	})
+
+	return e
+}
+
+func serveJSON(t *testing.T, e *echo.Echo, method string, path string, contentType string, body string, header http.Header) (int, map[string]any) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var response map[string]any
+	json.Unmarshal(rec.Body.Bytes(), &response)
+
+	// Records are wrapped in data, errors are returned as is
+	if data, ok := response["data"].(map[string]any); ok {
+		return rec.Code, data
+	}
+
+	return rec.Code, response
+}
+
+func TestPatchById(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        func(otherUser string) string
+		ifMatch     string
+		wantCode    int
+		wantName    string
+	}{
+		{"merge patch", "application/merge-patch+json", func(string) string { return `{"name": "b"}` }, "", 200, "b"},
+		{"plain json is merge patch", echo.MIMEApplicationJSON, func(string) string { return `{"name": "b", "tags": ["x"]}` }, "", 200, "b"},
+		{"json patch", "application/json-patch+json", func(string) string { return `[{"op": "replace", "path": "/name", "value": "b"}]` }, "", 200, "b"},
+		{"unsupported content type", "text/plain", func(string) string { return `name=b` }, "", 415, "a"},
+		{"body is not an object", echo.MIMEApplicationJSON, func(string) string { return `["name"]` }, "", 400, "a"},
+		{"json patch is not an array", "application/json-patch+json", func(string) string { return `{"op": "add"}` }, "", 400, "a"},
+		{"failing json patch test", "application/json-patch+json", func(string) string {
+			return `[{"op": "test", "path": "/name", "value": "z"}, {"op": "replace", "path": "/name", "value": "b"}]`
+		}, "", 409, "a"},
+		{"invalid json patch path", "application/json-patch+json", func(string) string { return `[{"op": "replace", "path": "/missing", "value": 1}]` }, "", 422, "a"},
+		{"version is managed by repo", echo.MIMEApplicationJSON, func(string) string { return `{"version": 9}` }, "", 422, "a"},
+		{"id is managed by repo", "application/json-patch+json", func(string) string { return `[{"op": "remove", "path": "/_id"}]` }, "", 422, "a"},
+		{"field unknown to schema", echo.MIMEApplicationJSON, func(string) string { return `{"role": "admin"}` }, "", 400, "a"},
+		{"unexported field of model", echo.MIMEApplicationJSON, func(string) string { return `{"secret": "x"}` }, "", 400, "a"},
+		{"schema validation", echo.MIMEApplicationJSON, func(string) string { return `{"name": null}` }, "", 422, "a"},
+		{"schema accepts patch", echo.MIMEApplicationJSON, func(string) string { return `{"name": "b", "tags": ["x"]}` }, "", 200, "b"},
+		{"moved to other parent", echo.MIMEApplicationJSON, func(otherUser string) string { return `{"userId": "` + otherUser + `"}` }, "", 422, "a"},
+		{"parent removed", echo.MIMEApplicationJSON, func(string) string { return `{"userId": null}` }, "", 422, "a"},
+		{"stale if-match", echo.MIMEApplicationJSON, func(string) string { return `{"name": "b"}` }, `"0"`, 412, "a"},
+		{"weak if-match", echo.MIMEApplicationJSON, func(string) string { return `{"name": "b"}` }, `W/"1"`, 412, "a"},
+		{"current if-match", echo.MIMEApplicationJSON, func(string) string { return `{"name": "b"}` }, `"0", "1"`, 200, "b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestApps(t)
+
+			_, owner := serveJSON(t, e, http.MethodPost, "/users", echo.MIMEApplicationJSON, `{"name": "owner"}`, nil)
+			_, other := serveJSON(t, e, http.MethodPost, "/users", echo.MIMEApplicationJSON, `{"name": "other"}`, nil)
+
+			appsPath := "/users/" + owner["_id"].(string) + "/apps"
+			code, app := serveJSON(t, e, http.MethodPost, appsPath, echo.MIMEApplicationJSON, `{"name": "a"}`, nil)
+
+			if code != http.StatusCreated {
+				t.Fatalf("create app: got %d %v", code, app)
+			}
+
+			var header http.Header
+			if test.ifMatch != "" {
+				header = http.Header{"If-Match": {test.ifMatch}}
+			}
+
+			appPath := appsPath + "/" + app["_id"].(string)
+			code, response := serveJSON(t, e, http.MethodPatch, appPath, test.contentType, test.body(other["_id"].(string)), header)
+
+			if code != test.wantCode {
+				t.Fatalf("got %d %v, want %d", code, response, test.wantCode)
+			}
+
+			_, stored := serveJSON(t, e, http.MethodGet, appPath, "", "", nil)
+
+			if stored["name"] != test.wantName {
+				t.Errorf("got name %v, want %s", stored["name"], test.wantName)
+			}
+
+			if stored["userId"] != owner["_id"] {
+				t.Errorf("got userId %v, want %v", stored["userId"], owner["_id"])
+			}
+		})
+	}
+}
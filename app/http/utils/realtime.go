@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/types"
+	repoPkg "github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	appTypes "github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/websocket"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Payload of resource change websocket message
+type ChangePayload struct {
+	ID   string `json:"_id"`
+	Data any    `json:"data,omitempty"` // Record rendered with GetAll OutputSchema
+}
+
+// Start publishing changes of resource to its websocket namespace, returns whether generated controllers must publish their writes
+func startRealtime[T any](hub *websocket.Hub, resourceName string, repo repoPkg.Repository[T], config types.GenerateResourceRoutesConfig) bool {
+	publishFromControllers := true
+
+	// Change streams see writes from every replica and client, only available on replica sets
+	if baseRepo, ok := repo.(*repoPkg.BaseRepo[T]); ok {
+		if err := baseRepo.WatchChanges(context.Background()); err == nil {
+			publishFromControllers = false
+		} else {
+			log.Printf("Change streams unavailable for %s, publishing changes from controllers: %v", resourceName, err)
+		}
+	}
+
+	// Joined through the subscribe route only, which applies route middlewares and Scope
+	if config.Parent != nil {
+		hub.Reserve(fmt.Sprintf("%s/*/%s", config.Parent.Resource, resourceName))
+	} else {
+		hub.Reserve(resourceName)
+	}
+
+	repoPkg.SubscribeChanges(resourceName, func(event appTypes.ChangeEvent) {
+		namespace, ok := changeNamespace(resourceName, config.Parent, event.Document)
+
+		if !ok {
+			return
+		}
+
+		payload := ChangePayload{ID: event.ID}
+
+		if event.Record != nil {
+			output, err := RenderOutput(event.Record, config.GetAll.OutputSchema, nil)
+
+			if err != nil {
+				log.Printf("Change event of %s %s not rendered: %v", resourceName, event.ID, err)
+				return
+			}
+
+			payload.Data = output
+		}
+
+		raw, err := json.Marshal(payload)
+
+		if err == nil {
+			msg := websocket.WSMessage{Namespace: namespace, Event: string(event.Op), Payload: raw}
+
+			// Stored document travels with the message so every replica filters it by subscriber scope
+			if event.Document != nil {
+				msg.Meta, err = bson.MarshalExtJSON(bson.M(event.Document), false, false)
+			}
+
+			if err == nil {
+				hub.Broadcast(msg)
+			}
+		}
+
+		if err != nil {
+			log.Printf("Change event of %s %s not sent: %v", resourceName, event.ID, err)
+		}
+	})
+
+	return publishFromControllers
+}
+
+// Websocket namespace of resource, nested resources have one per parent e.g: users/<id>/apps, unknown when parent of the record is unknown
+func changeNamespace(resourceName string, parent *types.ParentConfig, doc map[string]any) (string, bool) {
+	if parent == nil {
+		return resourceName, true
+	}
+
+	var parentId string
+
+	switch value := doc[parent.Field].(type) {
+	case string:
+		parentId = value
+	case bson.ObjectID:
+		parentId = value.Hex()
+	}
+
+	if parentId == "" {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s/%s/%s", parent.Resource, parentId, resourceName), true
+}
+
+type changePublisher func(op appTypes.ChangeOp, id string, record any)
+
+// Publisher of generated controller writes, no-op when changes are not published by controllers
+func newChangePublisher(resourceName string, enabled bool) changePublisher {
+	return func(op appTypes.ChangeOp, id string, record any) {
+		if !enabled {
+			return
+		}
+
+		doc := repoPkg.ChangeDocument(record)
+
+		if id == "" {
+			switch value := doc["_id"].(type) {
+			case string:
+				id = value
+			case bson.ObjectID:
+				id = value.Hex()
+			}
+		}
+
+		repoPkg.PublishChange(appTypes.ChangeEvent{Collection: resourceName, Op: op, ID: id, Record: record, Document: doc})
+	}
+}
+
+// Upgrade into websocket receiving changes of resource within Scope, nested resources only receive changes of the parent in route
+func subscribeChanges(hub *websocket.Hub, resourceName string, config types.GenerateResourceRoutesConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		namespace := resourceName
+
+		if config.Parent != nil {
+			namespace = fmt.Sprintf("%s/%s/%s", config.Parent.Resource, c.Param(parentParam(config.Parent)), resourceName)
+		}
+
+		scope, err := scopeFilters(c, config)
+
+		if err != nil {
+			return err
+		}
+
+		return hub.SubscribeFiltered(c, namespace, func(msg websocket.WSMessage) bool {
+			if len(scope) == 0 {
+				return true
+			}
+
+			var doc bson.M
+
+			// Unknown record cannot be proven within scope
+			if msg.Meta == nil || bson.UnmarshalExtJSON(msg.Meta, false, &doc) != nil {
+				return false
+			}
+
+			return repoPkg.MatchFilters(doc, scope)
+		})
+	}
+}
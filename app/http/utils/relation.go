@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/types"
+	repoPkg "github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	appTypes "github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/utils"
+
+	"github.com/gertd/go-pluralize"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const defaultMaxIncludeDepth = 2
+
+var (
+	resourceFinders      = map[string]func(id bson.ObjectID) (bool, error){}
+	resourceFindersMutex sync.RWMutex
+)
+
+// Remember how to check existence of generated resource, used by nested resources to validate their parent
+func registerResourceFinder(resourceName string, exists func(id bson.ObjectID) (bool, error)) {
+	resourceFindersMutex.Lock()
+	defer resourceFindersMutex.Unlock()
+
+	resourceFinders[resourceName] = exists
+}
+
+func resourceFinder(resourceName string) (func(id bson.ObjectID) (bool, error), bool) {
+	resourceFindersMutex.RLock()
+	defer resourceFindersMutex.RUnlock()
+
+	exists, ok := resourceFinders[resourceName]
+
+	return exists, ok
+}
+
+// Route path of resource, nested under parent resource when configured e.g: /users/:user/apps
+func resourcePath(resourceName string, parent *types.ParentConfig) string {
+	if parent == nil {
+		return fmt.Sprintf("/%s", resourceName)
+	}
+
+	return fmt.Sprintf("/%s/:%s/%s", parent.Resource, parentParam(parent), resourceName)
+}
+
+func parentParam(parent *types.ParentConfig) string {
+	return pluralize.NewClient().Singular(parent.Resource)
+}
+
+// Validate parent id route param and respond 404 when parent does not exist
+func parentMiddleware(parent *types.ParentConfig, exists func(id bson.ObjectID) (bool, error)) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			parentId, err := bson.ObjectIDFromHex(c.Param(parentParam(parent)))
+
+			if err != nil {
+				return echo.NewHTTPError(400, fmt.Sprintf("Invalid resource identifier: %s", c.Param(parentParam(parent))))
+			}
+
+			found, err := exists(parentId)
+
+			if err != nil {
+				return echo.NewHTTPError(500, err)
+			}
+
+			if !found {
+				return echo.NewHTTPError(404)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// Parent id of nested resource route, already validated by parentMiddleware
+func parentID(c echo.Context, parent *types.ParentConfig) bson.ObjectID {
+	parentId, _ := bson.ObjectIDFromHex(c.Param(parentParam(parent)))
+
+	return parentId
+}
+
+// Whether resource belongs to parent of nested resource route
+func belongsToParent(c echo.Context, parent *types.ParentConfig, resource any) (bool, error) {
+	var fields map[string]any
+
+	if err := utils.BindData(resource, &fields); err != nil {
+		return false, err
+	}
+
+	return fmt.Sprint(fields[parent.Field]) == parentID(c, parent).Hex(), nil
+}
+
+// Set parent field of nested resource inputs to parent id of the route
+func scopeToParent(c echo.Context, parent *types.ParentConfig, inputs any) (any, error) {
+	var fields map[string]any
+
+	if err := utils.BindData(inputs, &fields); err != nil {
+		return nil, err
+	}
+
+	if fields == nil {
+		fields = map[string]any{}
+	}
+
+	fields[parent.Field] = parentID(c, parent)
+
+	return fields, nil
+}
+
+// Parse ?include=author,comments.author rejecting paths nested deeper than max depth
+func ParseIncludes(c echo.Context, maxDepth int) ([]string, error) {
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxIncludeDepth
+	}
+
+	var includes []string
+
+	for _, include := range strings.Split(c.QueryParam("include"), ",") {
+		if include = strings.TrimSpace(include); include == "" {
+			continue
+		}
+
+		if depth := strings.Count(include, ".") + 1; depth > maxDepth {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Include %s exceeds maximum depth of %d.", include, maxDepth))
+		}
+
+		includes = append(includes, include)
+	}
+
+	return includes, nil
+}
+
+// Scope filters to parent of nested resource route
+func parentFilter(c echo.Context, parent *types.ParentConfig) appTypes.QueryParamsFilter {
+	return appTypes.QueryParamsFilter{Field: parent.Field, Operator: appTypes.OpEq, Value: parentID(c, parent)}
+}
+
+// Filters of config Scope hook, none when unset
+func scopeFilters(c echo.Context, config types.GenerateResourceRoutesConfig) ([]appTypes.QueryParamsFilter, error) {
+	if config.Scope == nil {
+		return nil, nil
+	}
+
+	return config.Scope(c)
+}
+
+// Respond 404 when resource looked up by id is outside of Scope, as if it did not exist
+func scopeResourceMiddleware(config types.GenerateResourceRoutesConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			scope, err := scopeFilters(c, config)
+
+			if err != nil {
+				return err
+			}
+
+			if within, err := withinScope(c.Get("resource"), scope); err != nil {
+				return err
+			} else if !within {
+				return echo.NewHTTPError(http.StatusNotFound)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// Whether stored resource matches scope filters, evaluated like change events of realtime subscribers
+func withinScope(resource any, scope []appTypes.QueryParamsFilter) (bool, error) {
+	if len(scope) == 0 {
+		return true, nil
+	}
+
+	bytes, err := bson.Marshal(resource)
+
+	if err != nil {
+		return false, err
+	}
+
+	var doc bson.M
+
+	if err := bson.Unmarshal(bytes, &doc); err != nil {
+		return false, err
+	}
+
+	return repoPkg.MatchFilters(doc, scope), nil
+}
@@ -0,0 +1,70 @@
+package utils_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestByIdRoutesScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		user       string
+		wantStatus int
+		wantTitle  string // Stored title afterwards, empty when deleted
+	}{
+		{"get own", http.MethodGet, "", "", "alice", http.StatusOK, "task"},
+		{"get other", http.MethodGet, "", "", "bob", http.StatusNotFound, "task"},
+		{"get requires guard", http.MethodGet, "", "", "", http.StatusUnauthorized, "task"},
+		{"update other", http.MethodPut, "", `{"title":"changed","ownerId":"alice"}`, "bob", http.StatusNotFound, "task"},
+		{"update own", http.MethodPut, "", `{"title":"changed","ownerId":"alice"}`, "alice", http.StatusOK, "changed"},
+		{"patch other", http.MethodPatch, "", `{"title":"changed"}`, "bob", http.StatusNotFound, "task"},
+		{"patch own", http.MethodPatch, "", `{"title":"changed"}`, "alice", http.StatusOK, "changed"},
+		{"delete other", http.MethodDelete, "", "", "bob", http.StatusNotFound, "task"},
+		{"delete own", http.MethodDelete, "", "", "alice", http.StatusNoContent, ""},
+		{"restore other", http.MethodPost, "/restore", "", "bob", http.StatusNotFound, "task"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := newTestTasks(t)
+
+			_, created := serveJSON(t, e, http.MethodPost, "/tasks/batch", echo.MIMEApplicationJSON, `[{"title":"task","ownerId":"alice"}]`, http.Header{"X-User": {"alice"}})
+			taskPath := "/tasks/" + created["results"].([]any)[0].(map[string]any)["data"].(map[string]any)["_id"].(string)
+
+			if test.path == "/restore" {
+				if status, _ := serveJSON(t, e, http.MethodDelete, taskPath, "", "", http.Header{"X-User": {"alice"}}); status != http.StatusNoContent {
+					t.Fatalf("delete before restore: got status %d", status)
+				}
+			}
+
+			header := http.Header{}
+			if test.user != "" {
+				header.Set("X-User", test.user)
+			}
+
+			if status, response := serveJSON(t, e, test.method, taskPath+test.path, echo.MIMEApplicationJSON, test.body, header); status != test.wantStatus {
+				t.Fatalf("got status %d %v, want %d", status, response, test.wantStatus)
+			}
+
+			if test.path == "/restore" {
+				serveJSON(t, e, http.MethodPost, taskPath+"/restore", "", "", http.Header{"X-User": {"alice"}})
+			}
+
+			status, stored := serveJSON(t, e, http.MethodGet, taskPath, "", "", http.Header{"X-User": {"alice"}})
+
+			got := ""
+			if status == http.StatusOK {
+				got, _ = stored["title"].(string)
+			}
+
+			if got != test.wantTitle {
+				t.Errorf("got title %q, want %q", got, test.wantTitle)
+			}
+		})
+	}
+}
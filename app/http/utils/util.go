@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha1"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,11 +17,13 @@ import (
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/types"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
-	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	repoPkg "github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
 	appTypes "github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/utils"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/websocket"
 
 	"github.com/gertd/go-pluralize"
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -51,31 +55,111 @@ func PrintRoutes(e *echo.Echo) {
 	w.Flush()
 }
 
+// Text index over searchable fields of resource
+func searchIndexes(config types.GenerateResourceRoutesConfig) []appTypes.IndexSpec {
+	if len(config.Search) == 0 {
+		return nil
+	}
+
+	return []appTypes.IndexSpec{{Fields: config.Search, Text: true}}
+}
+
 // Generate CRUD resource routes, must pass type T, usually the model stuct of the intended data
 func GenerateResourceRoutes[T any](resourceName string, config types.GenerateResourceRoutesConfig) {
 	pluralize := pluralize.NewClient()
 	resourceNameSingular := pluralize.Singular(resourceName)
 
-	mongoExt, mongoExtError := externals.GetExternal[*externals.MongoDBExternal](config.Externals)
+	repo, repoErr := repoPkg.NewResourceRepo[T](externals.ResourceDB(config.Externals), resourceName, repoPkg.ResourceRepoOptions{
+		SoftDelete: config.SoftDelete,
+		Versioned:  config.Versioned,
+		Indexes:    searchIndexes(config),
+	})
 
-	if mongoExtError != nil {
-		log.Fatalf("%v", mongoExtError)
+	if repoErr != nil {
+		log.Fatalf("%v", repoErr)
 		return
 	}
 
-	repo := &repo.UserRepo[T]{
-		BaseRepo: &repo.BaseRepo[T]{
-			DB: appTypes.AppDB{
-				MongoDB: mongoExt.DB,
-			},
-			Collection: resourceName,
-			UpdatedAt:  true,
-			CreatedAt:  true,
-		},
+	if syncer, ok := repo.(repoPkg.IndexSyncer); ok {
+		repoPkg.RegisterIndexes(resourceName, syncer)
+	}
+
+	if len(config.Relations) > 0 {
+		repoPkg.RegisterRelations(resourceName, config.Relations...)
+	}
+
+	registerResourceFinder(resourceName, func(id bson.ObjectID) (bool, error) {
+		resource, err := repo.GetByID(id)
+		return resource != nil, err
+	})
+
+	basePath := resourcePath(resourceName, config.Parent)
+
+	var parentMiddlewares []echo.MiddlewareFunc
+
+	if config.Parent != nil {
+		exists, ok := resourceFinder(config.Parent.Resource)
+
+		if !ok {
+			log.Fatalf("parent resource %s of %s must be generated first", config.Parent.Resource, resourceName)
+			return
+		}
+
+		parentMiddlewares = append(parentMiddlewares, parentMiddleware(config.Parent, exists))
+	}
+
+	publish := newChangePublisher(resourceName, false)
+
+	if config.Realtime {
+		hub, hubErr := externals.GetExternal[*websocket.Hub](config.Externals)
+
+		if hubErr != nil {
+			log.Fatalf("realtime %s requires a websocket hub registered as external", resourceName)
+			return
+		}
+
+		publish = newChangePublisher(resourceName, startRealtime(hub, resourceName, repo, config))
+
+		config.Router.Group(basePath, parentMiddlewares...).GET("/subscribe", func(c echo.Context) error {
+			handler := subscribeChanges(hub, resourceName, config)
+
+			for _, middleware := range config.GetAll.Middlewares {
+				handler = middleware(handler)
+			}
+
+			return handler(c)
+		})
+	}
+
+	if config.BatchCreate.Enabled || config.BatchUpdate.Enabled || config.BatchDelete.Enabled {
+		if config.Parent != nil {
+			log.Fatalf("batch routes for %s are not supported on nested resources", resourceName)
+			return
+		}
+
+		bulkRepo, ok := repo.(repoPkg.BulkRepository[T])
+
+		if !ok {
+			log.Fatalf("batch routes for %s require a repo supporting bulk operations", resourceName)
+			return
+		}
+
+		generateBatchRoutes(config.Router.Group(fmt.Sprintf("/%s/batch", resourceName)), bulkRepo, config, publish)
+	}
+
+	if config.Aggregate.Enabled {
+		aggregateRepo, ok := repo.(repoPkg.AggregateRepository[T])
+
+		if !ok {
+			log.Fatalf("aggregate route for %s requires a repo supporting aggregation", resourceName)
+			return
+		}
+
+		generateAggregateRoute(config.Router.Group(basePath, parentMiddlewares...), aggregateRepo, config)
 	}
 
 	if config.Create.Enabled || config.GetAll.Enabled {
-		routesWithoutId := config.Router.Group(fmt.Sprintf("/%s", resourceName))
+		routesWithoutId := config.Router.Group(basePath, parentMiddlewares...)
 
 		if config.Create.Enabled {
 			if config.Create.Override != nil {
@@ -107,20 +191,28 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 							}
 						}
 
+						if config.Parent != nil {
+							scoped, err := scopeToParent(c, config.Parent, inputs)
+
+							if err != nil {
+								return err
+							}
+
+							inputs = scoped
+						}
+
 						created, createdErr := repo.Create(inputs)
 
 						if createdErr != nil {
-							return echo.NewHTTPError(500, createdErr)
+							return RepoHTTPError(createdErr)
 						}
 
-						var output any
+						publish(appTypes.ChangeCreated, "", created)
 
-						if config.Create.OutputSchema != nil {
-							output = &config.Create.OutputSchema
-						}
+						output, outputErr := RenderOutput(created, config.Create.OutputSchema, nil)
 
-						if err := utils.BindData(created, &output); err != nil {
-							return err
+						if outputErr != nil {
+							return outputErr
 						}
 
 						return c.JSON(201, echo.Map{"data": output})
@@ -150,7 +242,7 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 			} else {
 				routesWithoutId.GET("", func(c echo.Context) error {
 					handler := func(c echo.Context) error {
-						filters, sorts := ParseQueryParams(c.QueryParams())
+						filtersAndSorts := ParseQueryParams(c.QueryParams())
 						pageString := c.QueryParam("page")
 						perPageString := c.QueryParam("per_page")
 
@@ -175,13 +267,47 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 							}
 						}
 
-						all, getAllErr := repo.GetAll(true, &appTypes.GetAllFiltersAndSorts{QueryParamsFilters: filters, QueryParamsSortFields: sorts}, &appTypes.PaginationParams{
+						deletedMode, deletedModeErr := ParseDeletedMode(c.QueryParams())
+
+						if deletedModeErr != nil {
+							return deletedModeErr
+						}
+
+						includes, includesErr := ParseIncludes(c, config.MaxIncludeDepth)
+
+						if includesErr != nil {
+							return includesErr
+						}
+
+						fields := ParseFields(c, config.SelectableFields, includes)
+
+						if filtersAndSorts.Search != "" && len(config.Search) == 0 {
+							return echo.NewHTTPError(400, "Search is not enabled for this resource.")
+						}
+
+						filtersAndSorts.DeletedMode = deletedMode
+						filtersAndSorts.Includes = includes
+						filtersAndSorts.Fields = fields
+
+						if config.Parent != nil {
+							filtersAndSorts.QueryParamsFilters = append(filtersAndSorts.QueryParamsFilters, parentFilter(c, config.Parent))
+						}
+
+						scope, scopeErr := scopeFilters(c, config)
+
+						if scopeErr != nil {
+							return scopeErr
+						}
+
+						filtersAndSorts.QueryParamsFilters = append(filtersAndSorts.QueryParamsFilters, scope...)
+
+						all, getAllErr := repo.GetAll(true, filtersAndSorts, &appTypes.PaginationParams{
 							Page:    page,
 							PerPage: perPage,
 						})
 
 						if getAllErr != nil {
-							return echo.NewHTTPError(500, getAllErr)
+							return RepoHTTPError(getAllErr)
 						}
 
 						var outputResults struct {
@@ -196,11 +322,11 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 							return err
 						}
 
-						if config.GetAll.OutputSchema != nil {
-							var records []any
+						if config.GetAll.OutputSchema != nil || fields != nil {
+							records := []any{}
 							for _, record := range all.Records {
-								output := &config.GetAll.OutputSchema
-								if err := utils.BindData(record, output); err != nil {
+								output, err := RenderOutput(record, config.GetAll.OutputSchema, fields)
+								if err != nil {
 									return err
 								}
 
@@ -226,8 +352,29 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 
 	}
 
-	if config.GetById.Enabled || config.UpdateById.Enabled || config.DeleteById.Enabled {
-		routesWithId := config.Router.Group(fmt.Sprintf("/%s/:%s", resourceName, resourceNameSingular))
+	softDeleteRepo, softDeleteEnabled := repo.(repoPkg.SoftDeleteRepository[T])
+	softDeleteEnabled = softDeleteEnabled && config.SoftDelete
+
+	if config.RestoreById.Enabled && !softDeleteEnabled {
+		log.Fatalf("restore route for %s requires SoftDelete to be enabled", resourceName)
+		return
+	}
+
+	patchRepo, patchEnabled := repo.(repoPkg.PatchRepository[T])
+
+	if config.PatchById.Enabled && config.PatchById.Override == nil && !patchEnabled {
+		log.Fatalf("patch route for %s requires a repo supporting patches", resourceName)
+		return
+	}
+
+	// Fields of the schema are the only patchable ones, T would expose fields such as passwords to clients
+	if config.PatchById.Enabled && config.PatchById.Override == nil && config.PatchById.InputSchema == nil {
+		log.Fatalf("patch route for %s requires an InputSchema declaring the patchable fields", resourceName)
+		return
+	}
+
+	if config.GetById.Enabled || config.UpdateById.Enabled || config.PatchById.Enabled || config.DeleteById.Enabled || config.RestoreById.Enabled {
+		routesWithId := config.Router.Group(fmt.Sprintf("%s/:%s", basePath, resourceNameSingular), parentMiddlewares...)
 
 		routesWithId.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 			return func(c echo.Context) error {
@@ -237,7 +384,20 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 					return echo.NewHTTPError(400, fmt.Sprintf("Invalid resource identifier: %s", c.Param(resourceNameSingular)))
 				}
 
-				resource, getByIdErr := repo.GetByID(objectId)
+				deletedMode, deletedModeErr := ParseDeletedMode(c.QueryParams())
+
+				if deletedModeErr != nil {
+					return deletedModeErr
+				}
+
+				getByID := repo.GetByID
+
+				// Soft deleted resource is only reachable for restore or when explicitly asked for
+				if softDeleteEnabled && (deletedMode != appTypes.DeletedExclude || strings.HasSuffix(c.Path(), "/restore")) {
+					getByID = softDeleteRepo.GetByIDWithDeleted
+				}
+
+				resource, getByIdErr := getByID(objectId)
 
 				if getByIdErr != nil {
 					return echo.NewHTTPError(500, getByIdErr)
@@ -247,15 +407,31 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 					return echo.NewHTTPError(404)
 				}
 
+				if config.Parent != nil {
+					if belongs, err := belongsToParent(c, config.Parent, resource); err != nil {
+						return err
+					} else if !belongs {
+						return echo.NewHTTPError(404)
+					}
+				}
+
+				// Stored record, ETag of GET follows it whatever fields or includes shape the response
+				c.Set("resource", resource)
+
 				return next(c)
 			}
 		})
 
+		// Runs within GetAll middlewares of every route so Scope sees the auth user
+		scopeResource := scopeResourceMiddleware(config)
+
 		if config.GetById.Enabled {
 			if config.GetById.Override != nil {
 				routesWithId.GET("", func(c echo.Context) error {
 					handler := config.GetById.Override
 
+					handler = scopeResource(handler)
+
 					for _, middleware := range config.GetAll.Middlewares {
 						handler = middleware(handler)
 					}
@@ -265,15 +441,70 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 			} else {
 				routesWithId.GET("", func(c echo.Context) error {
 					handler := func(c echo.Context) error {
-						all, getAllErr := repo.GetByID(c.Param(resourceNameSingular))
+						getByID := repo.GetByID
+						deletedMode, _ := ParseDeletedMode(c.QueryParams())
+
+						if softDeleteEnabled && deletedMode != appTypes.DeletedExclude {
+							getByID = softDeleteRepo.GetByIDWithDeleted
+						} else {
+							deletedMode = appTypes.DeletedExclude
+						}
+
+						includes, includesErr := ParseIncludes(c, config.MaxIncludeDepth)
+
+						if includesErr != nil {
+							return includesErr
+						}
+
+						fields := ParseFields(c, config.SelectableFields, includes)
+
+						if len(includes) > 0 || fields != nil {
+							lookupRepo, ok := repo.(repoPkg.LookupRepository[T])
+
+							if !ok {
+								return echo.NewHTTPError(400, "Includes and fields are not supported by this resource.")
+							}
+
+							getByID = func(id any) (*T, error) {
+								return lookupRepo.GetByIDWithOptions(id, appTypes.GetByIDOptions{DeletedMode: deletedMode, Includes: includes, Fields: fields})
+							}
+						}
+
+						all, getAllErr := getByID(c.Param(resourceNameSingular))
 
 						if getAllErr != nil {
-							return echo.NewHTTPError(500, getAllErr)
+							return RepoHTTPError(getAllErr)
+						}
+
+						stored, ok := c.Get("resource").(*T)
+
+						if !ok {
+							stored = all
 						}
 
-						return c.JSON(200, echo.Map{"data": all})
+						etag, etagErr := ResourceETag(stored, config.Versioned)
+
+						if etagErr != nil {
+							return etagErr
+						}
+
+						c.Response().Header().Set("ETag", etag)
+
+						if ifNoneMatch := c.Request().Header.Get("If-None-Match"); ifNoneMatch != "" && ETagMatches(ifNoneMatch, etag, true) {
+							return c.NoContent(304)
+						}
+
+						output, outputErr := RenderOutput(all, config.GetById.OutputSchema, fields)
+
+						if outputErr != nil {
+							return outputErr
+						}
+
+						return c.JSON(200, echo.Map{"data": output})
 					}
 
+					handler = scopeResource(handler)
+
 					for _, middleware := range config.GetAll.Middlewares {
 						handler = middleware(handler)
 					}
@@ -290,6 +521,8 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 				routesWithId.PUT("", func(c echo.Context) error {
 					handler := config.UpdateById.Override
 
+					handler = scopeResource(handler)
+
 					for _, middleware := range config.GetAll.Middlewares {
 						handler = middleware(handler)
 					}
@@ -315,15 +548,94 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 							}
 						}
 
-						updated, updatedErr := repo.UpdateByID(c.Param(resourceNameSingular), inputs)
+						if config.Parent != nil {
+							scoped, err := scopeToParent(c, config.Parent, inputs)
+
+							if err != nil {
+								return err
+							}
+
+							inputs = scoped
+						}
+
+						version, preconditionErr := checkIfMatch(c, repo, c.Param(resourceNameSingular), config.Versioned)
+
+						if preconditionErr != nil {
+							return preconditionErr
+						}
+
+						var (
+							updated    *T
+							updatedErr error
+						)
+
+						if versionedRepo, ok := repo.(repoPkg.VersionedRepository[T]); ok && version != nil {
+							updated, updatedErr = versionedRepo.UpdateByIDIfVersion(c.Param(resourceNameSingular), *version, inputs)
+						} else {
+							updated, updatedErr = repo.UpdateByID(c.Param(resourceNameSingular), inputs)
+						}
 
 						if updatedErr != nil {
-							return echo.NewHTTPError(500, updatedErr)
+							return RepoHTTPError(updatedErr)
+						}
+
+						publish(appTypes.ChangeUpdated, "", updated)
+
+						if etag, etagErr := ResourceETag(updated, config.Versioned); etagErr == nil {
+							c.Response().Header().Set("ETag", etag)
+						}
+
+						output, outputErr := RenderOutput(updated, config.UpdateById.OutputSchema, nil)
+
+						if outputErr != nil {
+							return outputErr
 						}
 
-						return c.JSON(200, echo.Map{"data": updated})
+						return c.JSON(200, echo.Map{"data": output})
 					}
 
+					handler = scopeResource(handler)
+
+					for _, middleware := range config.GetAll.Middlewares {
+						handler = middleware(handler)
+					}
+
+					return handler(c)
+				})
+			}
+		}
+
+		if config.PatchById.Enabled {
+			if config.PatchById.Override != nil {
+				routesWithId.PATCH("", func(c echo.Context) error {
+					handler := config.PatchById.Override
+
+					// Guarded like PUT, PatchById middlewares only add to it
+					for _, middleware := range config.PatchById.Middlewares {
+						handler = middleware(handler)
+					}
+
+					handler = scopeResource(handler)
+
+					for _, middleware := range config.GetAll.Middlewares {
+						handler = middleware(handler)
+					}
+
+					return handler(c)
+				})
+			} else {
+				routesWithId.PATCH("", func(c echo.Context) error {
+					handler := func(c echo.Context) error {
+						return patchResource(c, patchRepo, c.Param(resourceNameSingular), config, publish)
+					}
+
+					// Guarded like PUT, PatchById middlewares only add to it
+					for _, middleware := range config.PatchById.Middlewares {
+						handler = middleware(handler)
+					}
+
+					handler = scopeResource(handler)
+
 					for _, middleware := range config.GetAll.Middlewares {
 						handler = middleware(handler)
 					}
@@ -338,6 +650,8 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 				routesWithId.DELETE("", func(c echo.Context) error {
 					handler := config.DeleteById.Override
 
+					handler = scopeResource(handler)
+
 					for _, middleware := range config.GetAll.Middlewares {
 						handler = middleware(handler)
 					}
@@ -347,15 +661,33 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 			} else {
 				routesWithId.DELETE("", func(c echo.Context) error {
 					handler := func(c echo.Context) error {
+						if _, preconditionErr := checkIfMatch(c, repo, c.Param(resourceNameSingular), config.Versioned); preconditionErr != nil {
+							return preconditionErr
+						}
+
+						existing, getByIdErr := repo.GetByID(c.Param(resourceNameSingular))
+
+						if getByIdErr != nil {
+							return echo.NewHTTPError(500, getByIdErr)
+						}
+
+						if existing == nil {
+							return echo.NewHTTPError(404)
+						}
+
 						_, deleteByIdErr := repo.DeleteByID(c.Param(resourceNameSingular))
 
 						if deleteByIdErr != nil {
 							return echo.NewHTTPError(500, deleteByIdErr)
 						}
 
+						publish(appTypes.ChangeDeleted, c.Param(resourceNameSingular), existing)
+
 						return c.JSON(204, echo.Map{})
 					}
 
+					handler = scopeResource(handler)
+
 					for _, middleware := range config.GetAll.Middlewares {
 						handler = middleware(handler)
 					}
@@ -364,7 +696,206 @@ func GenerateResourceRoutes[T any](resourceName string, config types.GenerateRes
 				})
 			}
 		}
+
+		if config.RestoreById.Enabled {
+			if config.RestoreById.Override != nil {
+				routesWithId.POST("/restore", func(c echo.Context) error {
+					handler := config.RestoreById.Override
+
+					// Guarded like DELETE, RestoreById middlewares only add to it
+					for _, middleware := range config.RestoreById.Middlewares {
+						handler = middleware(handler)
+					}
+
+					handler = scopeResource(handler)
+
+					for _, middleware := range config.GetAll.Middlewares {
+						handler = middleware(handler)
+					}
+
+					return handler(c)
+				})
+			} else {
+				routesWithId.POST("/restore", func(c echo.Context) error {
+					handler := func(c echo.Context) error {
+						restored, restoreErr := softDeleteRepo.Restore(c.Param(resourceNameSingular))
+
+						if restoreErr != nil {
+							return RepoHTTPError(restoreErr)
+						}
+
+						if restored == nil {
+							return echo.NewHTTPError(409, "Resource is not deleted.")
+						}
+
+						publish(appTypes.ChangeRestored, "", restored)
+
+						output, outputErr := RenderOutput(restored, config.RestoreById.OutputSchema, nil)
+
+						if outputErr != nil {
+							return outputErr
+						}
+
+						return c.JSON(200, echo.Map{"data": output})
+					}
+
+					// Guarded like DELETE, RestoreById middlewares only add to it
+					for _, middleware := range config.RestoreById.Middlewares {
+						handler = middleware(handler)
+					}
+
+					handler = scopeResource(handler)
+
+					for _, middleware := range config.GetAll.Middlewares {
+						handler = middleware(handler)
+					}
+
+					return handler(c)
+				})
+			}
+		}
+	}
+}
+
+// Map repo error into http error, unique conflicts are left for the error handler to render with the offending field
+func RepoHTTPError(err error) error {
+	if errors.As(err, &repoPkg.ErrConflict{}) {
+		return err
+	}
+
+	if errors.Is(err, repoPkg.ErrVersionConflict) {
+		return echo.NewHTTPError(409, "Resource has been modified by another request.")
+	}
+
+	if errors.Is(err, repoPkg.ErrPatchTestFailed) {
+		return echo.NewHTTPError(409, err.Error())
+	}
+
+	if invalidPatch := (repoPkg.ErrInvalidPatch{}); errors.As(err, &invalidPatch) {
+		return echo.NewHTTPError(422, invalidPatch.Message)
+	}
+
+	if invalidInclude := (repoPkg.ErrInvalidInclude{}); errors.As(err, &invalidInclude) {
+		return echo.NewHTTPError(400, invalidInclude.Message)
+	}
+
+	if errors.Is(err, repoPkg.ErrSearchUnsupported) {
+		return echo.NewHTTPError(400, "Search is not supported by this resource.")
+	}
+
+	if errors.Is(err, repoPkg.ErrAggregateUnsupported) {
+		return echo.NewHTTPError(400, "Aggregation is not supported by this resource.")
+	}
+
+	return echo.NewHTTPError(500, err)
+}
+
+// Compute ETag of stored resource, follows version field for versioned resources otherwise hash of the stored document
+func ResourceETag(resource any, versioned bool) (string, error) {
+	if versioned {
+		var fields map[string]any
+
+		if err := utils.BindData(resource, &fields); err != nil {
+			return "", err
+		}
+
+		if version, ok := fields["version"].(float64); ok {
+			return fmt.Sprintf(`"%d"`, int64(version)), nil
+		}
+	}
+
+	// Stored shape, fields hidden from json output still change the tag
+	bytes, err := bson.Marshal(resource)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`"%x"`, sha1.Sum(bytes)), nil
+}
+
+// Check whether If-Match / If-None-Match header value matches the etag, weak comparison (RFC 9110) is only allowed for If-None-Match
+func ETagMatches(header string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if strings.HasPrefix(candidate, "W/") {
+			// Weak validators never match under strong comparison
+			if !weak {
+				continue
+			}
+
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == "*" || candidate == etag {
+			return true
+		}
 	}
+
+	return false
+}
+
+// Validate If-Match precondition against current resource, returns expected version for versioned resources
+func checkIfMatch[T any](c echo.Context, repo repoPkg.Repository[T], id string, versioned bool) (*int64, error) {
+	ifMatch := c.Request().Header.Get("If-Match")
+
+	if ifMatch == "" {
+		return nil, nil
+	}
+
+	current, err := repo.GetByID(id)
+
+	if err != nil {
+		return nil, echo.NewHTTPError(500, err)
+	}
+
+	etag, err := ResourceETag(current, versioned)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ETagMatches(ifMatch, etag, false) {
+		return nil, echo.NewHTTPError(412, "Resource has been modified, precondition failed.")
+	}
+
+	if !versioned || strings.TrimSpace(ifMatch) == "*" {
+		return nil, nil
+	}
+
+	// Pin the update to the matched version so a concurrent update in between still conflicts
+	version, err := strconv.ParseInt(strings.Trim(etag, `"`), 10, 64)
+
+	if err != nil {
+		return nil, nil
+	}
+
+	return &version, nil
+}
+
+// Parse soft deleted visibility from ?with_deleted=true or ?only_deleted=true query params
+func ParseDeletedMode(query url.Values) (appTypes.DeletedMode, error) {
+	params := []struct {
+		name string
+		mode appTypes.DeletedMode
+	}{{"only_deleted", appTypes.DeletedOnly}, {"with_deleted", appTypes.DeletedWith}}
+
+	for _, param := range params {
+		if value := query.Get(param.name); value != "" {
+			enabled, err := strconv.ParseBool(value)
+
+			if err != nil {
+				return appTypes.DeletedExclude, echo.NewHTTPError(400, fmt.Sprintf("Invalid query param: %s", param.name))
+			}
+
+			if enabled {
+				return param.mode, nil
+			}
+		}
+	}
+
+	return appTypes.DeletedExclude, nil
 }
 
 func ValidateInput(c echo.Context, schemaData interface{}) error {
@@ -378,6 +909,36 @@ func ValidateInput(c echo.Context, schemaData interface{}) error {
 	return nil
 }
 
+// Format validation errors keyed by json field name with readable messages
+func FormatValidationErrors(errs validator.ValidationErrors) map[string]string {
+	errors := make(map[string]string)
+	for _, err := range errs {
+		field := err.Field() // this will now return the `json` tag value
+		errors[field] = fmt.Sprintf("%s %s", field, validationMessage(err))
+	}
+	return errors
+}
+
+func validationMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is a required field."
+	case "email":
+		return "is not a valid email."
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s.", err.Param())
+	case "eqfield":
+		return fmt.Sprintf("does not match with %s.", NormalizeFieldName(err.Param()))
+	case "min":
+		return fmt.Sprintf("must be a minimum of %s character(s).", err.Param())
+	case "len":
+		return fmt.Sprintf("must be of %s character(s).", err.Param())
+	// Add more cases as needed
+	default:
+		return fmt.Sprintf("not valid (%s)", err.Tag())
+	}
+}
+
 func NormalizeFieldName(s string) string {
 	if s == "" {
 		return s
@@ -391,12 +952,16 @@ func GetAuthUser(c echo.Context) *models.User {
 	return c.Get("auth").(*models.User)
 }
 
-func ParseQueryParams(query url.Values) ([]appTypes.QueryParamsFilter, []appTypes.QueryParamsSortField) {
-	var filters []appTypes.QueryParamsFilter
-	var sorts []appTypes.QueryParamsSortField
+func ParseQueryParams(query url.Values) *appTypes.GetAllFiltersAndSorts {
+	builder := repoPkg.Query[any]()
+
+	var anyOf []appTypes.QueryGroup
 
-	// Parse filters
+	// Parse filters, or.filter.* params form a single or group
 	for key, values := range query {
+		or := strings.HasPrefix(key, "or.")
+		key = strings.TrimPrefix(key, "or.")
+
 		if !strings.HasPrefix(key, "filter.") {
 			continue
 		}
@@ -410,32 +975,30 @@ func ParseQueryParams(query url.Values) ([]appTypes.QueryParamsFilter, []appType
 		}
 
 		for _, val := range values {
-			filters = append(filters, appTypes.QueryParamsFilter{
-				Field:    field,
-				Operator: operator,
-				Value:    val,
-			})
+			if or {
+				anyOf = append(anyOf, repoPkg.Where(field, operator, val))
+			} else {
+				builder.Where(field, operator, val)
+			}
 		}
 	}
 
+	if len(anyOf) > 0 {
+		builder.Or(anyOf...)
+	}
+
+	if search := strings.TrimSpace(query.Get("q")); search != "" {
+		builder.Search(search)
+	}
+
 	// Parse sort
 	if sortQuery := query.Get("sort"); sortQuery != "" {
 		for _, s := range strings.Split(sortQuery, ",") {
-			s = strings.TrimSpace(s)
-			if s == "" {
-				continue
-			}
-			desc := false
-			if strings.HasPrefix(s, "-") {
-				desc = true
-				s = s[1:]
+			if s = strings.TrimSpace(s); s != "" {
+				builder.Sort(s)
 			}
-			sorts = append(sorts, appTypes.QueryParamsSortField{
-				Field:      s,
-				Descending: desc,
-			})
 		}
 	}
 
-	return filters, sorts
+	return builder.Build()
 }
@@ -0,0 +1,31 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/http/utils"
+)
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"1"`, false, true},
+		{`"2", "1"`, false, true},
+		{`"2"`, false, false},
+		{`*`, false, true},
+		{`W/"1"`, false, false},
+		{`W/"2", W/"1"`, false, false},
+		{`W/"1"`, true, true},
+		{`W/"2", "1"`, true, true},
+		{`W/"2"`, true, false},
+	}
+
+	for _, test := range tests {
+		if got := utils.ETagMatches(test.header, `"1"`, test.weak); got != test.want {
+			t.Errorf("header %s weak %v: got %v, want %v", test.header, test.weak, got, test.want)
+		}
+	}
+}
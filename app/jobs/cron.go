@@ -0,0 +1,194 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// When a task runs next, after the given time
+type schedule interface {
+	next(after time.Time) time.Time
+}
+
+type intervalSchedule struct {
+	every time.Duration
+}
+
+func (s intervalSchedule) next(after time.Time) time.Time {
+	return after.Add(s.every)
+}
+
+// Standard 5 field cron expression, fields are bitsets of allowed values
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+	location                      *time.Location
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+
+var dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// Parse cron expression e.g: "*/15 * * * *", "0 3 * * mon-fri", "@daily" or "@every 90s"
+func parseCron(expression string, location *time.Location) (schedule, error) {
+	expression = strings.TrimSpace(expression)
+
+	if every, ok := strings.CutPrefix(expression, "@every "); ok {
+		duration, err := time.ParseDuration(strings.TrimSpace(every))
+
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid cron interval %q", every)
+		}
+
+		return intervalSchedule{every: duration}, nil
+	}
+
+	if descriptor, ok := cronDescriptors[strings.ToLower(expression)]; ok {
+		expression = descriptor
+	}
+
+	fields := strings.Fields(expression)
+
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expression)
+	}
+
+	s := &cronSchedule{location: location}
+
+	var err error
+
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+
+	if s.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+
+	if s.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+
+	// 7 is sunday too
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domStar = strings.HasPrefix(fields[2], "*") || fields[2] == "?"
+	s.dowStar = strings.HasPrefix(fields[4], "*") || fields[4] == "?"
+
+	return s, nil
+}
+
+// Parse comma separated values, ranges and steps e.g: 1,15 or 1-5 or */10 or 10-40/5
+func parseCronField(field string, first int, last int, names map[string]int) (uint64, error) {
+	var bits uint64
+
+	for part := range strings.SplitSeq(field, ",") {
+		valueRange, stepValue, hasStep := strings.Cut(part, "/")
+		low, high := first, last
+
+		if valueRange != "*" && valueRange != "?" {
+			lowValue, highValue, isRange := strings.Cut(valueRange, "-")
+
+			var err error
+
+			if low, err = parseCronValue(lowValue, names); err != nil {
+				return 0, fmt.Errorf("invalid cron field %q", field)
+			}
+
+			high = low
+
+			if isRange {
+				if high, err = parseCronValue(highValue, names); err != nil {
+					return 0, fmt.Errorf("invalid cron field %q", field)
+				}
+			} else if hasStep {
+				high = last
+			}
+		}
+
+		step := 1
+
+		if hasStep {
+			var err error
+
+			if step, err = strconv.Atoi(stepValue); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid cron step %q", field)
+			}
+		}
+
+		if low < first || high > last || low > high {
+			return 0, fmt.Errorf("cron field %q is out of range %d-%d", field, first, last)
+		}
+
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if number, ok := names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+
+	return strconv.Atoi(value)
+}
+
+func (s *cronSchedule) next(after time.Time) time.Time {
+	t := after.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+
+	// Moving a field resets the smaller ones, matching is checked again from the largest field
+	for t.Year() <= limit {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// Day of month and day of week restricted together match either one, like cron does
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
@@ -0,0 +1,125 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // Waiting for its run time or a free worker
+	StatusRunning   Status = "running"   // Claimed by a worker until LockedUntil
+	StatusCompleted Status = "completed" // Handled, removed after QueueConfig.RetainCompleted
+	StatusDead      Status = "dead"      // Failed every attempt, kept until requeued, see Queue.Requeue
+)
+
+type Job struct {
+	ID          bson.ObjectID   `bson:"_id" json:"_id"`
+	Name        string          `bson:"name" json:"name"`
+	Payload     json.RawMessage `bson:"-" json:"payload"` // JSON of enqueued payload, decoded by Typed handlers
+	Status      Status          `bson:"status" json:"status"`
+	Attempts    int             `bson:"attempts" json:"attempts"` // Claims so far, the running one included
+	MaxAttempts int             `bson:"maxAttempts" json:"maxAttempts"`
+	RunAt       time.Time       `bson:"runAt" json:"runAt"`                                 // Not claimed before, next retry of failed job
+	LockedUntil *time.Time      `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"` // Visibility deadline of running job, claimed again by another worker after it
+	LockToken   string          `bson:"lockToken,omitempty" json:"-"`                       // Set per claim, results of a worker that lost its claim are discarded
+	LastError   string          `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	CreatedAt   time.Time       `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time       `bson:"updatedAt" json:"updatedAt"`
+	CompletedAt *time.Time      `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time      `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"` // Completed job is removed after it
+}
+
+// Handler of claimed job, returned error retries the job until its attempts run out
+type Handler func(ctx context.Context, job *Job) error
+
+// Handler of job with JSON payload decoded into T e.g: jobs.Typed(func(ctx context.Context, payload SendEmail) error {...})
+func Typed[T any](handler func(ctx context.Context, payload T) error) Handler {
+	return func(ctx context.Context, job *Job) error {
+		var payload T
+
+		if len(job.Payload) > 0 {
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return fmt.Errorf("invalid payload of job %s: %w", job.Name, err)
+			}
+		}
+
+		return handler(ctx, payload)
+	}
+}
+
+type Definition struct {
+	Name        string        // Unique job name e.g: auth.send-verification-email
+	Handler     Handler       // See Typed
+	MaxAttempts int           // Attempts before the job is dead, default is 5
+	Backoff     time.Duration // Delay before the first retry, doubled on every next one, default is 10s
+	MaxBackoff  time.Duration // Longest delay between retries, default is 1 hour
+}
+
+var (
+	registered      = map[string]Definition{}
+	registeredMutex sync.RWMutex
+)
+
+// Register job handler, usually called from init(), panics on duplicated name
+func Register(definition Definition) {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+
+	if _, ok := registered[definition.Name]; ok {
+		panic(fmt.Sprintf("job %s is registered twice", definition.Name))
+	}
+
+	if definition.MaxAttempts <= 0 {
+		definition.MaxAttempts = 5
+	}
+
+	if definition.Backoff <= 0 {
+		definition.Backoff = 10 * time.Second
+	}
+
+	if definition.MaxBackoff <= 0 {
+		definition.MaxBackoff = time.Hour
+	}
+
+	registered[definition.Name] = definition
+}
+
+func definitionOf(name string) (Definition, bool) {
+	registeredMutex.RLock()
+	defer registeredMutex.RUnlock()
+
+	definition, ok := registered[name]
+
+	return definition, ok
+}
+
+// Names of registered jobs, workers only claim these
+func registeredNames() []string {
+	registeredMutex.RLock()
+	defer registeredMutex.RUnlock()
+
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// Delay before retrying job failed attempts times
+func (d Definition) backoff(attempts int) time.Duration {
+	delay := d.Backoff
+
+	for i := 1; i < attempts && delay < d.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, d.MaxBackoff)
+}
@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// In-process store, jobs are lost on restart, useful for tests
+type MemoryStore struct {
+	jobs  map[bson.ObjectID]*Job
+	tasks map[string]*TaskState
+	runs  []TaskRun
+	mutex sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[bson.ObjectID]*Job), tasks: make(map[string]*TaskState)}
+}
+
+func (s *MemoryStore) Setup(ctx context.Context) error {
+	return nil
+}
+
+func (s *MemoryStore) Insert(ctx context.Context, job *Job) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored := *job
+	s.jobs[job.ID] = &stored
+
+	return nil
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, names []string, token string, now time.Time, timeout time.Duration) (*Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var due *Job
+
+	for id, job := range s.jobs {
+		if job.ExpiresAt != nil && job.ExpiresAt.Before(now) {
+			delete(s.jobs, id)
+			continue
+		}
+
+		if !slices.Contains(names, job.Name) || !isDue(job, now) {
+			continue
+		}
+
+		if due == nil || job.RunAt.Before(due.RunAt) {
+			due = job
+		}
+	}
+
+	if due == nil {
+		return nil, nil
+	}
+
+	lockedUntil := now.Add(timeout)
+
+	due.Status = StatusRunning
+	due.Attempts++
+	due.LockedUntil = &lockedUntil
+	due.LockToken = token
+	due.UpdatedAt = now
+
+	claimed := *due
+
+	return &claimed, nil
+}
+
+func isDue(job *Job, now time.Time) bool {
+	if job.Status == StatusPending {
+		return !job.RunAt.After(now)
+	}
+
+	return job.Status == StatusRunning && job.LockedUntil != nil && job.LockedUntil.Before(now)
+}
+
+func (s *MemoryStore) Finish(ctx context.Context, job *Job) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.jobs[job.ID]
+
+	if !ok || stored.Status != StatusRunning || stored.LockToken != job.LockToken {
+		return ErrClaimLost
+	}
+
+	finished := *job
+	finished.LockedUntil = nil
+	finished.LockToken = ""
+	s.jobs[job.ID] = &finished
+
+	return nil
+}
+
+func (s *MemoryStore) Requeue(ctx context.Context, id bson.ObjectID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, ok := s.jobs[id]
+
+	if !ok || job.Status != StatusDead {
+		return ErrJobNotFound
+	}
+
+	job.Status = StatusPending
+	job.Attempts = 0
+	job.RunAt = time.Now()
+	job.UpdatedAt = job.RunAt
+
+	return nil
+}
+
+// Snapshot of stored jobs e.g: to assert on in tests
+func (s *MemoryStore) Jobs() []Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+
+	slices.SortFunc(jobs, func(a, b Job) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return jobs
+}
+
+func (s *MemoryStore) InitTask(ctx context.Context, state TaskState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if existing, ok := s.tasks[state.Name]; !ok || existing.Spec != state.Spec {
+		s.tasks[state.Name] = &state
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) LeaseTask(ctx context.Context, name string, owner string, now time.Time, timeout time.Duration) (*TaskState, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state, ok := s.tasks[name]
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	leased := !state.RunAfter.After(now) && (state.LockedUntil == nil || state.LockedUntil.Before(now))
+
+	if leased {
+		lockedUntil := now.Add(timeout)
+
+		state.Owner = owner
+		state.LockedUntil = &lockedUntil
+	}
+
+	current := *state
+
+	return &current, leased, nil
+}
+
+func (s *MemoryStore) ReleaseTask(ctx context.Context, state TaskState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if existing, ok := s.tasks[state.Name]; ok && existing.Owner == state.Owner {
+		state.Owner = ""
+		state.LockedUntil = nil
+		s.tasks[state.Name] = &state
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) RecordRun(ctx context.Context, run TaskRun) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.runs = slices.DeleteFunc(s.runs, func(existing TaskRun) bool {
+		return existing.ExpiresAt.Before(run.FinishedAt)
+	})
+	s.runs = append(s.runs, run)
+
+	return nil
+}
+
+func (s *MemoryStore) Runs(ctx context.Context, task string, limit int) ([]TaskRun, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	runs := []TaskRun{}
+
+	for i := len(s.runs) - 1; i >= 0 && (limit <= 0 || len(runs) < limit); i-- {
+		if s.runs[i].Task == task {
+			runs = append(runs, s.runs[i])
+		}
+	}
+
+	return runs, nil
+}
+
+var _ Store = (*MemoryStore)(nil)
+var _ TaskStore = (*MemoryStore)(nil)
@@ -0,0 +1,236 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Jobs persisted in a collection, workers of every replica claim from it with atomic updates
+type MongoStore struct {
+	Collection *mongo.Collection
+	Tasks      *mongo.Collection // Schedule state and lease of tasks, <collection>_tasks
+	RunHistory *mongo.Collection // Run history of tasks, <collection>_runs
+}
+
+// Payload is kept as JSON string so it round trips into any Typed payload
+type mongoJob struct {
+	Job     `bson:",inline"`
+	Payload string `bson:"payload"`
+}
+
+func NewMongoStore(db *mongo.Database, collection string) *MongoStore {
+	if collection == "" {
+		collection = "jobs"
+	}
+
+	return &MongoStore{
+		Collection: db.Collection(collection),
+		Tasks:      db.Collection(collection + "_tasks"),
+		RunHistory: db.Collection(collection + "_runs"),
+	}
+}
+
+func (s *MongoStore) Setup(ctx context.Context) error {
+	_, err := s.Collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "name", Value: 1}, {Key: "runAt", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "lockedUntil", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.RunHistory.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "task", Value: 1}, {Key: "startedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+
+	return err
+}
+
+func (s *MongoStore) Insert(ctx context.Context, job *Job) error {
+	_, err := s.Collection.InsertOne(ctx, mongoJob{Job: *job, Payload: string(job.Payload)})
+
+	return err
+}
+
+func (s *MongoStore) Claim(ctx context.Context, names []string, token string, now time.Time, timeout time.Duration) (*Job, error) {
+	filter := bson.M{
+		"name": bson.M{"$in": names},
+		"$or": bson.A{
+			bson.M{"status": StatusPending, "runAt": bson.M{"$lte": now}},
+			bson.M{"status": StatusRunning, "lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"status": StatusRunning, "lockedUntil": now.Add(timeout), "lockToken": token, "updatedAt": now},
+		"$inc": bson.M{"attempts": 1},
+	}
+
+	var doc mongoJob
+
+	err := s.Collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "runAt", Value: 1}}).SetReturnDocument(options.After),
+	).Decode(&doc)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	job := doc.Job
+	job.Payload = json.RawMessage(doc.Payload)
+
+	return &job, nil
+}
+
+func (s *MongoStore) Finish(ctx context.Context, job *Job) error {
+	set := bson.M{"status": job.Status, "runAt": job.RunAt, "updatedAt": job.UpdatedAt, "lastError": job.LastError}
+	unset := bson.M{"lockedUntil": "", "lockToken": ""}
+
+	if job.CompletedAt != nil {
+		set["completedAt"] = job.CompletedAt
+	}
+
+	if job.ExpiresAt != nil {
+		set["expiresAt"] = job.ExpiresAt
+	}
+
+	result, err := s.Collection.UpdateOne(ctx,
+		bson.M{"_id": job.ID, "status": StatusRunning, "lockToken": job.LockToken},
+		bson.M{"$set": set, "$unset": unset},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrClaimLost
+	}
+
+	return nil
+}
+
+func (s *MongoStore) Requeue(ctx context.Context, id bson.ObjectID) error {
+	now := time.Now()
+
+	result, err := s.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": StatusDead},
+		bson.M{"$set": bson.M{"status": StatusPending, "attempts": 0, "runAt": now, "updatedAt": now}},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrJobNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoStore) InitTask(ctx context.Context, state TaskState) error {
+	schedule := bson.M{"spec": state.Spec, "nextRunAt": state.NextRunAt, "runAfter": state.RunAfter}
+
+	_, err := s.Tasks.UpdateOne(ctx, bson.M{"_id": state.Name}, bson.M{"$setOnInsert": schedule}, options.UpdateOne().SetUpsert(true))
+
+	// Replica initializing at the same time won
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	_, err = s.Tasks.UpdateOne(ctx, bson.M{"_id": state.Name, "spec": bson.M{"$ne": state.Spec}}, bson.M{"$set": schedule})
+
+	return err
+}
+
+func (s *MongoStore) LeaseTask(ctx context.Context, name string, owner string, now time.Time, timeout time.Duration) (*TaskState, bool, error) {
+	var state TaskState
+
+	err := s.Tasks.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":      name,
+			"runAfter": bson.M{"$lte": now},
+			"$or":      bson.A{bson.M{"lockedUntil": bson.M{"$exists": false}}, bson.M{"lockedUntil": bson.M{"$lt": now}}},
+		},
+		bson.M{"$set": bson.M{"owner": owner, "lockedUntil": now.Add(timeout)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&state)
+
+	if err == nil {
+		return &state, true, nil
+	}
+
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, err
+	}
+
+	// Not due or leased by another replica
+	err = s.Tasks.FindOne(ctx, bson.M{"_id": name}).Decode(&state)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &state, false, nil
+}
+
+func (s *MongoStore) ReleaseTask(ctx context.Context, state TaskState) error {
+	_, err := s.Tasks.UpdateOne(ctx,
+		bson.M{"_id": state.Name, "owner": state.Owner},
+		bson.M{
+			"$set":   bson.M{"spec": state.Spec, "nextRunAt": state.NextRunAt, "runAfter": state.RunAfter},
+			"$unset": bson.M{"owner": "", "lockedUntil": ""},
+		},
+	)
+
+	return err
+}
+
+func (s *MongoStore) RecordRun(ctx context.Context, run TaskRun) error {
+	_, err := s.RunHistory.InsertOne(ctx, run)
+
+	return err
+}
+
+func (s *MongoStore) Runs(ctx context.Context, task string, limit int) ([]TaskRun, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "startedAt", Value: -1}})
+
+	if limit > 0 {
+		findOptions.SetLimit(int64(limit))
+	}
+
+	cursor, err := s.RunHistory.Find(ctx, bson.M{"task": task}, findOptions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []TaskRun{}
+
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+var _ Store = (*MongoStore)(nil)
+var _ TaskStore = (*MongoStore)(nil)
@@ -0,0 +1,326 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type QueueConfig struct {
+	Store             Store         // Persistence of jobs, default is MongoStore ("jobs" collection) of registered MongoDB external, else MemoryStore when memory external is registered
+	Concurrency       int           // Jobs processed at the same time by this replica, default is 4
+	PollInterval      time.Duration // Delay before claiming again when no job is due, default is 1s
+	VisibilityTimeout time.Duration // Running job not finished within it is claimed again by another worker, also the deadline of handler ctx, default is 5 minutes
+	RetainCompleted   time.Duration // Completed jobs are removed after it, default is 24 hours
+	RetainRuns        time.Duration // Run history of scheduled tasks is removed after it, default is 7 days
+	DisableWorkers    bool          // Only enqueue from this replica, neither jobs nor scheduled tasks are run e.g: web only deployments, default is false
+}
+
+type EnqueueOptions struct {
+	Delay       time.Duration // Run no earlier than now + Delay
+	RunAt       time.Time     // Run no earlier than it, takes precedence over Delay
+	MaxAttempts int           // Overrides Definition.MaxAttempts
+}
+
+// Persistent job queue processed by a pool of workers, register with externals.RegisterExternals, InitHttpApp starts its workers
+type Queue struct {
+	config     QueueConfig
+	store      Store
+	owner      string // Id of this replica, holder of task leases
+	mutex      sync.RWMutex
+	stop       context.CancelFunc // Stops claiming
+	cancelJobs context.CancelFunc // Cancels running handlers when shutdown times out
+	jobsCtx    context.Context
+	workers    sync.WaitGroup
+}
+
+func NewQueue(config QueueConfig) *Queue {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 4
+	}
+
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+
+	if config.VisibilityTimeout <= 0 {
+		config.VisibilityTimeout = 5 * time.Minute
+	}
+
+	if config.RetainCompleted <= 0 {
+		config.RetainCompleted = 24 * time.Hour
+	}
+
+	if config.RetainRuns <= 0 {
+		config.RetainRuns = 7 * 24 * time.Hour
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &Queue{config: config, store: config.Store, owner: fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), bson.NewObjectID().Hex())}
+}
+
+// Store is resolved by Start, once database externals are connected
+func (q *Queue) ConnectRaw() error {
+	return nil
+}
+
+func (q *Queue) Healthcheck() error {
+	return nil
+}
+
+func (q *Queue) SuccessMessage() string {
+	return "Job queue ready."
+}
+
+// Resolve and prepare store, then start workers and scheduler of tasks unless disabled
+func (q *Queue) Start(appExternals *externals.AllAppExternals) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.stop != nil {
+		return nil
+	}
+
+	if q.store == nil {
+		if mongoExt, err := externals.GetExternal[*externals.MongoDBExternal](appExternals); err == nil {
+			q.store = NewMongoStore(mongoExt.DB, "jobs")
+		} else if _, err := externals.GetExternal[*externals.MemoryExternal](appExternals); err == nil {
+			q.store = NewMemoryStore()
+		} else {
+			return fmt.Errorf("no database external registered for job queue")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := q.store.Setup(ctx); err != nil {
+		return err
+	}
+
+	taskStore, supportsTasks := q.store.(TaskStore)
+
+	if HasScheduledTasks() && !supportsTasks {
+		return errors.New("job store does not support scheduled tasks")
+	}
+
+	if q.config.DisableWorkers {
+		return nil
+	}
+
+	var workerCtx context.Context
+
+	workerCtx, q.stop = context.WithCancel(context.Background())
+	q.jobsCtx, q.cancelJobs = context.WithCancel(context.Background())
+
+	for range q.config.Concurrency {
+		q.workers.Add(1)
+		go q.work(workerCtx)
+	}
+
+	if supportsTasks {
+		q.workers.Add(1)
+		go q.schedule(workerCtx, taskStore)
+	}
+
+	log.Printf("Job workers started with concurrency %d.\n", q.config.Concurrency)
+
+	return nil
+}
+
+// Stop claiming jobs and wait for running ones, handlers still running when ctx is done are cancelled
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mutex.RLock()
+	stop := q.stop
+	q.mutex.RUnlock()
+
+	if stop == nil {
+		return nil
+	}
+
+	stop()
+
+	finished := make(chan struct{})
+
+	go func() {
+		q.workers.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		q.cancelJobs()
+		return fmt.Errorf("job queue shutdown: %w", ctx.Err())
+	}
+}
+
+func (q *Queue) getStore() (Store, error) {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	if q.store == nil {
+		return nil, errors.New("job queue is not started, set QueueConfig.Store or start it with InitHttpApp")
+	}
+
+	return q.store, nil
+}
+
+// Enqueue job to run as soon as a worker is free, payload is JSON encoded, joins transaction of ctx when the store supports it
+func (q *Queue) Enqueue(ctx context.Context, name string, payload any) (*Job, error) {
+	return q.EnqueueWithOptions(ctx, name, payload, EnqueueOptions{})
+}
+
+// Enqueue delayed or scheduled job
+func (q *Queue) EnqueueWithOptions(ctx context.Context, name string, payload any, options EnqueueOptions) (*Job, error) {
+	store, err := q.getStore()
+
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	job := &Job{
+		ID:          bson.NewObjectID(),
+		Name:        name,
+		Payload:     raw,
+		Status:      StatusPending,
+		MaxAttempts: options.MaxAttempts,
+		RunAt:       now.Add(options.Delay),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if !options.RunAt.IsZero() {
+		job.RunAt = options.RunAt
+	}
+
+	// Jobs handled by another service keep the default
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = 5
+
+		if definition, ok := definitionOf(name); ok {
+			job.MaxAttempts = definition.MaxAttempts
+		}
+	}
+
+	if err := store.Insert(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Move dead job back to pending with fresh attempts
+func (q *Queue) Requeue(ctx context.Context, id bson.ObjectID) error {
+	store, err := q.getStore()
+
+	if err != nil {
+		return err
+	}
+
+	return store.Requeue(ctx, id)
+}
+
+// Claim and process due jobs until ctx is done, waiting PollInterval whenever none is due
+func (q *Queue) work(ctx context.Context) {
+	defer q.workers.Done()
+
+	for {
+		job, err := q.store.Claim(ctx, registeredNames(), bson.NewObjectID().Hex(), time.Now(), q.config.VisibilityTimeout)
+
+		if err != nil && ctx.Err() == nil {
+			log.Println("job claim error:", err)
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(q.config.PollInterval):
+			}
+
+			continue
+		}
+
+		q.process(job)
+	}
+}
+
+// Run handler of claimed job and save its outcome, failed job is retried with exponential backoff until it is dead
+func (q *Queue) process(job *Job) {
+	definition, _ := definitionOf(job.Name)
+
+	var err error
+
+	// Claimed again after the last attempt did not finish in time
+	if job.Attempts > job.MaxAttempts {
+		err = errors.New("job did not finish within visibility timeout")
+	} else {
+		ctx, cancel := context.WithTimeout(q.jobsCtx, q.config.VisibilityTimeout)
+		err = runHandler(definition.Handler, ctx, job)
+		cancel()
+	}
+
+	now := time.Now()
+	job.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		expiresAt := now.Add(q.config.RetainCompleted)
+
+		job.Status = StatusCompleted
+		job.CompletedAt = &now
+		job.ExpiresAt = &expiresAt
+		job.LastError = ""
+	case job.Attempts >= job.MaxAttempts:
+		log.Printf("Job %s %s is dead after %d attempts: %v\n", job.Name, job.ID.Hex(), job.Attempts, err)
+
+		job.Status = StatusDead
+		job.LastError = err.Error()
+	default:
+		log.Printf("Job %s %s failed on attempt %d of %d: %v\n", job.Name, job.ID.Hex(), job.Attempts, job.MaxAttempts, err)
+
+		job.Status = StatusPending
+		job.RunAt = now.Add(definition.backoff(job.Attempts))
+		job.LastError = err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := q.store.Finish(ctx, job); err != nil {
+		log.Printf("Job %s %s not saved: %v\n", job.Name, job.ID.Hex(), err)
+	}
+}
+
+func runHandler(handler Handler, ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job %s panicked: %v", job.Name, recovered)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+var _ externals.BaseExternal = (*Queue)(nil)
+var _ externals.ShutdownExternal = (*Queue)(nil)
@@ -0,0 +1,275 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Register definition for the duration of test, names are global so reruns would panic otherwise
+func registerForTest(t *testing.T, definition Definition) {
+	t.Helper()
+
+	Register(definition)
+
+	t.Cleanup(func() {
+		registeredMutex.Lock()
+		defer registeredMutex.Unlock()
+
+		delete(registered, definition.Name)
+	})
+}
+
+// Queue over memory store processing claimed jobs on the calling goroutine instead of workers
+func newTestQueue() (*Queue, *MemoryStore) {
+	store := NewMemoryStore()
+	queue := NewQueue(QueueConfig{Store: store, VisibilityTimeout: time.Minute})
+	queue.jobsCtx = context.Background()
+
+	return queue, store
+}
+
+// Claim the next due job as a worker would at now and process it, false when none is due
+func processNext(t *testing.T, queue *Queue, store *MemoryStore, name string, now time.Time) bool {
+	t.Helper()
+
+	job, err := store.Claim(context.Background(), []string{name}, "token-"+now.String(), now, queue.config.VisibilityTimeout)
+
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	if job == nil {
+		return false
+	}
+
+	queue.process(job)
+
+	return true
+}
+
+func TestQueueRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int // Attempts failing before the handler succeeds
+		panics       bool
+		maxAttempts  int
+		wantStatus   Status
+		wantAttempts int
+		wantError    string
+	}{
+		{"succeeds first", 0, false, 3, StatusCompleted, 1, ""},
+		{"succeeds on retry", 2, false, 3, StatusCompleted, 3, ""},
+		{"dead after max attempts", 5, false, 3, StatusDead, 3, "boom"},
+		{"single attempt", 1, false, 1, StatusDead, 1, "boom"},
+		{"panics", 5, true, 2, StatusDead, 2, "panicked"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := "test." + strings.ReplaceAll(test.name, " ", "-")
+			calls := 0
+
+			registerForTest(t, Definition{
+				Name:        name,
+				MaxAttempts: test.maxAttempts,
+				Backoff:     time.Second,
+				Handler: func(ctx context.Context, job *Job) error {
+					calls++
+
+					if calls > test.failures {
+						return nil
+					}
+
+					if test.panics {
+						panic("boom")
+					}
+
+					return errors.New("boom")
+				},
+			})
+
+			queue, store := newTestQueue()
+
+			if _, err := queue.Enqueue(context.Background(), name, nil); err != nil {
+				t.Fatalf("enqueue: %v", err)
+			}
+
+			now := time.Now()
+
+			for processNext(t, queue, store, name, now) {
+				job := store.Jobs()[0]
+
+				if job.Status == StatusPending {
+					// Retried no earlier than its backoff
+					if processNext(t, queue, store, name, now) {
+						t.Fatalf("retry claimed before backoff of attempt %d", job.Attempts)
+					}
+
+					if got, want := job.RunAt.Sub(job.UpdatedAt), time.Second<<(job.Attempts-1); got != want {
+						t.Errorf("attempt %d: got backoff %v, want %v", job.Attempts, got, want)
+					}
+
+					now = job.RunAt
+				}
+			}
+
+			job := store.Jobs()[0]
+
+			if job.Status != test.wantStatus {
+				t.Errorf("got status %s, want %s", job.Status, test.wantStatus)
+			}
+
+			if job.Attempts != test.wantAttempts {
+				t.Errorf("got %d attempts, want %d", job.Attempts, test.wantAttempts)
+			}
+
+			if !strings.Contains(job.LastError, test.wantError) || test.wantError == "" && job.LastError != "" {
+				t.Errorf("got last error %q, want %q", job.LastError, test.wantError)
+			}
+
+			if job.LockToken != "" || job.LockedUntil != nil {
+				t.Errorf("finished job is still locked")
+			}
+		})
+	}
+}
+
+func TestQueueRequeue(t *testing.T) {
+	failing := true
+
+	registerForTest(t, Definition{
+		Name:        "test.requeue",
+		MaxAttempts: 1,
+		Handler: func(ctx context.Context, job *Job) error {
+			if failing {
+				return errors.New("boom")
+			}
+
+			return nil
+		},
+	})
+
+	queue, store := newTestQueue()
+
+	job, err := queue.Enqueue(context.Background(), "test.requeue", nil)
+
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	if err := queue.Requeue(context.Background(), job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("requeue of pending job: got %v, want %v", err, ErrJobNotFound)
+	}
+
+	processNext(t, queue, store, "test.requeue", time.Now())
+
+	if status := store.Jobs()[0].Status; status != StatusDead {
+		t.Fatalf("got status %s, want %s", status, StatusDead)
+	}
+
+	// Dead jobs are never claimed again on their own
+	if processNext(t, queue, store, "test.requeue", time.Now().Add(24*time.Hour)) {
+		t.Fatalf("dead job was claimed")
+	}
+
+	if err := queue.Requeue(context.Background(), job.ID); err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+
+	if requeued := store.Jobs()[0]; requeued.Status != StatusPending || requeued.Attempts != 0 {
+		t.Fatalf("got %s with %d attempts, want pending with 0", requeued.Status, requeued.Attempts)
+	}
+
+	failing = false
+	processNext(t, queue, store, "test.requeue", time.Now())
+
+	if status := store.Jobs()[0].Status; status != StatusCompleted {
+		t.Errorf("got status %s, want %s", status, StatusCompleted)
+	}
+}
+
+func TestQueueVisibilityTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		wantStatus  Status
+		wantCalls   int
+	}{
+		{"reclaimed job runs again", 2, StatusCompleted, 1},
+		{"reclaimed last attempt is dead", 1, StatusDead, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := "test." + strings.ReplaceAll(test.name, " ", "-")
+			calls := 0
+
+			registerForTest(t, Definition{Name: name, MaxAttempts: test.maxAttempts, Handler: func(ctx context.Context, job *Job) error {
+				calls++
+				return nil
+			}})
+
+			queue, store := newTestQueue()
+
+			if _, err := queue.Enqueue(context.Background(), name, nil); err != nil {
+				t.Fatalf("enqueue: %v", err)
+			}
+
+			now := time.Now()
+
+			// Worker crashed after claiming, the job stays running until its visibility deadline
+			crashed, _ := store.Claim(context.Background(), []string{name}, "crashed", now, queue.config.VisibilityTimeout)
+
+			if crashed == nil {
+				t.Fatalf("job not claimed")
+			}
+
+			if processNext(t, queue, store, name, now.Add(queue.config.VisibilityTimeout/2)) {
+				t.Fatalf("running job claimed before its visibility deadline")
+			}
+
+			if !processNext(t, queue, store, name, now.Add(2*queue.config.VisibilityTimeout)) {
+				t.Fatalf("running job not claimed after its visibility deadline")
+			}
+
+			if job := store.Jobs()[0]; job.Status != test.wantStatus {
+				t.Errorf("got status %s, want %s", job.Status, test.wantStatus)
+			}
+
+			if calls != test.wantCalls {
+				t.Errorf("handler called %d times, want %d", calls, test.wantCalls)
+			}
+
+			// Result of the crashed worker is discarded
+			crashed.Status = StatusCompleted
+
+			if err := store.Finish(context.Background(), crashed); !errors.Is(err, ErrClaimLost) {
+				t.Errorf("finish of lost claim: got %v, want %v", err, ErrClaimLost)
+			}
+		})
+	}
+}
+
+func TestDefinitionBackoff(t *testing.T) {
+	definition := Definition{Backoff: 10 * time.Second, MaxBackoff: time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{10, time.Minute},
+	}
+
+	for _, test := range tests {
+		if got := definition.backoff(test.attempts); got != test.want {
+			t.Errorf("attempt %d: got %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// What a task does about runs missed while no replica was running it e.g: during a deploy
+type MissedRunPolicy string
+
+const (
+	RunOnce    MissedRunPolicy = "once" // Missed runs are caught up with a single run
+	RunAll     MissedRunPolicy = "all"  // Every missed run is run in turn
+	SkipMissed MissedRunPolicy = "skip" // Missed runs are recorded as skipped, task waits for its next run
+)
+
+type Task struct {
+	Name       string                                                 // Unique task name e.g: auth.purge-verification-codes
+	Cron       string                                                 // Cron expression e.g: "0 3 * * *", "*/15 * * * mon-fri", "@daily" or "@every 10m"
+	Every      time.Duration                                          // Interval between runs, used when Cron is empty
+	Location   *time.Location                                         // Time zone of Cron, default is UTC
+	Handler    func(ctx context.Context, scheduledAt time.Time) error // Run of the task, scheduledAt is the planned time without jitter
+	Timeout    time.Duration                                          // Lease of a run, also the deadline of handler ctx, another replica runs it again once passed, default is 5 minutes
+	Jitter     time.Duration                                          // Runs are delayed randomly up to it, spreads tasks sharing a schedule, default is none
+	MissedRuns MissedRunPolicy                                        // Default is RunOnce
+	schedule   schedule
+}
+
+type RunStatus string
+
+const (
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunSkipped   RunStatus = "skipped" // Missed run under SkipMissed policy
+)
+
+// History entry of a task run, removed after QueueConfig.RetainRuns
+type TaskRun struct {
+	ID          bson.ObjectID `bson:"_id" json:"_id"`
+	Task        string        `bson:"task" json:"task"`
+	Owner       string        `bson:"owner" json:"owner"` // Replica that ran it
+	ScheduledAt time.Time     `bson:"scheduledAt" json:"scheduledAt"`
+	StartedAt   time.Time     `bson:"startedAt" json:"startedAt"`
+	FinishedAt  time.Time     `bson:"finishedAt" json:"finishedAt"`
+	Status      RunStatus     `bson:"status" json:"status"`
+	Error       string        `bson:"error,omitempty" json:"error,omitempty"`
+	ExpiresAt   time.Time     `bson:"expiresAt" json:"-"`
+}
+
+// Schedule state of a task shared by replicas
+type TaskState struct {
+	Name        string     `bson:"_id"`
+	Spec        string     `bson:"spec"`                  // Schedule the state was computed with, state is reset when it changes
+	NextRunAt   time.Time  `bson:"nextRunAt"`             // Planned time of next run
+	RunAfter    time.Time  `bson:"runAfter"`              // NextRunAt with jitter, run is leased from then on
+	Owner       string     `bson:"owner,omitempty"`       // Replica holding the lease
+	LockedUntil *time.Time `bson:"lockedUntil,omitempty"` // Lease deadline
+}
+
+// Store capability for scheduled tasks, implemented by MongoStore and MemoryStore
+type TaskStore interface {
+	InitTask(ctx context.Context, state TaskState) error // Save state when missing or when its Spec changed
+	// Lease task whose RunAfter has passed and that is not leased by another replica, returns current state either way, nil when not initialized
+	LeaseTask(ctx context.Context, name string, owner string, now time.Time, timeout time.Duration) (*TaskState, bool, error)
+	ReleaseTask(ctx context.Context, state TaskState) error // Plan next run and drop lease of Owner
+	RecordRun(ctx context.Context, run TaskRun) error
+	Runs(ctx context.Context, task string, limit int) ([]TaskRun, error) // Latest runs first
+}
+
+var (
+	scheduled      = map[string]Task{}
+	scheduledMutex sync.RWMutex
+)
+
+// Schedule task run by one replica at a time, usually called from init() or through HttpAppConfig.Tasks, panics on duplicated name or invalid schedule
+func Schedule(task Task) {
+	scheduledMutex.Lock()
+	defer scheduledMutex.Unlock()
+
+	if _, ok := scheduled[task.Name]; ok {
+		panic(fmt.Sprintf("task %s is scheduled twice", task.Name))
+	}
+
+	if task.Location == nil {
+		task.Location = time.UTC
+	}
+
+	switch {
+	case task.Cron != "":
+		s, err := parseCron(task.Cron, task.Location)
+
+		if err != nil {
+			panic(fmt.Sprintf("task %s: %v", task.Name, err))
+		}
+
+		task.schedule = s
+	case task.Every > 0:
+		task.schedule = intervalSchedule{every: task.Every}
+	default:
+		panic(fmt.Sprintf("task %s has neither Cron nor Every", task.Name))
+	}
+
+	if task.Timeout <= 0 {
+		task.Timeout = 5 * time.Minute
+	}
+
+	if task.MissedRuns == "" {
+		task.MissedRuns = RunOnce
+	}
+
+	scheduled[task.Name] = task
+}
+
+func scheduledTasks() []Task {
+	scheduledMutex.RLock()
+	defer scheduledMutex.RUnlock()
+
+	tasks := make([]Task, 0, len(scheduled))
+	for _, task := range scheduled {
+		tasks = append(tasks, task)
+	}
+
+	return tasks
+}
+
+// Whether any task is scheduled, they need a started Queue to run
+func HasScheduledTasks() bool {
+	scheduledMutex.RLock()
+	defer scheduledMutex.RUnlock()
+
+	return len(scheduled) > 0
+}
+
+func (t Task) spec() string {
+	if t.Cron != "" {
+		return t.Cron + " " + t.Location.String()
+	}
+
+	return "@every " + t.Every.String()
+}
+
+// State planning the run following after
+func (t Task) plan(after time.Time) TaskState {
+	next := t.schedule.next(after)
+	runAfter := next
+
+	if t.Jitter > 0 {
+		runAfter = next.Add(rand.N(t.Jitter))
+	}
+
+	return TaskState{Name: t.Name, Spec: t.spec(), NextRunAt: next, RunAfter: runAfter}
+}
+
+// Lease due tasks every PollInterval and run them, until ctx is done
+func (q *Queue) schedule(ctx context.Context, store TaskStore) {
+	defer q.workers.Done()
+
+	initialized := map[string]bool{}
+	due := map[string]time.Time{} // RunAfter last seen, saves leasing tasks not due yet
+
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, task := range scheduledTasks() {
+			now := time.Now()
+
+			if !initialized[task.Name] {
+				if err := store.InitTask(ctx, task.plan(now)); err != nil {
+					if ctx.Err() == nil {
+						log.Printf("Task %s not initialized: %v\n", task.Name, err)
+					}
+					continue
+				}
+
+				initialized[task.Name] = true
+			}
+
+			if now.Before(due[task.Name]) {
+				continue
+			}
+
+			state, leased, err := store.LeaseTask(ctx, task.Name, q.owner, now, task.Timeout)
+
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Task %s not leased: %v\n", task.Name, err)
+				}
+				continue
+			}
+
+			if state == nil {
+				delete(initialized, task.Name)
+				continue
+			}
+
+			if !leased {
+				due[task.Name] = state.RunAfter
+				continue
+			}
+
+			delete(due, task.Name)
+
+			q.workers.Add(1)
+			go q.runTask(task, store, *state)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run leased task, record the run and plan the next one
+func (q *Queue) runTask(task Task, store TaskStore, state TaskState) {
+	defer q.workers.Done()
+
+	now := time.Now()
+
+	run := TaskRun{ID: bson.NewObjectID(), Task: task.Name, Owner: q.owner, ScheduledAt: state.NextRunAt, StartedAt: now}
+
+	// A whole period passed since the run was planned
+	missed := !task.schedule.next(state.NextRunAt).After(now)
+
+	if missed && task.MissedRuns == SkipMissed {
+		run.Status = RunSkipped
+	} else {
+		ctx, cancel := context.WithTimeout(q.jobsCtx, task.Timeout)
+		err := runTaskHandler(task, ctx, state.NextRunAt)
+		cancel()
+
+		run.Status = RunSucceeded
+
+		if err != nil {
+			log.Printf("Task %s failed: %v\n", task.Name, err)
+
+			run.Status = RunFailed
+			run.Error = err.Error()
+		}
+	}
+
+	run.FinishedAt = time.Now()
+	run.ExpiresAt = run.FinishedAt.Add(q.config.RetainRuns)
+
+	next := task.plan(run.FinishedAt)
+
+	if task.MissedRuns == RunAll {
+		next = task.plan(state.NextRunAt)
+	}
+
+	next.Owner = q.owner
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := errors.Join(store.RecordRun(ctx, run), store.ReleaseTask(ctx, next)); err != nil {
+		log.Printf("Task %s run not saved: %v\n", task.Name, err)
+	}
+}
+
+func runTaskHandler(task Task, ctx context.Context, scheduledAt time.Time) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("task %s panicked: %v", task.Name, recovered)
+		}
+	}()
+
+	return task.Handler(ctx, scheduledAt)
+}
+
+// Latest runs of task, newest first
+func (q *Queue) TaskRuns(ctx context.Context, task string, limit int) ([]TaskRun, error) {
+	store, err := q.getStore()
+
+	if err != nil {
+		return nil, err
+	}
+
+	taskStore, ok := store.(TaskStore)
+
+	if !ok {
+		return nil, errors.New("job store does not support scheduled tasks")
+	}
+
+	return taskStore.Runs(ctx, task, limit)
+}
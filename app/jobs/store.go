@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrClaimLost   = errors.New("job was claimed by another worker") // Visibility timeout passed before the worker finished
+)
+
+// Persistence of jobs, implemented for MongoDB and in memory
+type Store interface {
+	Setup(ctx context.Context) error // Prepare storage e.g: indexes, called once by Queue.Start
+	Insert(ctx context.Context, job *Job) error
+	// Lock due job of one of names until now + timeout, pending jobs and running ones past their visibility deadline are due, nil when none is
+	Claim(ctx context.Context, names []string, token string, now time.Time, timeout time.Duration) (*Job, error)
+	Finish(ctx context.Context, job *Job) error          // Save status, run time and error of claimed job and unlock it, ErrClaimLost when its claim was taken over
+	Requeue(ctx context.Context, id bson.ObjectID) error // Move dead job back to pending with fresh attempts, ErrJobNotFound when there is no such dead job
+}
@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var migrationTemplate = template.Must(template.New("migration").Parse(`package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/migrations"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+)
+
+func init() {
+	migrations.Register(migrations.Migration{
+		Version: {{.Version}},
+		Name:    "{{.Name}}",
+		Up: func(ctx context.Context, db types.AppDB) error {
+			return nil
+		},
+		Down: func(ctx context.Context, db types.AppDB) error {
+			return nil
+		},
+	})
+}
+`))
+
+var nonWordRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Generate new migration file inside dir, versioned by current UTC timestamp, returns created file path.
+// The package holding migration files must be blank imported by the app so init() registers them.
+func Create(dir string, name string) (string, error) {
+	name = strings.Trim(nonWordRegex.ReplaceAllString(strings.ToLower(name), "_"), "_")
+
+	if name == "" {
+		return "", fmt.Errorf("migration name is required")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	absDir, err := filepath.Abs(dir)
+
+	if err != nil {
+		return "", err
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.go", version, name))
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	packageName := nonWordRegex.ReplaceAllString(strings.ToLower(filepath.Base(absDir)), "")
+
+	return path, migrationTemplate.Execute(file, map[string]string{
+		"Package": packageName,
+		"Version": version,
+		"Name":    name,
+	})
+}
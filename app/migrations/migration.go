@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+)
+
+type Migration struct {
+	Version int64                                           // Unique sortable version, `migrate create` uses timestamp e.g: 20250101120000
+	Name    string                                          // Short snake case description e.g: add_deleted_at_to_notes
+	Up      func(ctx context.Context, db types.AppDB) error // Apply schema/data change
+	Down    func(ctx context.Context, db types.AppDB) error // Revert Up, optional but required by `migrate down`
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // Nil when migration is pending
+}
+
+var (
+	registered      = map[int64]Migration{}
+	registeredMutex sync.Mutex
+)
+
+// Register migration, usually called from init() of a migration file, panics on duplicated version
+func Register(migration Migration) {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+
+	if existing, ok := registered[migration.Version]; ok {
+		panic(fmt.Sprintf("migration version %d is registered twice (%s, %s)", migration.Version, existing.Name, migration.Name))
+	}
+
+	registered[migration.Version] = migration
+}
+
+// All registered migrations ordered by version
+func Registered() []Migration {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+
+	all := make([]Migration, 0, len(registered))
+	for _, migration := range registered {
+		all = append(all, migration)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Version < all[j].Version
+	})
+
+	return all
+}
@@ -0,0 +1,282 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Collection / table recording applied migrations
+var StateCollection = "_migrations"
+
+// Returned when another replica holds the migration lock
+var ErrLocked = fmt.Errorf("migrations are locked by another process")
+
+// Delay between attempts to take the lock held by another replica
+const lockPollInterval = 2 * time.Second
+
+// Lease used when LockTTL is not set
+const defaultLockTTL = 5 * time.Minute
+
+// Returned when the lock lease could not be renewed while migrating, another replica may take it over
+var ErrLockLost = fmt.Errorf("migration lock was lost")
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// Persistence of applied versions and the distributed lock, implemented for MongoDB and SQL
+type stateStore interface {
+	applied(ctx context.Context) (map[int64]appliedMigration, error)
+	markApplied(ctx context.Context, migration Migration) error
+	unmarkApplied(ctx context.Context, version int64) error
+	acquireLock(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+	renewLock(ctx context.Context, owner string, ttl time.Duration) (bool, error) // Extend lease still held by owner
+	releaseLock(ctx context.Context, owner string) error
+}
+
+type Migrator struct {
+	DB         types.AppDB
+	Migrations []Migration   // Defaults to Registered()
+	LockTTL    time.Duration // Lock lease renewed while migrating, expired lock of a crashed replica can be taken over, default is 5 minutes
+	store      stateStore
+	owner      string
+}
+
+// Build migrator from registered database external, preferring MongoDB over SQL
+func NewMigrator(appExternals *externals.AllAppExternals) (*Migrator, error) {
+	migrator := &Migrator{LockTTL: defaultLockTTL}
+
+	hostname, _ := os.Hostname()
+	migrator.owner = fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), bson.NewObjectID().Hex())
+
+	if mongoExt, err := externals.GetExternal[*externals.MongoDBExternal](appExternals); err == nil {
+		migrator.DB = types.AppDB{MongoDB: mongoExt.DB}
+		migrator.store = &mongoStateStore{db: mongoExt.DB}
+		return migrator, nil
+	}
+
+	if sqlExt, err := externals.GetExternal[*externals.SQLExternal](appExternals); err == nil {
+		migrator.DB = types.AppDB{SQL: sqlExt.DB, SQLDriver: sqlExt.Driver}
+		migrator.store = &sqlStateStore{db: sqlExt.DB, driver: sqlExt.Driver}
+		return migrator, nil
+	}
+
+	return nil, fmt.Errorf("no database external registered for migrations")
+}
+
+func (m *Migrator) migrations() []Migration {
+	if m.Migrations != nil {
+		return m.Migrations
+	}
+
+	return Registered()
+}
+
+// Hold the distributed lock while running fn, so only one replica migrates at a time.
+// The lease is renewed every third of LockTTL, ctx of fn is cancelled when renewing fails.
+func (m *Migrator) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	ttl := m.LockTTL
+	if ttl <= 0 {
+		ttl = defaultLockTTL
+	}
+
+	// Lease must leave room for a renewal tick
+	renewEvery := ttl / 3
+	if renewEvery <= 0 {
+		return fmt.Errorf("migration LockTTL %s is too short", ttl)
+	}
+
+	acquired, err := m.store.acquireLock(ctx, m.owner, ttl)
+
+	if err != nil {
+		return err
+	}
+
+	if !acquired {
+		return ErrLocked
+	}
+
+	defer func() {
+		if err := m.store.releaseLock(context.Background(), m.owner); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	// Heartbeat stops before the lock is released
+	heartbeatDone := make(chan struct{})
+	defer func() { <-heartbeatDone }()
+	defer cancel(nil)
+
+	go func() {
+		defer close(heartbeatDone)
+
+		ticker := time.NewTicker(renewEvery)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				renewed, err := m.store.renewLock(ctx, m.owner, ttl)
+
+				if ctx.Err() != nil {
+					return
+				}
+
+				if err != nil || !renewed {
+					cancel(errors.Join(ErrLockLost, err))
+					return
+				}
+			}
+		}
+	}()
+
+	err = fn(ctx)
+
+	if cause := context.Cause(ctx); errors.Is(cause, ErrLockLost) {
+		return errors.Join(cause, err)
+	}
+
+	return err
+}
+
+// Apply every pending migration like Up, waiting up to wait while another replica holds the lock
+func (m *Migrator) UpWhenUnlocked(ctx context.Context, wait time.Duration) ([]Migration, error) {
+	deadline := time.Now().Add(wait)
+
+	for {
+		done, err := m.Up(ctx)
+
+		if !errors.Is(err, ErrLocked) {
+			return done, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("waited %s for migrations of another process: %w", wait, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// Apply every pending migration in version order, returns applied migrations
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+
+	err := m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.store.applied(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations() {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			if migration.Up != nil {
+				if err := migration.Up(ctx, m.DB); err != nil {
+					return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+				}
+			}
+
+			if err := m.store.markApplied(ctx, migration); err != nil {
+				return err
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Revert the latest applied migrations, steps defaults to 1
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		steps = 1
+	}
+
+	var done []Migration
+
+	err := m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.store.applied(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		all := m.migrations()
+
+		for i := len(all) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := all[i]
+
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			if migration.Down == nil {
+				return fmt.Errorf("migration %d_%s has no down function", migration.Version, migration.Name)
+			}
+
+			if err := migration.Down(ctx, m.DB); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+
+			if err := m.store.unmarkApplied(ctx, migration.Version); err != nil {
+				return err
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Registered migrations with their applied time
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.store.applied(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+
+	for _, migration := range m.migrations() {
+		status := MigrationStatus{Migration: migration}
+
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,198 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	_ "modernc.org/sqlite"
+)
+
+var sqliteTestDatabases int
+
+// Migrator owned by owner with its state in its own in-memory SQLite database
+func newTestMigrator(t *testing.T, owner string, migrations ...Migration) *Migrator {
+	t.Helper()
+
+	sqliteTestDatabases++
+
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:/migrator-test-%d?vfs=memdb&_pragma=busy_timeout(1000)", sqliteTestDatabases))
+
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return &Migrator{
+		DB:         types.AppDB{SQL: db, SQLDriver: "sqlite"},
+		Migrations: migrations,
+		store:      &sqlStateStore{db: db, driver: "sqlite"},
+		owner:      owner,
+	}
+}
+
+// Migration creating table on up and dropping it on down
+func tableMigration(version int64, table string) Migration {
+	return Migration{
+		Version: version,
+		Name:    "create_" + table,
+		Up: func(ctx context.Context, db types.AppDB) error {
+			_, err := db.SQL.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE "%s" ("id" INTEGER)`, table))
+			return err
+		},
+		Down: func(ctx context.Context, db types.AppDB) error {
+			_, err := db.SQL.ExecContext(ctx, fmt.Sprintf(`DROP TABLE "%s"`, table))
+			return err
+		},
+	}
+}
+
+func appliedVersions(t *testing.T, m *Migrator) []int64 {
+	t.Helper()
+
+	statuses, err := m.Status(context.Background())
+
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	var versions []int64
+
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			versions = append(versions, status.Version)
+		}
+	}
+
+	return versions
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	m := newTestMigrator(t, "a", tableMigration(1, "notes"), tableMigration(2, "tags"))
+	ctx := context.Background()
+
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 2 {
+		t.Fatalf("up: got %d applied %v, want 2", len(applied), err)
+	}
+
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("up again: got %d applied %v, want 0", len(applied), err)
+	}
+
+	if got := appliedVersions(t, m); fmt.Sprint(got) != "[1 2]" {
+		t.Errorf("applied versions: got %v, want [1 2]", got)
+	}
+
+	reverted, err := m.Down(ctx, 0)
+
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("down: got %v %v, want version 2 reverted", reverted, err)
+	}
+
+	if got := appliedVersions(t, m); fmt.Sprint(got) != "[1]" {
+		t.Errorf("applied versions after down: got %v, want [1]", got)
+	}
+
+	if _, err := m.DB.SQL.Exec(`SELECT * FROM "tags"`); err == nil {
+		t.Error("tags table still exists after down")
+	}
+}
+
+func TestMigratorFailedMigrationStaysPending(t *testing.T) {
+	errBroken := errors.New("broken")
+	broken := Migration{Version: 2, Name: "broken", Up: func(context.Context, types.AppDB) error { return errBroken }}
+
+	m := newTestMigrator(t, "a", tableMigration(1, "notes"), broken, tableMigration(3, "tags"))
+
+	applied, err := m.Up(context.Background())
+
+	if !errors.Is(err, errBroken) || len(applied) != 1 {
+		t.Fatalf("got %d applied %v, want 1 applied and %v", len(applied), err, errBroken)
+	}
+
+	if got := appliedVersions(t, m); fmt.Sprint(got) != "[1]" {
+		t.Errorf("applied versions: got %v, want [1]", got)
+	}
+}
+
+func TestMigratorLock(t *testing.T) {
+	tests := []struct {
+		name    string
+		heldFor time.Duration // Lease of the lock held by another replica
+		wantErr error
+	}{
+		{"held by other replica", time.Minute, ErrLocked},
+		{"expired lease is taken over", -time.Minute, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := newTestMigrator(t, "a", tableMigration(1, "notes"))
+
+			if acquired, err := m.store.acquireLock(context.Background(), "b", test.heldFor); err != nil || !acquired {
+				t.Fatalf("lock of other replica: got %v %v", acquired, err)
+			}
+
+			if _, err := m.Up(context.Background()); !errors.Is(err, test.wantErr) {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigratorLockTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		wantErr bool
+	}{
+		{"unset defaults", 0, false},
+		{"negative defaults", -time.Second, false},
+		{"too short to renew", 2 * time.Nanosecond, true},
+		{"short", 30 * time.Millisecond, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := newTestMigrator(t, "a", tableMigration(1, "notes"))
+			m.LockTTL = test.ttl
+
+			if _, err := m.Up(context.Background()); (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigratorLockLost(t *testing.T) {
+	stolen := Migration{
+		Version: 1,
+		Name:    "stolen",
+		Up: func(ctx context.Context, db types.AppDB) error {
+			// Another replica takes the lock over, the next renewal fails
+			if _, err := db.SQL.Exec(fmt.Sprintf(`UPDATE "%s_lock" SET "owner" = 'b'`, StateCollection)); err != nil {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(2 * time.Second):
+				return errors.New("context was not cancelled")
+			}
+		},
+	}
+
+	m := newTestMigrator(t, "a", stolen)
+	m.LockTTL = 30 * time.Millisecond
+
+	if _, err := m.Up(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Errorf("got error %v, want %v", err, ErrLockLost)
+	}
+}
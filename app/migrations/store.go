@@ -0,0 +1,206 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type mongoStateStore struct {
+	db *mongo.Database
+}
+
+func (s *mongoStateStore) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	cursor, err := s.db.Collection(StateCollection).Find(ctx, bson.M{"_id": bson.M{"$type": "long"}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(ctx)
+
+	applied := map[int64]appliedMigration{}
+
+	for cursor.Next(ctx) {
+		var record struct {
+			Version   int64     `bson:"_id"`
+			Name      string    `bson:"name"`
+			AppliedAt time.Time `bson:"appliedAt"`
+		}
+
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+
+		applied[record.Version] = appliedMigration{Version: record.Version, Name: record.Name, AppliedAt: record.AppliedAt}
+	}
+
+	return applied, cursor.Err()
+}
+
+func (s *mongoStateStore) markApplied(ctx context.Context, migration Migration) error {
+	_, err := s.db.Collection(StateCollection).InsertOne(ctx, bson.M{"_id": migration.Version, "name": migration.Name, "appliedAt": time.Now()})
+
+	return err
+}
+
+func (s *mongoStateStore) unmarkApplied(ctx context.Context, version int64) error {
+	_, err := s.db.Collection(StateCollection).DeleteOne(ctx, bson.M{"_id": version})
+
+	return err
+}
+
+// Lock is a single document in the state collection, taken over only when its lease has expired
+func (s *mongoStateStore) acquireLock(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	_, err := s.db.Collection(StateCollection).UpdateOne(ctx,
+		bson.M{"_id": "lock", "$or": bson.A{bson.M{"owner": owner}, bson.M{"expiresAt": bson.M{"$lt": now}}}},
+		bson.M{"$set": bson.M{"owner": owner, "expiresAt": now.Add(ttl)}},
+		options.UpdateOne().SetUpsert(true),
+	)
+
+	if mongo.IsDuplicateKeyError(err) {
+		// Lock document exists and is held by another owner
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s *mongoStateStore) renewLock(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	result, err := s.db.Collection(StateCollection).UpdateOne(ctx, bson.M{"_id": "lock", "owner": owner}, bson.M{"$set": bson.M{"expiresAt": time.Now().Add(ttl)}})
+
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+func (s *mongoStateStore) releaseLock(ctx context.Context, owner string) error {
+	_, err := s.db.Collection(StateCollection).DeleteOne(ctx, bson.M{"_id": "lock", "owner": owner})
+
+	return err
+}
+
+type sqlStateStore struct {
+	db     *sql.DB
+	driver string
+	ready  bool
+}
+
+func (s *sqlStateStore) placeholder(n int) string {
+	if s.driver == "pgx" || s.driver == "postgres" {
+		return fmt.Sprintf("$%d", n)
+	}
+
+	return "?"
+}
+
+func (s *sqlStateStore) ensureTables(ctx context.Context) error {
+	if s.ready {
+		return nil
+	}
+
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" ("version" BIGINT PRIMARY KEY, "name" TEXT NOT NULL, "appliedAt" TIMESTAMP NOT NULL)`, StateCollection),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s_lock" ("id" INTEGER PRIMARY KEY, "owner" TEXT NOT NULL, "expiresAt" TIMESTAMP NOT NULL)`, StateCollection),
+	}
+
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	s.ready = true
+
+	return nil
+}
+
+func (s *sqlStateStore) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	if err := s.ensureTables(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT "version", "name", "appliedAt" FROM "%s"`, StateCollection))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+
+	for rows.Next() {
+		var record appliedMigration
+
+		if err := rows.Scan(&record.Version, &record.Name, &record.AppliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[record.Version] = record
+	}
+
+	return applied, rows.Err()
+}
+
+func (s *sqlStateStore) markApplied(ctx context.Context, migration Migration) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO "%s" ("version", "name", "appliedAt") VALUES (%s, %s, %s)`, StateCollection, s.placeholder(1), s.placeholder(2), s.placeholder(3)), migration.Version, migration.Name, time.Now().UTC())
+
+	return err
+}
+
+func (s *sqlStateStore) unmarkApplied(ctx context.Context, version int64) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM "%s" WHERE "version" = %s`, StateCollection, s.placeholder(1)), version)
+
+	return err
+}
+
+// Lock is a single row, expired lease is cleared first so the primary key decides the winner
+func (s *sqlStateStore) acquireLock(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	if err := s.ensureTables(ctx); err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM "%s_lock" WHERE "id" = 1 AND ("expiresAt" < %s OR "owner" = %s)`, StateCollection, s.placeholder(1), s.placeholder(2)), now, owner); err != nil {
+		return false, err
+	}
+
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO "%s_lock" ("id", "owner", "expiresAt") VALUES (1, %s, %s) ON CONFLICT DO NOTHING`, StateCollection, s.placeholder(1), s.placeholder(2)), owner, now.Add(ttl))
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected == 1, err
+}
+
+func (s *sqlStateStore) renewLock(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf(`UPDATE "%s_lock" SET "expiresAt" = %s WHERE "id" = 1 AND "owner" = %s`, StateCollection, s.placeholder(1), s.placeholder(2)), time.Now().UTC().Add(ttl), owner)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected == 1, err
+}
+
+func (s *sqlStateStore) releaseLock(ctx context.Context, owner string) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM "%s_lock" WHERE "id" = 1 AND "owner" = %s`, StateCollection, s.placeholder(1)), owner)
+
+	return err
+}
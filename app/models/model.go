@@ -8,10 +8,10 @@ import (
 
 type User struct {
 	ID                             bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	Username                       string        `json:"username" bson:"username"`
+	Username                       string        `json:"username" bson:"username" index:"unique"`
 	FirstName                      string        `json:"firstName" bson:"firstName"`
 	LastName                       string        `json:"lastName" bson:"lastName"`
-	Email                          string        `json:"email" bson:"email"`
+	Email                          string        `json:"email" bson:"email" index:"unique"`
 	Password                       string        `json:"password" bson:"password"`
 	EmailVerifiedAt                *time.Time    `json:"emailVerifiedAt" bson:"emailVerifiedAt"`
 	EmailVerificationCode          *string       `json:"emailVerificationCode" bson:"emailVerificationCode"`
@@ -0,0 +1,382 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Result key of interval bucket start
+const AggregatePeriodField = "period"
+
+var ErrAggregateUnsupported = errors.New("aggregation is not supported by this repository")
+
+// Repository able to compute grouped metrics, each result holds group by fields, period and metric keys
+type AggregateRepository[T any] interface {
+	Repository[T]
+	Aggregate(filtersAndSorts *types.GetAllFiltersAndSorts, query types.AggregateQuery) ([]map[string]any, error)
+}
+
+func mongoAccumulator(metric types.AggregateMetric) (bson.D, error) {
+	switch metric.Op {
+	case types.AggCount:
+		return bson.D{{Key: "$sum", Value: 1}}, nil
+	case types.AggSum, types.AggAvg, types.AggMin, types.AggMax:
+		return bson.D{{Key: "$" + string(metric.Op), Value: "$" + metric.Field}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported metric %s", metric.Op)
+	}
+}
+
+func (r *BaseRepo[T]) Aggregate(filtersAndSorts *types.GetAllFiltersAndSorts, query types.AggregateQuery) ([]map[string]any, error) {
+	if filtersAndSorts == nil {
+		filtersAndSorts = &types.GetAllFiltersAndSorts{}
+	}
+
+	var pipeline mongo.Pipeline
+
+	if matchStages := r.matchStage(filtersAndSorts); len(matchStages) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: matchStages}})
+	}
+
+	// Group keys are positional since field paths can't be used as keys
+	groupID := bson.D{}
+
+	for i, field := range query.GroupBy {
+		groupID = append(groupID, bson.E{Key: fmt.Sprintf("g%d", i), Value: "$" + field})
+	}
+
+	if query.Interval != nil {
+		groupID = append(groupID, bson.E{Key: AggregatePeriodField, Value: bson.D{{Key: "$dateTrunc", Value: bson.D{
+			{Key: "date", Value: "$" + query.Interval.Field},
+			{Key: "unit", Value: query.Interval.Unit},
+		}}}})
+	}
+
+	group := bson.D{{Key: "_id", Value: groupID}}
+
+	for i, metric := range query.Metrics {
+		accumulator, err := mongoAccumulator(metric)
+
+		if err != nil {
+			return nil, err
+		}
+
+		group = append(group, bson.E{Key: fmt.Sprintf("m%d", i), Value: accumulator})
+	}
+
+	pipeline = append(pipeline, bson.D{{Key: "$group", Value: group}}, bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}})
+
+	if query.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: query.Limit}})
+	}
+
+	cursor, err := r.aggregateSearch(filtersAndSorts.Search, pipeline)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(r.getContext())
+
+	results := []map[string]any{}
+
+	for cursor.Next(r.getContext()) {
+		var doc bson.M
+
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+
+		id, _ := doc["_id"].(bson.M)
+		result := map[string]any{}
+
+		for i, field := range query.GroupBy {
+			result[field] = jsonCompatible(id[fmt.Sprintf("g%d", i)])
+		}
+
+		if query.Interval != nil {
+			result[AggregatePeriodField] = jsonCompatible(id[AggregatePeriodField])
+		}
+
+		for i, metric := range query.Metrics {
+			result[metric.Key()] = doc[fmt.Sprintf("m%d", i)]
+		}
+
+		results = append(results, result)
+	}
+
+	return results, cursor.Err()
+}
+
+func (r *SQLRepo[T]) Aggregate(filtersAndSorts *types.GetAllFiltersAndSorts, query types.AggregateQuery) ([]map[string]any, error) {
+	if filtersAndSorts == nil {
+		filtersAndSorts = &types.GetAllFiltersAndSorts{}
+	}
+
+	// Date truncation differs per dialect, intervals are left to MongoDB and in-memory repos
+	if query.Interval != nil || filtersAndSorts.Search != "" {
+		return nil, ErrAggregateUnsupported
+	}
+
+	where, args, err := r.buildGroupWhere(filtersAndSorts.Conditions(), filtersAndSorts.DeletedMode, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		groupColumns []*sqlColumn
+		selects      []string
+		groups       []string
+	)
+
+	for _, field := range query.GroupBy {
+		column, err := r.column(field)
+
+		if err != nil {
+			return nil, err
+		}
+
+		groupColumns = append(groupColumns, column)
+		groups = append(groups, quoteIdentifier(column.Name))
+	}
+
+	selects = append(selects, groups...)
+
+	for _, metric := range query.Metrics {
+		if metric.Op == types.AggCount {
+			selects = append(selects, "COUNT(*)")
+			continue
+		}
+
+		column, err := r.column(metric.Field)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch metric.Op {
+		case types.AggSum, types.AggAvg, types.AggMin, types.AggMax:
+			selects = append(selects, fmt.Sprintf("%s(%s)", strings.ToUpper(string(metric.Op)), quoteIdentifier(column.Name)))
+		default:
+			return nil, fmt.Errorf("unsupported metric %s", metric.Op)
+		}
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(selects, ", "), quoteIdentifier(r.Table), where)
+
+	if len(groups) > 0 {
+		statement = fmt.Sprintf("%s GROUP BY %s ORDER BY %s", statement, strings.Join(groups, ", "), strings.Join(groups, ", "))
+	}
+
+	if query.Limit > 0 {
+		statement = fmt.Sprintf("%s LIMIT %d", statement, query.Limit)
+	}
+
+	rows, err := r.executor().QueryContext(r.getContext(), statement, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results := []map[string]any{}
+
+	for rows.Next() {
+		values := make([]any, len(selects))
+		pointers := make([]any, len(selects))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		result := map[string]any{}
+
+		for i, column := range groupColumns {
+			result[query.GroupBy[i]] = fromSQLValue(values[i], column.Type)
+		}
+
+		for i, metric := range query.Metrics {
+			result[metric.Key()] = fromSQLValue(values[len(groupColumns)+i], nil)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// Start of interval containing value in UTC, nil when value is not a date, weeks start on sunday like $dateTrunc
+func truncateTime(value any, unit string) any {
+	var t time.Time
+
+	switch v := value.(type) {
+	case time.Time:
+		t = v.UTC()
+	case bson.DateTime:
+		t = v.Time().UTC()
+	default:
+		return nil
+	}
+
+	switch unit {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "week":
+		return time.Date(t.Year(), t.Month(), t.Day()-int(t.Weekday()), 0, 0, 0, 0, time.UTC)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "year":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return nil
+	}
+}
+
+// Compute metric over documents of a group, mirrors $group accumulators
+func computeMetric(docs []bson.M, metric types.AggregateMetric) (any, error) {
+	if metric.Op == types.AggCount {
+		return len(docs), nil
+	}
+
+	var (
+		sum     float64
+		numbers int
+		extreme any
+	)
+
+	for _, doc := range docs {
+		value := doc[metric.Field]
+
+		if number, ok := toFloat(value); ok {
+			sum += number
+			numbers++
+		}
+
+		if value == nil {
+			continue
+		}
+
+		if extreme == nil || (metric.Op == types.AggMin && compareValues(value, extreme) < 0) || (metric.Op == types.AggMax && compareValues(value, extreme) > 0) {
+			extreme = value
+		}
+	}
+
+	switch metric.Op {
+	case types.AggSum:
+		return sum, nil
+	case types.AggAvg:
+		if numbers == 0 {
+			return nil, nil
+		}
+
+		return sum / float64(numbers), nil
+	case types.AggMin, types.AggMax:
+		return jsonCompatible(extreme), nil
+	default:
+		return nil, fmt.Errorf("unsupported metric %s", metric.Op)
+	}
+}
+
+func (r *MemoryRepo[T]) Aggregate(filtersAndSorts *types.GetAllFiltersAndSorts, query types.AggregateQuery) ([]map[string]any, error) {
+	if filtersAndSorts == nil {
+		filtersAndSorts = &types.GetAllFiltersAndSorts{}
+	}
+
+	docs, err := r.matchDocs(filtersAndSorts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	type bucket struct {
+		key  []any
+		docs []bson.M
+	}
+
+	var (
+		buckets []*bucket
+		indexed = map[string]*bucket{}
+	)
+
+	for _, doc := range docs {
+		var key []any
+
+		for _, field := range query.GroupBy {
+			key = append(key, doc[field])
+		}
+
+		if query.Interval != nil {
+			key = append(key, truncateTime(doc[query.Interval.Field], query.Interval.Unit))
+		}
+
+		hash := fmt.Sprintf("%#v", key)
+
+		if _, ok := indexed[hash]; !ok {
+			indexed[hash] = &bucket{key: key}
+			buckets = append(buckets, indexed[hash])
+		}
+
+		indexed[hash].docs = append(indexed[hash].docs, doc)
+	}
+
+	sort.SliceStable(buckets, func(i, j int) bool {
+		for k := range buckets[i].key {
+			if result := compareValues(buckets[i].key[k], buckets[j].key[k]); result != 0 {
+				return result < 0
+			}
+		}
+
+		return false
+	})
+
+	if query.Limit > 0 && len(buckets) > query.Limit {
+		buckets = buckets[:query.Limit]
+	}
+
+	results := []map[string]any{}
+
+	for _, bucket := range buckets {
+		result := map[string]any{}
+
+		for i, field := range query.GroupBy {
+			result[field] = jsonCompatible(bucket.key[i])
+		}
+
+		if query.Interval != nil {
+			result[AggregatePeriodField] = bucket.key[len(query.GroupBy)]
+		}
+
+		for _, metric := range query.Metrics {
+			value, err := computeMetric(bucket.docs, metric)
+
+			if err != nil {
+				return nil, err
+			}
+
+			result[metric.Key()] = value
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+var _ AggregateRepository[any] = (*BaseRepo[any])(nil)
+var _ AggregateRepository[any] = (*SQLRepo[any])(nil)
+var _ AggregateRepository[any] = (*MemoryRepo[any])(nil)
@@ -3,6 +3,9 @@ package repo
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
@@ -11,11 +14,56 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
+// Repository contract shared by every database backed repo, refer BaseRepo (MongoDB) and SQLRepo (Postgres/SQLite)
+type Repository[T any] interface {
+	Create(data any) (*T, error)
+	GetAll(paginated bool, filtersAndSorts *types.GetAllFiltersAndSorts, paginationParams *types.PaginationParams) (*types.PaginatedRecords[T], error)
+	GetByID(id any) (*T, error)
+	UpdateByID(id any, data any) (*T, error)
+	DeleteByID(id any) (bool, error)
+}
+
+// Returned when an update carries a version that no longer matches the stored record
+var ErrVersionConflict = errors.New("version conflict")
+
+// Repository with optimistic concurrency enabled, UpdateByID honours "version" in update data
+type VersionedRepository[T any] interface {
+	Repository[T]
+	UpdateByIDIfVersion(id any, version int64, data any) (*T, error) // Update only when stored version matches, otherwise ErrVersionConflict
+}
+
+// Repository with soft delete enabled, DeleteByID only marks deletedAt
+type SoftDeleteRepository[T any] interface {
+	Repository[T]
+	GetByIDWithDeleted(id any) (*T, error)        // Get single record regardless of soft deleted state
+	Restore(id any) (*T, error)                   // Unset deletedAt, returns nil when record is not soft deleted
+	Purge(olderThan time.Duration) (int64, error) // Permanently delete records soft deleted longer than given duration
+}
+
 type BaseRepo[T any] struct {
 	DB         types.AppDB
 	Collection string
 	UpdatedAt  bool
 	CreatedAt  bool
+	SoftDelete bool              // Base repo soft delete config, mark deletedAt instead of removing the document, default is false
+	Versioned  bool              // Base repo optimistic concurrency config, version field is incremented on every update, default is false
+	Indexes    []types.IndexSpec // Indexes on top of ones declared by T, created by SyncIndexes
+	ctx        context.Context
+}
+
+// Bind repo calls to context, calls made with a WithTransaction context join the transaction automatically
+func (r *BaseRepo[T]) WithContext(ctx context.Context) *BaseRepo[T] {
+	clone := *r
+	clone.ctx = ctx
+	return &clone
+}
+
+func (r *BaseRepo[T]) getContext() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+
+	return context.TODO()
 }
 
 func bindData(input any, outputSchema any) error {
@@ -48,6 +96,176 @@ func bindBson(input any) (bson.M, error) {
 	return updateMap, nil
 }
 
+// Apply createdAt/updatedAt timestamps to a new record according to repo timestamp config
+func stampTimestamps(parsed bson.M, createdAt bool, updatedAt bool) {
+	now := time.Now()
+
+	if createdAt {
+		parsed["createdAt"] = now
+	}
+
+	if updatedAt {
+		parsed["updatedAt"] = now
+	}
+}
+
+// Translate query params filters into mongo $match stage contents
+func buildMatchStages(filters []types.QueryParamsFilter) bson.D {
+	matchStages := bson.D{}
+	for _, item := range filters {
+		if condition, ok := matchCondition(item); ok {
+			matchStages = append(matchStages, condition)
+		}
+	}
+
+	return matchStages
+}
+
+func matchCondition(item types.QueryParamsFilter) (bson.E, bool) {
+	switch item.Operator {
+	case types.OpLike:
+		return bson.E{Key: item.Field, Value: bson.D{{
+			Key: "$regex", Value: fmt.Sprint(item.Value),
+		}, {Key: "$options", Value: "i"}}}, true
+	case types.OpStart:
+		return bson.E{Key: item.Field, Value: bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(fmt.Sprint(item.Value))}, {Key: "$options", Value: "i"}}}, true
+	case types.OpEnd:
+		return bson.E{Key: item.Field, Value: bson.D{{Key: "$regex", Value: regexp.QuoteMeta(fmt.Sprint(item.Value)) + "$"}, {Key: "$options", Value: "i"}}}, true
+	case types.OpGte:
+		return bson.E{Key: item.Field, Value: bson.D{{Key: "$gte", Value: item.Value}}}, true
+	case types.OpLte:
+		return bson.E{Key: item.Field, Value: bson.D{{Key: "$lte", Value: item.Value}}}, true
+	case types.OpEq:
+		return bson.E{Key: item.Field, Value: item.Value}, true
+	}
+
+	return bson.E{}, false
+}
+
+// Translate nested query group into mongo $match stage contents, flat and groups keep the plain field form
+func buildGroupMatch(group types.QueryGroup) bson.D {
+	if group.Logic != types.LogicOr && len(group.Groups) == 0 {
+		return buildMatchStages(group.Filters)
+	}
+
+	conditions := bson.A{}
+
+	for _, item := range group.Filters {
+		if condition, ok := matchCondition(item); ok {
+			conditions = append(conditions, bson.D{condition})
+		}
+	}
+
+	for _, subGroup := range group.Groups {
+		if match := buildGroupMatch(subGroup); len(match) > 0 {
+			conditions = append(conditions, match)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return bson.D{}
+	}
+
+	if group.Logic == types.LogicOr {
+		return bson.D{{Key: "$or", Value: conditions}}
+	}
+
+	return bson.D{{Key: "$and", Value: conditions}}
+}
+
+// Pull client supplied version out of update data, explicit expected version takes precedence
+func takeVersion(parsed bson.M, expectedVersion *int64) (*int64, error) {
+	value, ok := parsed["version"]
+	delete(parsed, "version")
+
+	if expectedVersion != nil {
+		return expectedVersion, nil
+	}
+
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	var version int64
+
+	switch v := value.(type) {
+	case int32:
+		version = int64(v)
+	case int64:
+		version = v
+	case int:
+		version = int64(v)
+	case float64:
+		version = int64(v)
+	default:
+		return nil, fmt.Errorf("invalid version value %v", value)
+	}
+
+	return &version, nil
+}
+
+// Soft deleted visibility condition, nil when soft delete is disabled or all records are visible
+func (r *BaseRepo[T]) deletedCondition(mode types.DeletedMode) *bson.E {
+	if !r.SoftDelete {
+		return nil
+	}
+
+	switch mode {
+	case types.DeletedWith:
+		return nil
+	case types.DeletedOnly:
+		return &bson.E{Key: "deletedAt", Value: bson.D{{Key: "$ne", Value: nil}}}
+	default:
+		return &bson.E{Key: "deletedAt", Value: nil}
+	}
+}
+
+// Filter matching records excluding soft deleted ones
+func (r *BaseRepo[T]) visibleFilter(filters []types.QueryParamsFilter) bson.D {
+	filter := buildMatchStages(filters)
+
+	if condition := r.deletedCondition(types.DeletedExclude); condition != nil {
+		filter = append(filter, *condition)
+	}
+
+	return filter
+}
+
+// $match stage contents of filters, search and soft deleted visibility
+func (r *BaseRepo[T]) matchStage(filtersAndSorts *types.GetAllFiltersAndSorts) bson.D {
+	matchStages := buildGroupMatch(filtersAndSorts.Conditions())
+
+	if condition := r.deletedCondition(filtersAndSorts.DeletedMode); condition != nil {
+		matchStages = append(matchStages, *condition)
+	}
+
+	// $text must be part of the first stage
+	if filtersAndSorts.Search != "" {
+		matchStages = append(bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: filtersAndSorts.Search}}}}, matchStages...)
+	}
+
+	return matchStages
+}
+
+// Translate query params sort fields into mongo $sort stage contents
+func buildSortStages(sortFields []types.QueryParamsSortField) bson.D {
+	sortStages := bson.D{}
+	for _, item := range sortFields {
+		if item.Field != "" {
+
+			value := 1
+
+			if item.Descending {
+				value = -1
+			}
+
+			sortStages = append(sortStages, bson.E{Key: item.Field, Value: value})
+		}
+	}
+
+	return sortStages
+}
+
 func (r *BaseRepo[T]) Create(data any) (*T, error) {
 	parsed, parsedErr := bindBson(data)
 
@@ -55,19 +273,10 @@ func (r *BaseRepo[T]) Create(data any) (*T, error) {
 		return nil, parsedErr
 	}
 
-	now := time.Now()
-
-	if r.CreatedAt && r.UpdatedAt {
-		parsed["updatedAt"] = now
-		parsed["createdAt"] = now
-	} else {
-		if r.CreatedAt {
-			parsed["createdAt"] = now
-		}
+	stampTimestamps(parsed, r.CreatedAt, r.UpdatedAt)
 
-		if r.UpdatedAt {
-			parsed["createdAt"] = now
-		}
+	if r.Versioned {
+		parsed["version"] = 1
 	}
 
 	var (
@@ -79,9 +288,9 @@ func (r *BaseRepo[T]) Create(data any) (*T, error) {
 		return nil, bindErr
 	}
 
-	created, err := r.DB.MongoDB.Collection(r.Collection).InsertOne(context.TODO(), typed)
+	created, err := r.DB.MongoDB.Collection(r.Collection).InsertOne(r.getContext(), typed)
 	if err != nil {
-		return nil, err
+		return nil, mongoError(err)
 	}
 
 	createdObj, err := r.GetByID(created.InsertedID)
@@ -107,41 +316,29 @@ func (r *BaseRepo[T]) GetAll(paginated bool, filtersAndSorts *types.GetAllFilter
 		total                           int32 = 0
 	)
 
-	if filtersAndSorts != nil {
-		matchStages := bson.D{}
-		for _, item := range filtersAndSorts.QueryParamsFilters {
-			switch item.Operator {
-			case types.OpLike:
-				matchStages = append(matchStages, bson.E{Key: item.Field, Value: bson.D{{
-					Key: "$regex", Value: item.Value,
-				}, {Key: "$options", Value: "i"}}})
-			case types.OpEq:
-				matchStages = append(matchStages, bson.E{Key: item.Field, Value: item.Value})
-			}
-		}
-
-		sortStages := bson.D{}
-		for _, item := range filtersAndSorts.QueryParamsSortFields {
-			if item.Field != "" {
+	if filtersAndSorts == nil {
+		filtersAndSorts = &types.GetAllFiltersAndSorts{}
+	}
 
-				value := 1
+	includes, err := resolveIncludes(r.Collection, filtersAndSorts.Includes)
 
-				if item.Descending {
-					value = -1
-				}
+	if err != nil {
+		return nil, err
+	}
 
-				sortStages = append(sortStages, bson.E{Key: item.Field, Value: value})
-			}
-		}
+	matchStages := r.matchStage(filtersAndSorts)
+	sortStages := buildSortStages(relevanceSorts(filtersAndSorts.QueryParamsSortFields, filtersAndSorts.Search))
 
-		if len(matchStages) > 0 {
-			pipelineStagesWithoutPagination = append(pipelineStagesWithoutPagination, bson.D{{Key: "$match", Value: matchStages}})
-		}
+	if len(matchStages) > 0 {
+		pipelineStagesWithoutPagination = append(pipelineStagesWithoutPagination, bson.D{{Key: "$match", Value: matchStages}})
+	}
 
-		if len(sortStages) > 0 {
-			pipelineStagesWithoutPagination = append(pipelineStagesWithoutPagination, bson.D{{Key: "$sort", Value: sortStages}})
-		}
+	if filtersAndSorts.Search != "" {
+		pipelineStagesWithoutPagination = append(pipelineStagesWithoutPagination, bson.D{{Key: "$addFields", Value: bson.D{{Key: SearchScoreField, Value: bson.D{{Key: "$meta", Value: "textScore"}}}}}})
+	}
 
+	if len(sortStages) > 0 {
+		pipelineStagesWithoutPagination = append(pipelineStagesWithoutPagination, bson.D{{Key: "$sort", Value: sortStages}})
 	}
 
 	if paginated {
@@ -160,20 +357,31 @@ func (r *BaseRepo[T]) GetAll(paginated bool, filtersAndSorts *types.GetAllFilter
 		}}, bson.D{{Key: "$limit", Value: perPage}})
 	} else {
 		pipelineStages = pipelineStagesWithoutPagination
+
+		if filtersAndSorts.Limit > 0 {
+			pipelineStages = append(pipelineStages, bson.D{{Key: "$limit", Value: filtersAndSorts.Limit}})
+		}
+	}
+
+	// Lookups run after pagination so only returned records are joined
+	pipelineStages = append(pipelineStages, lookupStages(includes)...)
+
+	if project := projectStage(projectedFields(filtersAndSorts.Fields, filtersAndSorts.Includes)); project != nil {
+		pipelineStages = append(pipelineStages, project)
 	}
 
-	results, err := r.DB.MongoDB.Collection(r.Collection).Aggregate(context.TODO(), pipelineStages)
+	results, err := r.aggregateSearch(filtersAndSorts.Search, pipelineStages)
 	if err != nil {
 		return nil, err
 	}
 
 	if paginated {
-		if countResults, err := r.DB.MongoDB.Collection(r.Collection).Aggregate(context.TODO(), append(pipelineStagesWithoutPagination, bson.D{{Key: "$count", Value: "count"}})); err != nil {
+		if countResults, err := r.DB.MongoDB.Collection(r.Collection).Aggregate(r.getContext(), append(pipelineStagesWithoutPagination, bson.D{{Key: "$count", Value: "count"}})); err != nil {
 			return nil, err
 		} else {
 			var result bson.M
 
-			if ok := countResults.Next(context.TODO()); !ok {
+			if ok := countResults.Next(r.getContext()); !ok {
 				total = 0
 			} else {
 				if err := countResults.Decode(&result); err != nil {
@@ -186,7 +394,7 @@ func (r *BaseRepo[T]) GetAll(paginated bool, filtersAndSorts *types.GetAllFilter
 	}
 
 	var typedResults []T
-	for results.Next(context.TODO()) {
+	for results.Next(r.getContext()) {
 		var result T
 		if err := results.Decode(&result); err != nil {
 			return nil, err
@@ -214,6 +422,14 @@ func (r *BaseRepo[T]) GetAll(paginated bool, filtersAndSorts *types.GetAllFilter
 }
 
 func (r *BaseRepo[T]) GetByID(id any) (*T, error) {
+	return r.getByID(id, types.DeletedExclude)
+}
+
+func (r *BaseRepo[T]) GetByIDWithDeleted(id any) (*T, error) {
+	return r.getByID(id, types.DeletedWith)
+}
+
+func (r *BaseRepo[T]) getByID(id any, mode types.DeletedMode) (*T, error) {
 	if _, ok := id.(bson.ObjectID); !ok {
 		if objectId, err := bson.ObjectIDFromHex(id.(string)); err != nil {
 			return nil, err
@@ -224,7 +440,13 @@ func (r *BaseRepo[T]) GetByID(id any) (*T, error) {
 
 	var result T
 
-	if err := r.DB.MongoDB.Collection(r.Collection).FindOne(context.TODO(), bson.M{"_id": id}).Decode(&result); err != nil {
+	filter := bson.D{{Key: "_id", Value: id}}
+
+	if condition := r.deletedCondition(mode); condition != nil {
+		filter = append(filter, *condition)
+	}
+
+	if err := r.DB.MongoDB.Collection(r.Collection).FindOne(r.getContext(), filter).Decode(&result); err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		} else {
@@ -236,6 +458,14 @@ func (r *BaseRepo[T]) GetByID(id any) (*T, error) {
 }
 
 func (r *BaseRepo[T]) UpdateByID(id any, data any) (*T, error) {
+	return r.updateByID(id, nil, data)
+}
+
+func (r *BaseRepo[T]) UpdateByIDIfVersion(id any, version int64, data any) (*T, error) {
+	return r.updateByID(id, &version, data)
+}
+
+func (r *BaseRepo[T]) updateByID(id any, expectedVersion *int64, data any) (*T, error) {
 	if _, ok := id.(bson.ObjectID); !ok {
 		if objectId, err := bson.ObjectIDFromHex(id.(string)); err != nil {
 			return nil, err
@@ -244,21 +474,29 @@ func (r *BaseRepo[T]) UpdateByID(id any, data any) (*T, error) {
 		}
 	}
 
-	parsed, parsedErr := bindBson(data)
+	filter, update, _, buildErr := r.buildUpdate(id.(bson.ObjectID), expectedVersion, data)
 
-	if parsedErr != nil {
-		return nil, parsedErr
+	if buildErr != nil {
+		return nil, buildErr
 	}
 
-	if r.UpdatedAt {
-		parsed["updatedAt"] = time.Now()
-	}
+	if len(update) > 0 {
+		result, err := r.DB.MongoDB.Collection(r.Collection).UpdateOne(r.getContext(), filter, update)
 
-	if _, err := r.DB.MongoDB.Collection(r.Collection).UpdateByID(context.TODO(), id, bson.D{{Key: "$set", Value: parsed}}); err != nil {
-		return nil, err
+		if err != nil {
+			return nil, mongoError(err)
+		}
+
+		if result.MatchedCount == 0 && len(filter) > 1 {
+			if existing, err := r.GetByIDWithDeleted(id); err != nil {
+				return nil, err
+			} else if existing != nil {
+				return nil, ErrVersionConflict
+			}
+		}
 	}
 
-	updated, updateErr := r.GetByID(id)
+	updated, updateErr := r.GetByIDWithDeleted(id)
 
 	if updateErr != nil {
 		return nil, updateErr
@@ -284,7 +522,23 @@ func (r *BaseRepo[T]) DeleteByID(id any) (bool, error) {
 
 	filter := bson.M{"_id": id}
 
-	result, err := r.DB.MongoDB.Collection(r.Collection).DeleteOne(context.TODO(), filter)
+	if r.SoftDelete {
+		update := bson.M{"deletedAt": time.Now()}
+
+		if r.UpdatedAt {
+			update["updatedAt"] = update["deletedAt"]
+		}
+
+		result, err := r.DB.MongoDB.Collection(r.Collection).UpdateOne(r.getContext(), bson.M{"_id": id, "deletedAt": nil}, bson.D{{Key: "$set", Value: update}})
+
+		if err != nil {
+			return false, err
+		}
+
+		return result.MatchedCount > 0, nil
+	}
+
+	result, err := r.DB.MongoDB.Collection(r.Collection).DeleteOne(r.getContext(), filter)
 
 	if err != nil {
 		return false, err
@@ -296,3 +550,45 @@ func (r *BaseRepo[T]) DeleteByID(id any) (bool, error) {
 		return false, nil
 	}
 }
+
+func (r *BaseRepo[T]) Restore(id any) (*T, error) {
+	if _, ok := id.(bson.ObjectID); !ok {
+		if objectId, err := bson.ObjectIDFromHex(id.(string)); err != nil {
+			return nil, err
+		} else {
+			id = objectId
+		}
+	}
+
+	update := bson.M{"deletedAt": nil}
+
+	if r.UpdatedAt {
+		update["updatedAt"] = time.Now()
+	}
+
+	result, err := r.DB.MongoDB.Collection(r.Collection).UpdateOne(r.getContext(), bson.M{"_id": id, "deletedAt": bson.M{"$ne": nil}}, bson.D{{Key: "$set", Value: update}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, nil
+	}
+
+	return r.GetByID(id)
+}
+
+func (r *BaseRepo[T]) Purge(olderThan time.Duration) (int64, error) {
+	result, err := r.DB.MongoDB.Collection(r.Collection).DeleteMany(r.getContext(), bson.M{"deletedAt": bson.M{"$ne": nil, "$lte": time.Now().Add(-olderThan)}})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+var _ Repository[any] = (*BaseRepo[any])(nil)
+var _ SoftDeleteRepository[any] = (*BaseRepo[any])(nil)
+var _ VersionedRepository[any] = (*BaseRepo[any])(nil)
@@ -0,0 +1,789 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Returned for items not attempted because an earlier item failed in ordered mode
+var ErrBulkSkipped = errors.New("skipped after an earlier item failed")
+
+// Returned for bulk update / delete items whose record does not exist
+var ErrRecordNotFound = errors.New("record not found")
+
+// Outcome of a single bulk item, Index is the position of the item in the input slice
+type BulkResult[T any] struct {
+	Index  int
+	Record *T    // Created or updated record, nil for deletes and failed items
+	Error  error // Item failure, nil on success
+}
+
+type BulkUpdate struct {
+	ID   any // Record id
+	Data any // Properties to set, "version" is honoured by versioned repos
+}
+
+// Repository supporting bulk writes, ordered mode stops at the first failing item while unordered mode attempts every item
+type BulkRepository[T any] interface {
+	Repository[T]
+	CreateMany(data []any, ordered bool) ([]BulkResult[T], error)
+	UpdateManyByID(updates []BulkUpdate, ordered bool) ([]BulkResult[T], error)
+	DeleteManyByID(ids []any, ordered bool) ([]BulkResult[T], error)
+	UpdateMany(filters []types.QueryParamsFilter, data any) (int64, error) // Set data on every matching record, returns matched count
+	DeleteMany(filters []types.QueryParamsFilter) (int64, error)           // Delete (or soft delete) every matching record, returns affected count
+}
+
+// Per item errors of a bulk operation, tracks which input item each write model belongs to
+type bulkPlan struct {
+	errs      []error
+	positions []int // Input index of every planned write model
+	ordered   bool
+}
+
+func newBulkPlan(size int, ordered bool) *bulkPlan {
+	return &bulkPlan{errs: make([]error, size), ordered: ordered}
+}
+
+// Record item preparation failure, returns false when remaining items must not be planned
+func (p *bulkPlan) fail(index int, err error) bool {
+	p.errs[index] = err
+
+	if p.ordered {
+		p.skipFrom(index + 1)
+		return false
+	}
+
+	return true
+}
+
+func (p *bulkPlan) skipFrom(index int) {
+	for i := index; i < len(p.errs); i++ {
+		if p.errs[i] == nil {
+			p.errs[i] = ErrBulkSkipped
+		}
+	}
+}
+
+// Map bulk write exception back onto input items, other errors fail the whole operation
+func (p *bulkPlan) applyWriteError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var bulkException mongo.BulkWriteException
+
+	if !errors.As(err, &bulkException) || len(bulkException.WriteErrors) == 0 {
+		return err
+	}
+
+	firstFailed := len(p.positions)
+
+	for _, writeError := range bulkException.WriteErrors {
+		if writeError.Index < 0 || writeError.Index >= len(p.positions) {
+			continue
+		}
+
+		p.errs[p.positions[writeError.Index]] = mongoError(mongo.WriteException{WriteErrors: []mongo.WriteError{writeError.WriteError}})
+		firstFailed = min(firstFailed, writeError.Index)
+	}
+
+	if p.ordered {
+		// Server stops at the first failing write in ordered mode
+		for _, position := range p.positions[firstFailed:] {
+			if p.errs[position] == nil {
+				p.errs[position] = ErrBulkSkipped
+			}
+		}
+	}
+
+	return nil
+}
+
+func bulkResults[T any](errs []error, records map[int]*T) []BulkResult[T] {
+	results := make([]BulkResult[T], len(errs))
+
+	for i, err := range errs {
+		results[i] = BulkResult[T]{Index: i, Error: err}
+
+		if err == nil {
+			results[i].Record = records[i]
+		}
+	}
+
+	return results
+}
+
+// Run bulk items one by one, used by backends without native bulk writes
+func runEach[T any](size int, ordered bool, fn func(index int) (*T, error)) []BulkResult[T] {
+	errs := make([]error, size)
+	records := map[int]*T{}
+
+	for i := 0; i < size; i++ {
+		if ordered && i > 0 && errs[i-1] != nil {
+			errs[i] = ErrBulkSkipped
+			continue
+		}
+
+		records[i], errs[i] = fn(i)
+	}
+
+	return bulkResults(errs, records)
+}
+
+// Fetch records by ids in a single query keyed by id, soft deleted records are left out
+func (r *BaseRepo[T]) findByIDs(ids []bson.ObjectID) (map[bson.ObjectID]*T, error) {
+	found := map[bson.ObjectID]*T{}
+
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	filter := bson.D{{Key: "_id", Value: bson.M{"$in": ids}}}
+
+	if condition := r.deletedCondition(types.DeletedExclude); condition != nil {
+		filter = append(filter, *condition)
+	}
+
+	cursor, err := r.DB.MongoDB.Collection(r.Collection).Find(r.getContext(), filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(r.getContext())
+
+	for cursor.Next(r.getContext()) {
+		id, ok := cursor.Current.Lookup("_id").ObjectIDOK()
+
+		if !ok {
+			continue
+		}
+
+		var result T
+
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+
+		found[id] = &result
+	}
+
+	return found, cursor.Err()
+}
+
+func (r *BaseRepo[T]) CreateMany(data []any, ordered bool) ([]BulkResult[T], error) {
+	plan := newBulkPlan(len(data), ordered)
+	ids := make([]bson.ObjectID, len(data))
+
+	var docs []any
+
+	for i, item := range data {
+		doc, err := r.prepareCreate(item)
+
+		if err != nil {
+			if !plan.fail(i, err) {
+				break
+			}
+
+			continue
+		}
+
+		id, ok := doc["_id"].(bson.ObjectID)
+
+		if !ok || id.IsZero() {
+			id = bson.NewObjectID()
+			doc["_id"] = id
+		}
+
+		ids[i] = id
+		docs = append(docs, doc)
+		plan.positions = append(plan.positions, i)
+	}
+
+	if len(docs) > 0 {
+		_, err := r.DB.MongoDB.Collection(r.Collection).InsertMany(r.getContext(), docs, options.InsertMany().SetOrdered(ordered))
+
+		if err := plan.applyWriteError(err); err != nil {
+			return nil, err
+		}
+	}
+
+	created, err := r.findByIDs(ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := map[int]*T{}
+	for i, id := range ids {
+		records[i] = created[id]
+	}
+
+	return bulkResults(plan.errs, records), nil
+}
+
+// Bind create data through T with timestamps and version applied, mirrors Create
+func (r *BaseRepo[T]) prepareCreate(data any) (bson.M, error) {
+	parsed, err := bindBson(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stampTimestamps(parsed, r.CreatedAt, r.UpdatedAt)
+
+	if r.Versioned {
+		parsed["version"] = 1
+	}
+
+	var typed T
+
+	if err := bindData(parsed, &typed); err != nil {
+		return nil, err
+	}
+
+	return bindBson(typed)
+}
+
+func (r *BaseRepo[T]) UpdateManyByID(updates []BulkUpdate, ordered bool) ([]BulkResult[T], error) {
+	plan := newBulkPlan(len(updates), ordered)
+	ids := make([]bson.ObjectID, len(updates))
+	versions := make([]*int64, len(updates))
+
+	for i, item := range updates {
+		id, err := parseObjectID(item.ID)
+
+		if err != nil {
+			if !plan.fail(i, err) {
+				break
+			}
+
+			continue
+		}
+
+		ids[i] = id
+	}
+
+	// Existing records are looked up first so ordered mode stops at the first missing or stale item before writing
+	existing, err := r.findByIDs(ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var models []mongo.WriteModel
+
+	for i, item := range updates {
+		if plan.errs[i] != nil {
+			continue
+		}
+
+		filter, update, version, err := r.buildUpdate(ids[i], nil, item.Data)
+
+		if err == nil {
+			if record, ok := existing[ids[i]]; !ok {
+				err = ErrRecordNotFound
+			} else if version != nil && recordVersion(record) != *version {
+				err = ErrVersionConflict
+			}
+		}
+
+		if err != nil {
+			if !plan.fail(i, err) {
+				break
+			}
+
+			continue
+		}
+
+		if condition := r.deletedCondition(types.DeletedExclude); condition != nil {
+			filter = append(filter, *condition)
+		}
+
+		versions[i] = version
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update))
+		plan.positions = append(plan.positions, i)
+	}
+
+	if len(models) > 0 {
+		_, err := r.DB.MongoDB.Collection(r.Collection).BulkWrite(r.getContext(), models, options.BulkWrite().SetOrdered(ordered))
+
+		if err := plan.applyWriteError(err); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := r.findByIDs(ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := map[int]*T{}
+
+	for _, i := range plan.positions {
+		if plan.errs[i] != nil {
+			continue
+		}
+
+		record, ok := updated[ids[i]]
+
+		if !ok {
+			plan.errs[i] = ErrRecordNotFound
+			continue
+		}
+
+		// Bulk write result has no per item match count, stored version tells whether a concurrent write got in between
+		if versions[i] != nil && recordVersion(record) != *versions[i]+1 {
+			plan.errs[i] = ErrVersionConflict
+			continue
+		}
+
+		records[i] = record
+	}
+
+	return bulkResults(plan.errs, records), nil
+}
+
+// Build filter and update document for a single record update, shared by updateByID and bulk updates
+func (r *BaseRepo[T]) buildUpdate(id bson.ObjectID, expectedVersion *int64, data any) (bson.D, bson.D, *int64, error) {
+	parsed, err := bindBson(data)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	delete(parsed, "_id")
+
+	if r.UpdatedAt {
+		parsed["updatedAt"] = time.Now()
+	}
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{}
+
+	var version *int64
+
+	if r.Versioned {
+		if version, err = takeVersion(parsed, expectedVersion); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if version != nil {
+			filter = append(filter, bson.E{Key: "version", Value: *version})
+		}
+
+		update = append(update, bson.E{Key: "$inc", Value: bson.M{"version": 1}})
+	}
+
+	if len(parsed) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: parsed})
+	}
+
+	return filter, update, version, nil
+}
+
+func (r *BaseRepo[T]) DeleteManyByID(ids []any, ordered bool) ([]BulkResult[T], error) {
+	plan := newBulkPlan(len(ids), ordered)
+	objectIDs := make([]bson.ObjectID, len(ids))
+
+	for i, id := range ids {
+		objectId, err := parseObjectID(id)
+
+		if err != nil {
+			if !plan.fail(i, err) {
+				break
+			}
+
+			continue
+		}
+
+		objectIDs[i] = objectId
+	}
+
+	// Existing records are looked up first, bulk write result has no per item deleted count
+	existing, err := r.findByIDs(objectIDs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var models []mongo.WriteModel
+
+	for i, id := range objectIDs {
+		if plan.errs[i] != nil {
+			continue
+		}
+
+		if _, ok := existing[id]; !ok {
+			if !plan.fail(i, ErrRecordNotFound) {
+				break
+			}
+
+			continue
+		}
+
+		if r.SoftDelete {
+			update := bson.M{"deletedAt": now}
+
+			if r.UpdatedAt {
+				update["updatedAt"] = now
+			}
+
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id, "deletedAt": nil}).SetUpdate(bson.D{{Key: "$set", Value: update}}))
+		} else {
+			models = append(models, mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": id}))
+		}
+
+		plan.positions = append(plan.positions, i)
+	}
+
+	if len(models) > 0 {
+		_, err := r.DB.MongoDB.Collection(r.Collection).BulkWrite(r.getContext(), models, options.BulkWrite().SetOrdered(ordered))
+
+		if err := plan.applyWriteError(err); err != nil {
+			return nil, err
+		}
+	}
+
+	return bulkResults[T](plan.errs, nil), nil
+}
+
+// Stored version of record, zero when unversioned
+func recordVersion(record any) int64 {
+	var fields struct {
+		Version int64 `json:"version"`
+	}
+
+	bindData(record, &fields)
+
+	return fields.Version
+}
+
+func (r *BaseRepo[T]) UpdateMany(filters []types.QueryParamsFilter, data any) (int64, error) {
+	parsed, err := bindBson(data)
+
+	if err != nil {
+		return 0, err
+	}
+
+	delete(parsed, "_id")
+	delete(parsed, "version")
+
+	if r.UpdatedAt {
+		parsed["updatedAt"] = time.Now()
+	}
+
+	update := bson.D{}
+
+	if r.Versioned {
+		update = append(update, bson.E{Key: "$inc", Value: bson.M{"version": 1}})
+	}
+
+	if len(parsed) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: parsed})
+	}
+
+	if len(update) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.DB.MongoDB.Collection(r.Collection).UpdateMany(r.getContext(), r.visibleFilter(filters), update)
+
+	if err != nil {
+		return 0, mongoError(err)
+	}
+
+	return result.MatchedCount, nil
+}
+
+func (r *BaseRepo[T]) DeleteMany(filters []types.QueryParamsFilter) (int64, error) {
+	if r.SoftDelete {
+		now := time.Now()
+		update := bson.M{"deletedAt": now}
+
+		if r.UpdatedAt {
+			update["updatedAt"] = now
+		}
+
+		result, err := r.DB.MongoDB.Collection(r.Collection).UpdateMany(r.getContext(), r.visibleFilter(filters), bson.D{{Key: "$set", Value: update}})
+
+		if err != nil {
+			return 0, err
+		}
+
+		return result.ModifiedCount, nil
+	}
+
+	result, err := r.DB.MongoDB.Collection(r.Collection).DeleteMany(r.getContext(), r.visibleFilter(filters))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func (r *SQLRepo[T]) CreateMany(data []any, ordered bool) ([]BulkResult[T], error) {
+	return runEach(len(data), ordered, func(i int) (*T, error) {
+		return r.Create(data[i])
+	}), nil
+}
+
+func (r *SQLRepo[T]) UpdateManyByID(updates []BulkUpdate, ordered bool) ([]BulkResult[T], error) {
+	return runEach(len(updates), ordered, func(i int) (*T, error) {
+		return requireRecord(updateVisible(r, updates[i].ID, updates[i].Data))
+	}), nil
+}
+
+func (r *SQLRepo[T]) DeleteManyByID(ids []any, ordered bool) ([]BulkResult[T], error) {
+	return runEach(len(ids), ordered, func(i int) (*T, error) {
+		return nil, requireDeleted(r.DeleteByID(ids[i]))
+	}), nil
+}
+
+func (r *SQLRepo[T]) UpdateMany(filters []types.QueryParamsFilter, data any) (int64, error) {
+	parsed, err := bindBson(data)
+
+	if err != nil {
+		return 0, err
+	}
+
+	delete(parsed, "_id")
+	delete(parsed, "version")
+
+	if r.UpdatedAt {
+		parsed["updatedAt"] = time.Now()
+	}
+
+	var (
+		assignments []string
+		args        []any
+	)
+
+	if r.Versioned {
+		assignments = append(assignments, fmt.Sprintf("%s = %s + 1", quoteIdentifier("version"), quoteIdentifier("version")))
+	}
+
+	for key, value := range parsed {
+		column, err := r.column(key)
+
+		if err != nil {
+			continue
+		}
+
+		arg, err := sqlValue(value)
+
+		if err != nil {
+			return 0, err
+		}
+
+		args = append(args, arg)
+		assignments = append(assignments, fmt.Sprintf("%s = %s", quoteIdentifier(column.Name), r.placeholder(len(args))))
+	}
+
+	if len(assignments) == 0 {
+		return 0, nil
+	}
+
+	where, args, err := r.buildWhere(filters, types.DeletedExclude, args)
+
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.executor().ExecContext(r.getContext(), fmt.Sprintf("UPDATE %s SET %s%s", quoteIdentifier(r.Table), strings.Join(assignments, ", "), where), args...)
+
+	if err != nil {
+		return 0, sqlError(err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *SQLRepo[T]) DeleteMany(filters []types.QueryParamsFilter) (int64, error) {
+	var (
+		query string
+		args  []any
+	)
+
+	if r.SoftDelete {
+		now := time.Now().UTC()
+		assignments := fmt.Sprintf("%s = %s", quoteIdentifier("deletedAt"), r.placeholder(1))
+		args = []any{now}
+
+		if r.stampsUpdatedAt() {
+			args = append(args, now)
+			assignments = fmt.Sprintf("%s, %s = %s", assignments, quoteIdentifier("updatedAt"), r.placeholder(len(args)))
+		}
+
+		query = fmt.Sprintf("UPDATE %s SET %s", quoteIdentifier(r.Table), assignments)
+	} else {
+		query = fmt.Sprintf("DELETE FROM %s", quoteIdentifier(r.Table))
+	}
+
+	where, args, err := r.buildWhere(filters, types.DeletedExclude, args)
+
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.executor().ExecContext(r.getContext(), query+where, args...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *MemoryRepo[T]) CreateMany(data []any, ordered bool) ([]BulkResult[T], error) {
+	return runEach(len(data), ordered, func(i int) (*T, error) {
+		return r.Create(data[i])
+	}), nil
+}
+
+func (r *MemoryRepo[T]) UpdateManyByID(updates []BulkUpdate, ordered bool) ([]BulkResult[T], error) {
+	return runEach(len(updates), ordered, func(i int) (*T, error) {
+		return requireRecord(updateVisible(r, updates[i].ID, updates[i].Data))
+	}), nil
+}
+
+func (r *MemoryRepo[T]) DeleteManyByID(ids []any, ordered bool) ([]BulkResult[T], error) {
+	return runEach(len(ids), ordered, func(i int) (*T, error) {
+		return nil, requireDeleted(r.DeleteByID(ids[i]))
+	}), nil
+}
+
+func (r *MemoryRepo[T]) UpdateMany(filters []types.QueryParamsFilter, data any) (int64, error) {
+	parsed, err := bindBson(data)
+
+	if err != nil {
+		return 0, err
+	}
+
+	delete(parsed, "_id")
+	delete(parsed, "version")
+
+	if r.UpdatedAt {
+		parsed["updatedAt"] = time.Now()
+	}
+
+	var (
+		matched     int64
+		conflictErr error
+	)
+
+	r.store().write(r.Collection, func(docs []bson.M) []bson.M {
+		updatedDocs := make([]bson.M, len(docs))
+		copy(updatedDocs, docs)
+
+		for i, doc := range updatedDocs {
+			if !r.visible(doc, types.DeletedExclude) || !MatchFilters(doc, filters) {
+				continue
+			}
+
+			updated := cloneDoc(doc)
+
+			for key, value := range parsed {
+				updated[key] = value
+			}
+
+			if r.Versioned {
+				current, _ := toFloat(updated["version"])
+				updated["version"] = int64(current) + 1
+			}
+
+			if conflictErr = r.uniqueViolation(updatedDocs, updated); conflictErr != nil {
+				// Leave collection untouched, matching documents are updated all or nothing
+				return docs
+			}
+
+			updatedDocs[i] = updated
+			matched++
+		}
+
+		return updatedDocs
+	})
+
+	if conflictErr != nil {
+		return 0, conflictErr
+	}
+
+	return matched, nil
+}
+
+func (r *MemoryRepo[T]) DeleteMany(filters []types.QueryParamsFilter) (int64, error) {
+	var affected int64
+
+	now := time.Now()
+
+	r.store().write(r.Collection, func(docs []bson.M) []bson.M {
+		var kept []bson.M
+
+		for _, doc := range docs {
+			if !r.visible(doc, types.DeletedExclude) || !MatchFilters(doc, filters) {
+				kept = append(kept, doc)
+				continue
+			}
+
+			affected++
+
+			if r.SoftDelete {
+				updated := cloneDoc(doc)
+				updated["deletedAt"] = now
+
+				if r.UpdatedAt {
+					updated["updatedAt"] = now
+				}
+
+				kept = append(kept, updated)
+			}
+		}
+
+		return kept
+	})
+
+	return affected, nil
+}
+
+// Update record unless it is soft deleted, like the filter of BaseRepo bulk updates
+func updateVisible[T any](repo Repository[T], id any, data any) (*T, error) {
+	existing, err := repo.GetByID(id)
+
+	if err != nil || existing == nil {
+		return nil, err
+	}
+
+	return repo.UpdateByID(id, data)
+}
+
+func requireRecord[T any](record *T, err error) (*T, error) {
+	if err == nil && record == nil {
+		return nil, ErrRecordNotFound
+	}
+
+	return record, err
+}
+
+func requireDeleted(deleted bool, err error) error {
+	if err == nil && !deleted {
+		return ErrRecordNotFound
+	}
+
+	return err
+}
+
+var _ BulkRepository[any] = (*BaseRepo[any])(nil)
+var _ BulkRepository[any] = (*SQLRepo[any])(nil)
+var _ BulkRepository[any] = (*MemoryRepo[any])(nil)
@@ -0,0 +1,223 @@
+package repo
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Whether bulk item error is the wanted one, ErrConflict carries details so only its type is compared
+func isBulkError(got error, want error) bool {
+	if _, ok := want.(ErrConflict); ok {
+		return errors.As(got, new(ErrConflict))
+	}
+
+	return errors.Is(got, want)
+}
+
+func assertBulkResults[T any](t *testing.T, results []BulkResult[T], want []error) {
+	t.Helper()
+
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+
+		if !isBulkError(result.Error, want[i]) {
+			t.Errorf("item %d: got error %v, want %v", i, result.Error, want[i])
+		}
+
+		if result.Error != nil && result.Record != nil {
+			t.Errorf("item %d: failed item returned record %v", i, result.Record)
+		}
+	}
+}
+
+func TestMemoryRepoCreateMany(t *testing.T) {
+	tests := []struct {
+		name      string
+		ordered   bool
+		wantErrs  []error
+		wantCount int64
+	}{
+		{"ordered stops at first failure", true, []error{nil, ErrConflict{}, ErrBulkSkipped}, 1},
+		{"unordered attempts every item", false, []error{nil, ErrConflict{}, nil}, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &MemoryRepo[testNote]{
+				Store:      NewMemoryStore(),
+				Collection: "notes",
+				Indexes:    []types.IndexSpec{{Fields: []string{"title"}, Unique: true}},
+			}
+
+			results, err := repo.CreateMany([]any{testNote{Title: "a"}, testNote{Title: "a"}, testNote{Title: "b"}}, test.ordered)
+
+			if err != nil {
+				t.Fatalf("create many: %v", err)
+			}
+
+			assertBulkResults(t, results, test.wantErrs)
+
+			if count, _ := repo.Count(nil); count != test.wantCount {
+				t.Errorf("got count %d, want %d", count, test.wantCount)
+			}
+		})
+	}
+}
+
+func TestMemoryRepoUpdateManyByID(t *testing.T) {
+	tests := []struct {
+		name       string
+		ordered    bool
+		update     func(notes []*testNote) []BulkUpdate
+		wantErrs   []error
+		wantTitles []string
+	}{
+		{
+			"ordered stops at soft deleted record",
+			true,
+			func(notes []*testNote) []BulkUpdate {
+				return []BulkUpdate{{notes[0].ID, map[string]any{"title": "a2"}}, {notes[2].ID, map[string]any{"title": "c2"}}, {notes[1].ID, map[string]any{"title": "b2"}}}
+			},
+			[]error{nil, ErrRecordNotFound, ErrBulkSkipped},
+			[]string{"a2", "b"},
+		},
+		{
+			"unordered skips soft deleted record",
+			false,
+			func(notes []*testNote) []BulkUpdate {
+				return []BulkUpdate{{notes[0].ID, map[string]any{"title": "a2"}}, {notes[2].ID, map[string]any{"title": "c2"}}, {notes[1].ID, map[string]any{"title": "b2"}}}
+			},
+			[]error{nil, ErrRecordNotFound, nil},
+			[]string{"a2", "b2"},
+		},
+		{
+			"unknown id",
+			false,
+			func(notes []*testNote) []BulkUpdate {
+				return []BulkUpdate{{bson.NewObjectID(), map[string]any{"title": "x"}}, {notes[0].ID, map[string]any{"title": "a2"}}}
+			},
+			[]error{ErrRecordNotFound, nil},
+			[]string{"a2", "b"},
+		},
+		{
+			"stale version",
+			false,
+			func(notes []*testNote) []BulkUpdate {
+				return []BulkUpdate{{notes[0].ID, map[string]any{"title": "a2", "version": 5}}, {notes[1].ID, map[string]any{"title": "b2", "version": 1}}}
+			},
+			[]error{ErrVersionConflict, nil},
+			[]string{"a", "b2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo, notes := newTestNotes(t, true, true, testNote{Title: "a"}, testNote{Title: "b"}, testNote{Title: "c"})
+
+			if _, err := repo.DeleteByID(notes[2].ID); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+
+			results, err := repo.UpdateManyByID(test.update(notes), test.ordered)
+
+			if err != nil {
+				t.Fatalf("update many: %v", err)
+			}
+
+			assertBulkResults(t, results, test.wantErrs)
+
+			stored, err := Query[testNote]().Sort("_id").All(repo)
+
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+
+			if got := noteTitles(stored); !slices.Equal(got, test.wantTitles) {
+				t.Errorf("got titles %v, want %v", got, test.wantTitles)
+			}
+
+			if trashed, _ := repo.GetByIDWithDeleted(notes[2].ID); trashed.Title != "c" {
+				t.Errorf("soft deleted record updated to %q", trashed.Title)
+			}
+		})
+	}
+}
+
+func TestMemoryRepoDeleteManyByID(t *testing.T) {
+	tests := []struct {
+		name      string
+		ordered   bool
+		wantErrs  []error
+		wantCount int64
+	}{
+		{"ordered", true, []error{nil, ErrRecordNotFound, ErrBulkSkipped}, 1},
+		{"unordered", false, []error{nil, ErrRecordNotFound, nil}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo, notes := newTestNotes(t, false, false, testNote{Title: "a"}, testNote{Title: "b"})
+
+			results, err := repo.DeleteManyByID([]any{notes[0].ID, bson.NewObjectID().Hex(), notes[1].ID.Hex()}, test.ordered)
+
+			if err != nil {
+				t.Fatalf("delete many: %v", err)
+			}
+
+			assertBulkResults(t, results, test.wantErrs)
+
+			if count, _ := repo.Count(nil); count != test.wantCount {
+				t.Errorf("got count %d, want %d", count, test.wantCount)
+			}
+		})
+	}
+}
+
+func TestMemoryRepoUpdateAndDeleteMany(t *testing.T) {
+	repo, _ := newTestNotes(t, true, false,
+		testNote{Title: "a", Priority: 1},
+		testNote{Title: "b", Priority: 1},
+		testNote{Title: "c", Priority: 2},
+	)
+
+	lowPriority := []types.QueryParamsFilter{{Field: "priority", Operator: Eq, Value: 1}}
+
+	if updated, err := repo.UpdateMany(lowPriority, map[string]any{"tags": []string{"later"}}); err != nil || updated != 2 {
+		t.Fatalf("update many: got %d %v, want 2", updated, err)
+	}
+
+	notes, err := Query[testNote]().Where("priority", Eq, 1).All(repo)
+
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	for _, note := range notes {
+		if len(note.Tags) != 1 || note.Tags[0] != "later" {
+			t.Errorf("%s: got tags %v, want [later]", note.Title, note.Tags)
+		}
+	}
+
+	if deleted, err := repo.DeleteMany(lowPriority); err != nil || deleted != 2 {
+		t.Fatalf("delete many: got %d %v, want 2", deleted, err)
+	}
+
+	if deleted, err := repo.DeleteMany(lowPriority); err != nil || deleted != 0 {
+		t.Errorf("delete many of soft deleted: got %d %v, want 0", deleted, err)
+	}
+
+	if count, _ := repo.Count(nil); count != 1 {
+		t.Errorf("got count %d, want 1", count)
+	}
+}
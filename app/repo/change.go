@@ -0,0 +1,214 @@
+package repo
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Events buffered per subscriber, a slow subscriber drops events instead of blocking writes
+const changeBufferSize = 256
+
+// Documents remembered by a change stream for hard deletes on deployments without pre-images
+const changeKnownDocuments = 1024
+
+type changeSubscriber struct {
+	collection string
+	events     chan types.ChangeEvent
+}
+
+var (
+	changeSubscribers      = map[*changeSubscriber]bool{}
+	changeSubscribersMutex sync.RWMutex
+)
+
+// Deliver change to subscribers of its collection, see SubscribeChanges
+func PublishChange(event types.ChangeEvent) {
+	changeSubscribersMutex.RLock()
+	defer changeSubscribersMutex.RUnlock()
+
+	for subscriber := range changeSubscribers {
+		if subscriber.collection != event.Collection {
+			continue
+		}
+
+		select {
+		case subscriber.events <- event:
+		default:
+			log.Printf("Change event of %s %s dropped, subscriber is too slow", event.Collection, event.ID)
+		}
+	}
+}
+
+// Call fn with changes of collection in publish order from its own goroutine until unsubscribed
+func SubscribeChanges(collection string, fn func(types.ChangeEvent)) (unsubscribe func()) {
+	subscriber := &changeSubscriber{collection: collection, events: make(chan types.ChangeEvent, changeBufferSize)}
+
+	changeSubscribersMutex.Lock()
+	changeSubscribers[subscriber] = true
+	changeSubscribersMutex.Unlock()
+
+	go func() {
+		for event := range subscriber.events {
+			fn(event)
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			changeSubscribersMutex.Lock()
+			defer changeSubscribersMutex.Unlock()
+
+			delete(changeSubscribers, subscriber)
+			close(subscriber.events)
+		})
+	}
+}
+
+// Record as stored document of change event
+func ChangeDocument(record any) map[string]any {
+	if record == nil {
+		return nil
+	}
+
+	bytes, err := bson.Marshal(record)
+
+	if err != nil {
+		return nil
+	}
+
+	var doc bson.M
+
+	if err := bson.Unmarshal(bytes, &doc); err != nil {
+		return nil
+	}
+
+	return doc
+}
+
+type changeStreamEvent struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID bson.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument             bson.M `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.M `bson:"fullDocumentBeforeChange"` // Set when collection records pre-images
+	UpdateDescription        struct {
+		UpdatedFields bson.M   `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+// Map change stream event into change op, soft deletes and restores are updates of deletedAt
+func (r *BaseRepo[T]) changeOp(change changeStreamEvent) (types.ChangeOp, bool) {
+	switch change.OperationType {
+	case "insert":
+		return types.ChangeCreated, true
+	case "delete":
+		return types.ChangeDeleted, true
+	case "update", "replace":
+		if r.SoftDelete {
+			if deletedAt, ok := change.UpdateDescription.UpdatedFields["deletedAt"]; ok {
+				if deletedAt != nil {
+					return types.ChangeDeleted, true
+				}
+
+				return types.ChangeRestored, true
+			}
+		}
+
+		return types.ChangeUpdated, true
+	default:
+		return "", false
+	}
+}
+
+// Publish writes made to the collection by any replica or client, read from a change stream until ctx is done, fails on deployments without change streams e.g: standalone servers.
+// Hard deletes carry the pre-image when the collection records them (MongoDB 6+), otherwise the last document seen by the stream.
+func (r *BaseRepo[T]) WatchChanges(ctx context.Context) error {
+	collection := r.DB.MongoDB.Collection(r.Collection)
+	streamOptions := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	stream, err := collection.Watch(ctx, mongo.Pipeline{}, streamOptions.SetFullDocumentBeforeChange(options.WhenAvailable))
+
+	// Servers before 6.0 reject pre-image options
+	if err != nil {
+		stream, err = collection.Watch(ctx, mongo.Pipeline{}, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer stream.Close(context.Background())
+
+		known := map[bson.ObjectID]bson.M{}
+
+		for stream.Next(ctx) {
+			var change changeStreamEvent
+
+			if err := stream.Decode(&change); err != nil {
+				log.Printf("Invalid change event of %s: %v", r.Collection, err)
+				continue
+			}
+
+			op, ok := r.changeOp(change)
+
+			if !ok {
+				continue
+			}
+
+			id := change.DocumentKey.ID
+			doc := change.FullDocument
+
+			if change.OperationType == "delete" {
+				if doc = change.FullDocumentBeforeChange; doc == nil {
+					doc = known[id]
+				}
+
+				delete(known, id)
+			} else if doc != nil {
+				if _, ok := known[id]; !ok && len(known) >= changeKnownDocuments {
+					for forgotten := range known {
+						delete(known, forgotten)
+						break
+					}
+				}
+
+				known[id] = doc
+			}
+
+			event := types.ChangeEvent{Collection: r.Collection, Op: op, ID: id.Hex()}
+
+			// Decoded like repo reads so stored fields missing from T are never published
+			if doc != nil {
+				record, err := decodeDoc[T](doc)
+
+				if err != nil {
+					log.Printf("Invalid change event of %s %s: %v", r.Collection, id.Hex(), err)
+					continue
+				}
+
+				event.Record = record
+				event.Document = ChangeDocument(record)
+			}
+
+			PublishChange(event)
+		}
+
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			log.Printf("Change stream of %s stopped: %v", r.Collection, err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Repository supporting lookups by filters, soft deleted records are never matched
+type FinderRepository[T any] interface {
+	Repository[T]
+	FindOne(filters []types.QueryParamsFilter) (*T, error) // First matching record, nil when none matches
+	Exists(filters []types.QueryParamsFilter) (bool, error)
+	Count(filters []types.QueryParamsFilter) (int64, error)
+	Upsert(filters []types.QueryParamsFilter, data any) (*T, bool, error) // Update first matching record or create one from eq filters and data, reports whether it was created
+}
+
+func (r *BaseRepo[T]) FindOne(filters []types.QueryParamsFilter) (*T, error) {
+	var result T
+
+	if err := r.DB.MongoDB.Collection(r.Collection).FindOne(r.getContext(), r.visibleFilter(filters)).Decode(&result); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *BaseRepo[T]) Exists(filters []types.QueryParamsFilter) (bool, error) {
+	count, err := r.DB.MongoDB.Collection(r.Collection).CountDocuments(r.getContext(), r.visibleFilter(filters), options.Count().SetLimit(1))
+
+	return count > 0, err
+}
+
+func (r *BaseRepo[T]) Count(filters []types.QueryParamsFilter) (int64, error) {
+	return r.DB.MongoDB.Collection(r.Collection).CountDocuments(r.getContext(), r.visibleFilter(filters))
+}
+
+func (r *BaseRepo[T]) Upsert(filters []types.QueryParamsFilter, data any) (*T, bool, error) {
+	parsed, err := bindBson(data)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	delete(parsed, "_id")
+	delete(parsed, "version")
+
+	now := time.Now()
+
+	if r.UpdatedAt {
+		parsed["updatedAt"] = now
+	}
+
+	update := bson.D{}
+
+	if len(parsed) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: parsed})
+	}
+
+	if r.CreatedAt {
+		update = append(update, bson.E{Key: "$setOnInsert", Value: bson.M{"createdAt": now}})
+	}
+
+	if r.Versioned {
+		// $inc on a missing field starts it at 1, so inserted records get version 1 like Create
+		update = append(update, bson.E{Key: "$inc", Value: bson.M{"version": 1}})
+	}
+
+	if len(update) == 0 {
+		return nil, false, fmt.Errorf("upsert data is empty")
+	}
+
+	// Eq filters are copied into the inserted document by MongoDB
+	result, err := r.DB.MongoDB.Collection(r.Collection).UpdateOne(r.getContext(), r.visibleFilter(filters), update, options.UpdateOne().SetUpsert(true))
+
+	if err != nil {
+		return nil, false, mongoError(err)
+	}
+
+	if result.UpsertedID != nil {
+		created, err := r.GetByID(result.UpsertedID)
+		return created, true, err
+	}
+
+	updated, err := r.FindOne(filters)
+
+	return updated, false, err
+}
+
+func (r *SQLRepo[T]) FindOne(filters []types.QueryParamsFilter) (*T, error) {
+	where, args, err := r.buildWhere(filters, types.DeletedExclude, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.executor().QueryContext(r.getContext(), fmt.Sprintf("SELECT * FROM %s%s LIMIT 1", quoteIdentifier(r.Table), where), args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results, err := r.scanRows(rows)
+
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+
+	return &results[0], nil
+}
+
+func (r *SQLRepo[T]) Exists(filters []types.QueryParamsFilter) (bool, error) {
+	where, args, err := r.buildWhere(filters, types.DeletedExclude, nil)
+
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+
+	err = r.executor().QueryRowContext(r.getContext(), fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s%s)", quoteIdentifier(r.Table), where), args...).Scan(&exists)
+
+	return exists, err
+}
+
+func (r *SQLRepo[T]) Count(filters []types.QueryParamsFilter) (int64, error) {
+	where, args, err := r.buildWhere(filters, types.DeletedExclude, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+
+	err = r.executor().QueryRowContext(r.getContext(), fmt.Sprintf("SELECT COUNT(*) FROM %s%s", quoteIdentifier(r.Table), where), args...).Scan(&count)
+
+	return count, err
+}
+
+// Find then write, run within WithTransaction (or rely on a unique index) when concurrent upserts must not both create
+func (r *SQLRepo[T]) Upsert(filters []types.QueryParamsFilter, data any) (*T, bool, error) {
+	return upsert(r, filters, data)
+}
+
+func (r *MemoryRepo[T]) FindOne(filters []types.QueryParamsFilter) (*T, error) {
+	var found bson.M
+
+	r.store().read(r.Collection, func(docs []bson.M) {
+		for _, doc := range docs {
+			if r.visible(doc, types.DeletedExclude) && MatchFilters(doc, filters) {
+				found = cloneDoc(doc)
+				return
+			}
+		}
+	})
+
+	if found == nil {
+		return nil, nil
+	}
+
+	return decodeDoc[T](found)
+}
+
+func (r *MemoryRepo[T]) Exists(filters []types.QueryParamsFilter) (bool, error) {
+	found, err := r.FindOne(filters)
+
+	return found != nil, err
+}
+
+func (r *MemoryRepo[T]) Count(filters []types.QueryParamsFilter) (int64, error) {
+	var count int64
+
+	r.store().read(r.Collection, func(docs []bson.M) {
+		for _, doc := range docs {
+			if r.visible(doc, types.DeletedExclude) && MatchFilters(doc, filters) {
+				count++
+			}
+		}
+	})
+
+	return count, nil
+}
+
+func (r *MemoryRepo[T]) Upsert(filters []types.QueryParamsFilter, data any) (*T, bool, error) {
+	return upsert(r, filters, data)
+}
+
+// Upsert for backends without native support, created record takes eq filter values like MongoDB does
+func upsert[T any](repo FinderRepository[T], filters []types.QueryParamsFilter, data any) (*T, bool, error) {
+	existing, err := repo.FindOne(filters)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	parsed, err := bindBson(data)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	delete(parsed, "_id")
+	delete(parsed, "version")
+
+	if existing != nil {
+		current, err := bindBson(existing)
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		updated, err := repo.UpdateByID(current["_id"], parsed)
+		return updated, false, err
+	}
+
+	for _, filter := range filters {
+		if _, ok := parsed[filter.Field]; !ok && filter.Operator == types.OpEq {
+			parsed[filter.Field] = filter.Value
+		}
+	}
+
+	created, err := repo.Create(parsed)
+
+	return created, err == nil, err
+}
+
+var _ FinderRepository[any] = (*BaseRepo[any])(nil)
+var _ FinderRepository[any] = (*SQLRepo[any])(nil)
+var _ FinderRepository[any] = (*MemoryRepo[any])(nil)
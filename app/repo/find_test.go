@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+)
+
+// Soft deleted and versioned notes finder of every backend able to run without a server
+func newTestFinders(t *testing.T, notes ...testNote) map[string]FinderRepository[testNote] {
+	t.Helper()
+
+	memory, _ := newTestNotes(t, true, true, notes...)
+	sql, _ := newTestSQLNotes(t, true, true, notes...)
+
+	return map[string]FinderRepository[testNote]{"memory": memory, "sql": sql}
+}
+
+func TestFindOne(t *testing.T) {
+	for name, repo := range newTestFinders(t, testNote{Title: "a", Priority: 1}, testNote{Title: "b", Priority: 2}, testNote{Title: "trashed", Priority: 2}) {
+		t.Run(name, func(t *testing.T) {
+			trashed, err := repo.FindOne([]types.QueryParamsFilter{{Field: "title", Operator: types.OpEq, Value: "trashed"}})
+
+			if err != nil || trashed == nil {
+				t.Fatalf("find trashed: got %v %v", trashed, err)
+			}
+
+			if deleted, err := repo.DeleteByID(trashed.ID); err != nil || !deleted {
+				t.Fatalf("delete: %v %v", deleted, err)
+			}
+
+			tests := []struct {
+				name      string
+				filters   []types.QueryParamsFilter
+				wantTitle string // Empty when nothing matches
+				wantCount int64
+			}{
+				{"match", []types.QueryParamsFilter{{Field: "priority", Operator: types.OpGte, Value: 2}}, "b", 1},
+				{"no match", []types.QueryParamsFilter{{Field: "title", Operator: types.OpEq, Value: "z"}}, "", 0},
+				{"soft deleted is never matched", []types.QueryParamsFilter{{Field: "title", Operator: types.OpEq, Value: "trashed"}}, "", 0},
+				{"no filters", nil, "a", 2},
+			}
+
+			for _, test := range tests {
+				found, err := repo.FindOne(test.filters)
+
+				if err != nil {
+					t.Fatalf("%s: find: %v", test.name, err)
+				}
+
+				got := ""
+				if found != nil {
+					got = found.Title
+				}
+
+				if got != test.wantTitle {
+					t.Errorf("%s: got %q, want %q", test.name, got, test.wantTitle)
+				}
+
+				if exists, err := repo.Exists(test.filters); err != nil || exists != (test.wantTitle != "") {
+					t.Errorf("%s: exists got %v %v, want %v", test.name, exists, err, test.wantTitle != "")
+				}
+
+				if count, err := repo.Count(test.filters); err != nil || count != test.wantCount {
+					t.Errorf("%s: count got %d %v, want %d", test.name, count, err, test.wantCount)
+				}
+			}
+		})
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	for name, repo := range newTestFinders(t, testNote{Title: "a", Priority: 1}) {
+		t.Run(name, func(t *testing.T) {
+			filters := []types.QueryParamsFilter{{Field: "title", Operator: types.OpEq, Value: "b"}}
+
+			created, isCreated, err := repo.Upsert(filters, map[string]any{"priority": 2})
+
+			if err != nil || !isCreated {
+				t.Fatalf("first upsert: got created %v %v, want created", isCreated, err)
+			}
+
+			if created.Title != "b" || created.Priority != 2 || created.Version != 1 {
+				t.Errorf("created: got %+v, want title from eq filter, priority 2 at version 1", created)
+			}
+
+			updated, isCreated, err := repo.Upsert(filters, map[string]any{"priority": 3, "version": 9})
+
+			if err != nil || isCreated {
+				t.Fatalf("second upsert: got created %v %v, want updated", isCreated, err)
+			}
+
+			if updated.ID != created.ID || updated.Priority != 3 || updated.Version != 2 {
+				t.Errorf("updated: got %+v, want same record with priority 3 at version 2", updated)
+			}
+
+			if count, err := repo.Count(nil); err != nil || count != 2 {
+				t.Errorf("got count %d %v, want 2", count, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Returned when a write violates a unique index, Field is the offending field (empty when it can't be resolved)
+type ErrConflict struct {
+	Field string
+}
+
+func (e ErrConflict) Error() string {
+	if e.Field == "" {
+		return "duplicate value"
+	}
+
+	return fmt.Sprintf("duplicate value for %s", e.Field)
+}
+
+// Models can declare indexes by implementing this interface, on top of `index` struct tags
+type IndexedModel interface {
+	Indexes() []types.IndexSpec
+}
+
+// Repo able to create its declared indexes
+type IndexSyncer interface {
+	SyncIndexes() error
+}
+
+var (
+	indexSyncers      = map[string]IndexSyncer{}
+	indexSyncersMutex sync.Mutex
+)
+
+// Register repo indexes to be created by SyncAllIndexes, keyed by collection/table so registering again replaces it
+func RegisterIndexes(collection string, syncer IndexSyncer) {
+	indexSyncersMutex.Lock()
+	defer indexSyncersMutex.Unlock()
+
+	indexSyncers[collection] = syncer
+}
+
+// Create indexes of every registered repo
+func SyncAllIndexes() error {
+	indexSyncersMutex.Lock()
+	defer indexSyncersMutex.Unlock()
+
+	names := make([]string, 0, len(indexSyncers))
+	for name := range indexSyncers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := indexSyncers[name].SyncIndexes(); err != nil {
+			return fmt.Errorf("sync indexes of %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Collect indexes declared by T through Indexes() method and `index` struct tags, fails on invalid tag options.
+// Tag options: unique, text, desc, ttl=<duration>, name=<name> (fields sharing a name form a compound index)
+// e.g: `bson:"email" index:"unique"`, `bson:"userId" index:"name=user_title"`, `bson:"expiredAt" index:"ttl=24h"`
+func ModelIndexes[T any]() ([]types.IndexSpec, error) {
+	var (
+		zero    T
+		specs   []types.IndexSpec
+		grouped = map[string]int{}
+	)
+
+	if model, ok := any(zero).(IndexedModel); ok {
+		specs = append(specs, model.Indexes()...)
+	} else if model, ok := any(&zero).(IndexedModel); ok {
+		specs = append(specs, model.Indexes()...)
+	}
+
+	t := reflect.TypeOf(zero)
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return specs, nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("index")
+
+		if !ok {
+			continue
+		}
+
+		name := tagName(field.Tag.Get("bson"))
+		if name == "" {
+			name = tagName(field.Tag.Get("json"))
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		spec := types.IndexSpec{}
+		fieldName := name
+
+		for _, option := range strings.Split(tag, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+
+			switch key {
+			case "unique":
+				spec.Unique = true
+			case "text":
+				spec.Text = true
+			case "desc":
+				fieldName = "-" + name
+			case "ttl":
+				duration, err := time.ParseDuration(value)
+
+				if err != nil || duration < time.Second {
+					return nil, fmt.Errorf("invalid ttl %q of %s index, expected duration of at least 1s e.g: 24h", value, name)
+				}
+
+				spec.ExpireAfter = duration
+			case "name":
+				spec.Name = value
+			}
+		}
+
+		if position, ok := grouped[spec.Name]; ok && spec.Name != "" {
+			specs[position].Fields = append(specs[position].Fields, fieldName)
+			specs[position].Unique = specs[position].Unique || spec.Unique
+			continue
+		}
+
+		spec.Fields = []string{fieldName}
+		specs = append(specs, spec)
+
+		if spec.Name != "" {
+			grouped[spec.Name] = len(specs) - 1
+		}
+	}
+
+	return specs, nil
+}
+
+// Indexes of T followed by extra indexes, text indexes merged into one as MongoDB allows a single text index per collection
+func declaredIndexes[T any](extra []types.IndexSpec) ([]types.IndexSpec, error) {
+	specs, err := ModelIndexes[T]()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		merged []types.IndexSpec
+		text   = -1
+	)
+
+	for _, spec := range append(specs, extra...) {
+		if !spec.Text {
+			merged = append(merged, spec)
+			continue
+		}
+
+		if text < 0 {
+			spec.Fields = slices.Clone(spec.Fields)
+			merged = append(merged, spec)
+			text = len(merged) - 1
+			continue
+		}
+
+		for _, field := range spec.Fields {
+			if !slices.Contains(merged[text].Fields, field) {
+				merged[text].Fields = append(merged[text].Fields, field)
+			}
+		}
+
+		if merged[text].Name == "" {
+			merged[text].Name = spec.Name
+		}
+	}
+
+	return merged, nil
+}
+
+func indexName(spec types.IndexSpec, prefix string) string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+
+	parts := []string{prefix}
+	for _, field := range spec.Fields {
+		parts = append(parts, strings.ReplaceAll(strings.TrimPrefix(field, "-"), ".", "_"))
+	}
+
+	if spec.Text {
+		parts = append(parts, "text")
+	}
+
+	return strings.Join(parts, "_")
+}
+
+func (r *BaseRepo[T]) indexSpecs() ([]types.IndexSpec, error) {
+	return declaredIndexes[T](r.Indexes)
+}
+
+func (r *BaseRepo[T]) indexModel(spec types.IndexSpec) mongo.IndexModel {
+	keys := bson.D{}
+
+	for _, field := range spec.Fields {
+		switch {
+		case spec.Text:
+			keys = append(keys, bson.E{Key: strings.TrimPrefix(field, "-"), Value: "text"})
+		case strings.HasPrefix(field, "-"):
+			keys = append(keys, bson.E{Key: field[1:], Value: -1})
+		default:
+			keys = append(keys, bson.E{Key: field, Value: 1})
+		}
+	}
+
+	indexOptions := options.Index().SetName(indexName(spec, r.Collection))
+
+	if spec.Unique {
+		indexOptions.SetUnique(true)
+	}
+
+	if spec.ExpireAfter > 0 {
+		indexOptions.SetExpireAfterSeconds(int32(spec.ExpireAfter.Seconds()))
+	}
+
+	return mongo.IndexModel{Keys: keys, Options: indexOptions}
+}
+
+func (r *BaseRepo[T]) SyncIndexes() error {
+	specs, err := r.indexSpecs()
+
+	if err != nil || len(specs) == 0 {
+		return err
+	}
+
+	var models []mongo.IndexModel
+
+	for _, spec := range specs {
+		models = append(models, r.indexModel(spec))
+	}
+
+	_, err = r.DB.MongoDB.Collection(r.Collection).Indexes().CreateMany(r.getContext(), models)
+
+	return err
+}
+
+// Run pipeline of search, creating the text index once when missing e.g: SyncIndexes is off
+func (r *BaseRepo[T]) aggregateSearch(search string, pipeline any) (*mongo.Cursor, error) {
+	cursor, err := r.DB.MongoDB.Collection(r.Collection).Aggregate(r.getContext(), pipeline)
+
+	var serverErr mongo.ServerError
+
+	// IndexNotFound
+	if search == "" || !errors.As(err, &serverErr) || !serverErr.HasErrorCode(27) {
+		return cursor, err
+	}
+
+	if err := r.syncTextIndex(); err != nil {
+		return nil, err
+	}
+
+	return r.DB.MongoDB.Collection(r.Collection).Aggregate(r.getContext(), pipeline)
+}
+
+// Create the text index alone so searching works without SyncIndexes
+func (r *BaseRepo[T]) syncTextIndex() error {
+	specs, err := r.indexSpecs()
+
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		if spec.Text {
+			_, err := r.DB.MongoDB.Collection(r.Collection).Indexes().CreateOne(r.getContext(), r.indexModel(spec))
+
+			return err
+		}
+	}
+
+	return ErrSearchUnsupported
+}
+
+var duplicateKeyRegex = regexp.MustCompile(`dup key: \{ ?"?([^:" ]+)"?:`)
+
+// Translate mongo duplicate key error into ErrConflict, other errors are returned as is
+func mongoError(err error) error {
+	if err == nil || !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	var writeException mongo.WriteException
+	if errors.As(err, &writeException) {
+		for _, writeError := range writeException.WriteErrors {
+			if keyValue, ok := writeError.Raw.Lookup("keyValue").DocumentOK(); ok {
+				if elements, err := keyValue.Elements(); err == nil && len(elements) > 0 {
+					return ErrConflict{Field: elements[0].Key()}
+				}
+			}
+		}
+	}
+
+	if match := duplicateKeyRegex.FindStringSubmatch(err.Error()); match != nil {
+		return ErrConflict{Field: match[1]}
+	}
+
+	return ErrConflict{}
+}
+
+func (r *SQLRepo[T]) SyncIndexes() error {
+	specs, err := declaredIndexes[T](r.Indexes)
+
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		if spec.ExpireAfter > 0 {
+			// TTL indexes are not supported by SQL databases, expired rows must be purged by a scheduled task
+			continue
+		}
+
+		if spec.Text {
+			// Full-text search is not supported by SQLRepo, a btree index would not serve it
+			continue
+		}
+
+		var columns []string
+
+		for _, field := range spec.Fields {
+			direction := "ASC"
+
+			if strings.HasPrefix(field, "-") {
+				direction = "DESC"
+				field = field[1:]
+			}
+
+			column, err := r.column(field)
+
+			if err != nil {
+				return err
+			}
+
+			columns = append(columns, fmt.Sprintf("%s %s", quoteIdentifier(column.Name), direction))
+		}
+
+		unique := ""
+
+		if spec.Unique {
+			unique = "UNIQUE "
+		}
+
+		query := fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s (%s)", unique, quoteIdentifier(indexName(spec, r.Table)), quoteIdentifier(r.Table), strings.Join(columns, ", "))
+
+		if _, err := r.executor().ExecContext(r.getContext(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var (
+	sqliteUniqueRegex   = regexp.MustCompile(`UNIQUE constraint failed: [^.]+\.([^, ]+)`)
+	postgresUniqueRegex = regexp.MustCompile(`Key \(([^,)]+)`)
+)
+
+// Translate SQL unique violation into ErrConflict, other errors are returned as is
+func sqlError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code != "23505" {
+			return err
+		}
+
+		if match := postgresUniqueRegex.FindStringSubmatch(pgErr.Detail); match != nil {
+			return ErrConflict{Field: strings.Trim(match[1], `"`)}
+		}
+
+		return ErrConflict{}
+	}
+
+	if match := sqliteUniqueRegex.FindStringSubmatch(err.Error()); match != nil {
+		return ErrConflict{Field: match[1]}
+	}
+
+	return err
+}
+
+// In-memory repo has no index structures, unique indexes are enforced on every write instead
+func (r *MemoryRepo[T]) SyncIndexes() error {
+	return nil
+}
+
+// Find unique index violated by candidate document among docs, ignoring the document itself
+func (r *MemoryRepo[T]) uniqueViolation(docs []bson.M, candidate bson.M) error {
+	specs, err := declaredIndexes[T](r.Indexes)
+
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		if !spec.Unique {
+			continue
+		}
+
+		for _, doc := range docs {
+			if doc["_id"] == candidate["_id"] {
+				continue
+			}
+
+			duplicated := true
+
+			for _, field := range spec.Fields {
+				field = strings.TrimPrefix(field, "-")
+
+				if compareValues(doc[field], candidate[field]) != 0 {
+					duplicated = false
+					break
+				}
+			}
+
+			if duplicated {
+				return ErrConflict{Field: strings.TrimPrefix(spec.Fields[0], "-")}
+			}
+		}
+	}
+
+	return nil
+}
+
+var _ IndexSyncer = (*BaseRepo[any])(nil)
+var _ IndexSyncer = (*SQLRepo[any])(nil)
+var _ IndexSyncer = (*MemoryRepo[any])(nil)
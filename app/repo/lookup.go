@@ -0,0 +1,196 @@
+package repo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Repository able to eager load relations and project fields of a single record
+type LookupRepository[T any] interface {
+	Repository[T]
+	GetByIDWithOptions(id any, options types.GetByIDOptions) (*T, error)
+}
+
+// Top level fields kept by projection, _id and included relations are always kept, nil keeps every field
+func projectedFields(fields []string, includes []string) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	kept := []string{"_id"}
+
+	for _, include := range includes {
+		name, _, _ := strings.Cut(include, ".")
+		fields = append(fields, name)
+	}
+
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" && !slices.Contains(kept, field) {
+			kept = append(kept, field)
+		}
+	}
+
+	return kept
+}
+
+// Translate projected fields into $project stage, nil when every field is kept
+func projectStage(fields []string) bson.D {
+	if fields == nil {
+		return nil
+	}
+
+	projection := bson.D{}
+
+	for _, field := range fields {
+		projection = append(projection, bson.E{Key: field, Value: 1})
+	}
+
+	return bson.D{{Key: "$project", Value: projection}}
+}
+
+// Remove fields left out of projection from document in place, mirrors BaseRepo $project stage
+func projectDoc(doc bson.M, fields []string) {
+	if fields == nil {
+		return
+	}
+
+	for key := range doc {
+		if !slices.Contains(fields, key) {
+			delete(doc, key)
+		}
+	}
+}
+
+// Select list of projected fields, unknown columns are skipped like MongoDB does
+func (r *SQLRepo[T]) selectColumns(fields []string) string {
+	if fields == nil {
+		return "*"
+	}
+
+	var columns []string
+
+	for _, field := range fields {
+		if field == "_id" {
+			columns = append(columns, quoteIdentifier(field))
+		} else if column, err := r.column(field); err == nil {
+			columns = append(columns, quoteIdentifier(column.Name))
+		}
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+func (r *BaseRepo[T]) GetByIDWithOptions(id any, options types.GetByIDOptions) (*T, error) {
+	objectId, err := parseObjectID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := resolveIncludes(r.Collection, options.Includes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.D{{Key: "_id", Value: objectId}}
+
+	if condition := r.deletedCondition(options.DeletedMode); condition != nil {
+		match = append(match, *condition)
+	}
+
+	pipeline := append(mongo.Pipeline{{{Key: "$match", Value: match}}}, lookupStages(nodes)...)
+
+	if project := projectStage(projectedFields(options.Fields, options.Includes)); project != nil {
+		pipeline = append(pipeline, project)
+	}
+
+	cursor, err := r.DB.MongoDB.Collection(r.Collection).Aggregate(r.getContext(), pipeline)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer cursor.Close(r.getContext())
+
+	if !cursor.Next(r.getContext()) {
+		return nil, cursor.Err()
+	}
+
+	var result T
+
+	if err := cursor.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *SQLRepo[T]) GetByIDWithOptions(id any, options types.GetByIDOptions) (*T, error) {
+	if len(options.Includes) > 0 {
+		return nil, ErrInvalidInclude{Message: "Includes are not supported by SQL repositories."}
+	}
+
+	idString, err := sqlID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s", r.selectColumns(projectedFields(options.Fields, nil)), quoteIdentifier(r.Table), quoteIdentifier("_id"), r.placeholder(1))
+
+	if condition := r.deletedCondition(options.DeletedMode); condition != "" {
+		query = fmt.Sprintf("%s AND %s", query, condition)
+	}
+
+	rows, err := r.executor().QueryContext(r.getContext(), query, idString)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results, err := r.scanRows(rows)
+
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+
+	return &results[0], nil
+}
+
+func (r *MemoryRepo[T]) GetByIDWithOptions(id any, options types.GetByIDOptions) (*T, error) {
+	objectId, err := parseObjectID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := resolveIncludes(r.Collection, options.Includes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	doc := r.find(objectId, options.DeletedMode)
+
+	if doc == nil {
+		return nil, nil
+	}
+
+	r.includeDocs([]bson.M{doc}, nodes)
+	projectDoc(doc, projectedFields(options.Fields, options.Includes))
+
+	return decodeDoc[T](doc)
+}
+
+var _ LookupRepository[any] = (*BaseRepo[any])(nil)
+var _ LookupRepository[any] = (*SQLRepo[any])(nil)
+var _ LookupRepository[any] = (*MemoryRepo[any])(nil)
@@ -0,0 +1,719 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Returned on commit when another write landed on the store after the transaction started, safe to retry
+var ErrMemoryTxConflict = errors.New("memory transaction conflict")
+
+// In-memory document store shared by MemoryRepo instances, meant for tests and local development without a database
+type MemoryStore struct {
+	mutex       sync.RWMutex
+	collections map[string][]bson.M
+	revision    int64
+}
+
+type memoryTx struct {
+	parent   *MemoryStore
+	store    *MemoryStore // Private copy of parent, swapped in on commit
+	revision int64        // Parent revision when the transaction started
+}
+
+type memoryTxContextKey struct{}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{collections: map[string][]bson.M{}}
+}
+
+func (s *MemoryStore) clone() *MemoryStore {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	cloned := &MemoryStore{collections: map[string][]bson.M{}, revision: s.revision}
+
+	for name, docs := range s.collections {
+		copied := make([]bson.M, len(docs))
+		for i, doc := range docs {
+			copied[i] = cloneDoc(doc)
+		}
+		cloned.collections[name] = copied
+	}
+
+	return cloned
+}
+
+func cloneDoc(doc bson.M) bson.M {
+	copied := bson.M{}
+	for key, value := range doc {
+		copied[key] = value
+	}
+
+	return copied
+}
+
+// Run fn atomically, writes made through MemoryRepo with the given context are only visible after fn succeeds.
+// Conflicting concurrent writes abort the commit and fn is retried up to maxAttempts times.
+func (s *MemoryStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error, maxAttempts int) error {
+	if tx, ok := ctx.Value(memoryTxContextKey{}).(*memoryTx); ok && tx.parent == s {
+		// Already within a transaction of this store, join it
+		return fn(ctx)
+	}
+
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		tx := &memoryTx{parent: s, store: s.clone()}
+		tx.revision = tx.store.revision
+
+		if err = fn(context.WithValue(ctx, memoryTxContextKey{}, tx)); err != nil {
+			return err
+		}
+
+		if err = s.commit(tx); !errors.Is(err, ErrMemoryTxConflict) {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (s *MemoryStore) commit(tx *memoryTx) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.revision != tx.revision {
+		return ErrMemoryTxConflict
+	}
+
+	s.collections = tx.store.collections
+	s.revision++
+
+	return nil
+}
+
+func (s *MemoryStore) read(collection string, fn func(docs []bson.M)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	fn(s.collections[collection])
+}
+
+func (s *MemoryStore) write(collection string, fn func(docs []bson.M) []bson.M) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.collections[collection] = fn(s.collections[collection])
+	s.revision++
+}
+
+// In-memory implementation of Repository, documents are kept as bson so T is decoded using bson tags like BaseRepo
+type MemoryRepo[T any] struct {
+	Store      *MemoryStore
+	Collection string
+	UpdatedAt  bool
+	CreatedAt  bool
+	SoftDelete bool              // Mark deletedAt instead of removing the document, default is false
+	Versioned  bool              // Increment version field on every update for optimistic concurrency, default is false
+	Indexes    []types.IndexSpec // Indexes on top of ones declared by T, only unique indexes are enforced
+	ctx        context.Context
+}
+
+// Bind repo calls to context, calls made with a WithTransaction context join the transaction automatically
+func (r *MemoryRepo[T]) WithContext(ctx context.Context) *MemoryRepo[T] {
+	clone := *r
+	clone.ctx = ctx
+	return &clone
+}
+
+func (r *MemoryRepo[T]) store() *MemoryStore {
+	if r.ctx != nil {
+		if tx, ok := r.ctx.Value(memoryTxContextKey{}).(*memoryTx); ok && tx.parent == r.Store {
+			return tx.store
+		}
+	}
+
+	return r.Store
+}
+
+func decodeDoc[T any](doc bson.M) (*T, error) {
+	bytes, err := bson.Marshal(doc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var typed T
+
+	if err := bson.Unmarshal(bytes, &typed); err != nil {
+		return nil, err
+	}
+
+	return &typed, nil
+}
+
+func parseObjectID(id any) (bson.ObjectID, error) {
+	switch v := id.(type) {
+	case bson.ObjectID:
+		return v, nil
+	case string:
+		return bson.ObjectIDFromHex(v)
+	default:
+		return bson.ObjectID{}, fmt.Errorf("invalid id type %T", id)
+	}
+}
+
+func (r *MemoryRepo[T]) visible(doc bson.M, mode types.DeletedMode) bool {
+	if !r.SoftDelete {
+		return true
+	}
+
+	deleted := doc["deletedAt"] != nil
+
+	switch mode {
+	case types.DeletedWith:
+		return true
+	case types.DeletedOnly:
+		return deleted
+	default:
+		return !deleted
+	}
+}
+
+// Evaluate query params filters against document, mirrors BaseRepo $match translation e.g: scoping change events to subscribers
+func MatchFilters(doc bson.M, filters []types.QueryParamsFilter) bool {
+	return matchesGroup(doc, types.QueryGroup{Logic: types.LogicAnd, Filters: filters})
+}
+
+func matchesGroup(doc bson.M, group types.QueryGroup) bool {
+	conditions := 0
+
+	for _, item := range group.Filters {
+		matched, ok := matchesFilter(doc, item)
+
+		if !ok {
+			continue
+		}
+
+		conditions++
+
+		if matched == (group.Logic == types.LogicOr) {
+			return matched
+		}
+	}
+
+	for _, subGroup := range group.Groups {
+		conditions++
+
+		if matched := matchesGroup(doc, subGroup); matched == (group.Logic == types.LogicOr) {
+			return matched
+		}
+	}
+
+	// Empty group matches everything
+	return group.Logic != types.LogicOr || conditions == 0
+}
+
+// Whether document satisfies filter, ok is false for unsupported operators which are ignored
+func matchesFilter(doc bson.M, item types.QueryParamsFilter) (matched bool, ok bool) {
+	value, exists := doc[item.Field]
+	expected := filterString(item.Value)
+
+	switch item.Operator {
+	case types.OpLike:
+		return exists && strings.Contains(strings.ToLower(filterString(value)), strings.ToLower(expected)), true
+	case types.OpStart:
+		return exists && strings.HasPrefix(strings.ToLower(filterString(value)), strings.ToLower(expected)), true
+	case types.OpEnd:
+		return exists && strings.HasSuffix(strings.ToLower(filterString(value)), strings.ToLower(expected)), true
+	case types.OpGte:
+		return exists && value != nil && compareValues(value, item.Value) >= 0, true
+	case types.OpLte:
+		return exists && value != nil && compareValues(value, item.Value) <= 0, true
+	case types.OpEq:
+		return exists && filterString(value) == expected, true
+	}
+
+	return false, false
+}
+
+// Order two document values, nil sorts first like MongoDB
+func compareValues(a any, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	if dt, ok := a.(bson.DateTime); ok {
+		a = dt.Time()
+	}
+
+	if dt, ok := b.(bson.DateTime); ok {
+		b = dt.Time()
+	}
+
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := b.(time.Time); ok {
+			return at.Compare(bt)
+		}
+	}
+
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	return strings.Compare(filterString(a), filterString(b))
+}
+
+// Document value as compared by filters, ids match their hex form
+func filterString(value any) string {
+	if id, ok := value.(bson.ObjectID); ok {
+		return id.Hex()
+	}
+
+	return fmt.Sprint(value)
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func sortDocs(docs []bson.M, sortFields []types.QueryParamsSortField) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, item := range sortFields {
+			if item.Field == "" {
+				continue
+			}
+
+			if result := compareValues(docs[i][item.Field], docs[j][item.Field]); result != 0 {
+				if item.Descending {
+					return result > 0
+				}
+
+				return result < 0
+			}
+		}
+
+		return false
+	})
+}
+
+func (r *MemoryRepo[T]) find(id bson.ObjectID, mode types.DeletedMode) bson.M {
+	var found bson.M
+
+	r.store().read(r.Collection, func(docs []bson.M) {
+		for _, doc := range docs {
+			if doc["_id"] == id && r.visible(doc, mode) {
+				found = cloneDoc(doc)
+				return
+			}
+		}
+	})
+
+	return found
+}
+
+func (r *MemoryRepo[T]) Create(data any) (*T, error) {
+	parsed, parsedErr := bindBson(data)
+
+	if parsedErr != nil {
+		return nil, parsedErr
+	}
+
+	stampTimestamps(parsed, r.CreatedAt, r.UpdatedAt)
+
+	if r.Versioned {
+		parsed["version"] = 1
+	}
+
+	var typed T
+
+	if bindErr := bindData(parsed, &typed); bindErr != nil {
+		return nil, bindErr
+	}
+
+	doc, err := bindBson(typed)
+
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := doc["_id"].(bson.ObjectID)
+
+	if !ok || id.IsZero() {
+		id = bson.NewObjectID()
+		doc["_id"] = id
+	}
+
+	var conflictErr error
+
+	r.store().write(r.Collection, func(docs []bson.M) []bson.M {
+		if conflictErr = r.uniqueViolation(docs, doc); conflictErr != nil {
+			return docs
+		}
+
+		return append(docs, doc)
+	})
+
+	if conflictErr != nil {
+		return nil, conflictErr
+	}
+
+	return r.GetByID(id)
+}
+
+// Clones of documents matching filters, search and soft deleted visibility, mirrors BaseRepo $match stage
+func (r *MemoryRepo[T]) matchDocs(filtersAndSorts *types.GetAllFiltersAndSorts) ([]bson.M, error) {
+	specs, err := declaredIndexes[T](r.Indexes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	searchFields := textIndexFields(specs)
+
+	if filtersAndSorts.Search != "" && searchFields == nil {
+		return nil, ErrSearchUnsupported
+	}
+
+	var matched []bson.M
+
+	r.store().read(r.Collection, func(docs []bson.M) {
+		for _, doc := range docs {
+			if !r.visible(doc, filtersAndSorts.DeletedMode) || !matchesGroup(doc, filtersAndSorts.Conditions()) {
+				continue
+			}
+
+			if filtersAndSorts.Search == "" {
+				matched = append(matched, cloneDoc(doc))
+			} else if score := searchScore(doc, searchFields, filtersAndSorts.Search); score > 0 {
+				scored := cloneDoc(doc)
+				scored[SearchScoreField] = score
+				matched = append(matched, scored)
+			}
+		}
+	})
+
+	return matched, nil
+}
+
+func (r *MemoryRepo[T]) GetAll(paginated bool, filtersAndSorts *types.GetAllFiltersAndSorts, paginationParams *types.PaginationParams) (*types.PaginatedRecords[T], error) {
+	var (
+		page    = 1
+		perPage = 10
+		matched []bson.M
+	)
+
+	if filtersAndSorts == nil {
+		filtersAndSorts = &types.GetAllFiltersAndSorts{}
+	}
+
+	includes, err := resolveIncludes(r.Collection, filtersAndSorts.Includes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	matched, err = r.matchDocs(filtersAndSorts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sortDocs(matched, relevanceSorts(filtersAndSorts.QueryParamsSortFields, filtersAndSorts.Search))
+
+	total := 0
+
+	if paginated {
+		if paginationParams.Page != 0 {
+			page = paginationParams.Page
+		}
+
+		if paginationParams.PerPage != 0 {
+			perPage = paginationParams.PerPage
+		}
+
+		total = len(matched)
+		start := min(max((page-1)*perPage, 0), total)
+		end := min(start+perPage, total)
+		matched = matched[start:end]
+	} else if filtersAndSorts.Limit > 0 && len(matched) > filtersAndSorts.Limit {
+		matched = matched[:filtersAndSorts.Limit]
+	}
+
+	r.includeDocs(matched, includes)
+
+	for _, doc := range matched {
+		projectDoc(doc, projectedFields(filtersAndSorts.Fields, filtersAndSorts.Includes))
+	}
+
+	typedResults := []T{}
+
+	for _, doc := range matched {
+		typed, err := decodeDoc[T](doc)
+
+		if err != nil {
+			return nil, err
+		}
+
+		typedResults = append(typedResults, *typed)
+	}
+
+	return &types.PaginatedRecords[T]{
+		Records:    typedResults,
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}, nil
+}
+
+func (r *MemoryRepo[T]) GetByID(id any) (*T, error) {
+	return r.getByID(id, types.DeletedExclude)
+}
+
+func (r *MemoryRepo[T]) GetByIDWithDeleted(id any) (*T, error) {
+	return r.getByID(id, types.DeletedWith)
+}
+
+func (r *MemoryRepo[T]) getByID(id any, mode types.DeletedMode) (*T, error) {
+	objectId, err := parseObjectID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	doc := r.find(objectId, mode)
+
+	if doc == nil {
+		return nil, nil
+	}
+
+	return decodeDoc[T](doc)
+}
+
+// Apply update to matching document, returns whether a document matched
+func (r *MemoryRepo[T]) update(id bson.ObjectID, match func(doc bson.M) bool, apply func(doc bson.M)) (bool, error) {
+	var (
+		matched     bool
+		conflictErr error
+	)
+
+	r.store().write(r.Collection, func(docs []bson.M) []bson.M {
+		for i, doc := range docs {
+			if doc["_id"] == id && match(doc) {
+				updated := cloneDoc(doc)
+				apply(updated)
+
+				if conflictErr = r.uniqueViolation(docs, updated); conflictErr != nil {
+					break
+				}
+
+				docs[i] = updated
+				matched = true
+				break
+			}
+		}
+
+		return docs
+	})
+
+	return matched, conflictErr
+}
+
+func (r *MemoryRepo[T]) UpdateByID(id any, data any) (*T, error) {
+	return r.updateByID(id, nil, data)
+}
+
+func (r *MemoryRepo[T]) UpdateByIDIfVersion(id any, version int64, data any) (*T, error) {
+	return r.updateByID(id, &version, data)
+}
+
+func (r *MemoryRepo[T]) updateByID(id any, expectedVersion *int64, data any) (*T, error) {
+	objectId, err := parseObjectID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, parsedErr := bindBson(data)
+
+	if parsedErr != nil {
+		return nil, parsedErr
+	}
+
+	delete(parsed, "_id")
+
+	if r.UpdatedAt {
+		parsed["updatedAt"] = time.Now()
+	}
+
+	var version *int64
+
+	if r.Versioned {
+		if version, err = takeVersion(parsed, expectedVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	matched, err := r.update(objectId, func(doc bson.M) bool {
+		if version == nil {
+			return true
+		}
+
+		current, _ := toFloat(doc["version"])
+		return int64(current) == *version
+	}, func(doc bson.M) {
+		for key, value := range parsed {
+			doc[key] = value
+		}
+
+		if r.Versioned {
+			current, _ := toFloat(doc["version"])
+			doc["version"] = int64(current) + 1
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !matched && version != nil && r.find(objectId, types.DeletedWith) != nil {
+		return nil, ErrVersionConflict
+	}
+
+	return r.GetByIDWithDeleted(objectId)
+}
+
+func (r *MemoryRepo[T]) DeleteByID(id any) (bool, error) {
+	objectId, err := parseObjectID(id)
+
+	if err != nil {
+		return false, err
+	}
+
+	if r.SoftDelete {
+		now := time.Now()
+
+		return r.update(objectId, func(doc bson.M) bool {
+			return doc["deletedAt"] == nil
+		}, func(doc bson.M) {
+			doc["deletedAt"] = now
+
+			if r.UpdatedAt {
+				doc["updatedAt"] = now
+			}
+		})
+	}
+
+	deleted := false
+
+	r.store().write(r.Collection, func(docs []bson.M) []bson.M {
+		for i, doc := range docs {
+			if doc["_id"] == objectId {
+				deleted = true
+				return append(docs[:i:i], docs[i+1:]...)
+			}
+		}
+
+		return docs
+	})
+
+	return deleted, nil
+}
+
+func (r *MemoryRepo[T]) Restore(id any) (*T, error) {
+	objectId, err := parseObjectID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := r.update(objectId, func(doc bson.M) bool {
+		return doc["deletedAt"] != nil
+	}, func(doc bson.M) {
+		doc["deletedAt"] = nil
+
+		if r.UpdatedAt {
+			doc["updatedAt"] = time.Now()
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !restored {
+		return nil, nil
+	}
+
+	return r.GetByID(objectId)
+}
+
+func (r *MemoryRepo[T]) Purge(olderThan time.Duration) (int64, error) {
+	var (
+		purged    int64
+		threshold = time.Now().Add(-olderThan)
+	)
+
+	r.store().write(r.Collection, func(docs []bson.M) []bson.M {
+		kept := docs[:0:0]
+
+		for _, doc := range docs {
+			if doc["deletedAt"] != nil && compareValues(doc["deletedAt"], threshold) <= 0 {
+				purged++
+				continue
+			}
+
+			kept = append(kept, doc)
+		}
+
+		return kept
+	})
+
+	return purged, nil
+}
+
+var _ Repository[any] = (*MemoryRepo[any])(nil)
+var _ SoftDeleteRepository[any] = (*MemoryRepo[any])(nil)
+var _ VersionedRepository[any] = (*MemoryRepo[any])(nil)
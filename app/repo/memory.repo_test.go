@@ -0,0 +1,328 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type testNote struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Title     string        `bson:"title" json:"title"`
+	Priority  int           `bson:"priority" json:"priority"`
+	Tags      []string      `bson:"tags,omitempty" json:"tags,omitempty"`
+	Version   int64         `bson:"version,omitempty" json:"version,omitempty"`
+	DeletedAt *time.Time    `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+func newTestNotes(t *testing.T, softDelete bool, versioned bool, notes ...testNote) (*MemoryRepo[testNote], []*testNote) {
+	t.Helper()
+
+	repo := &MemoryRepo[testNote]{Store: NewMemoryStore(), Collection: "notes", SoftDelete: softDelete, Versioned: versioned}
+
+	var created []*testNote
+
+	for _, note := range notes {
+		record, err := repo.Create(note)
+
+		if err != nil {
+			t.Fatalf("create %s: %v", note.Title, err)
+		}
+
+		created = append(created, record)
+	}
+
+	return repo, created
+}
+
+func noteTitles(notes []testNote) []string {
+	titles := []string{}
+
+	for _, note := range notes {
+		titles = append(titles, note.Title)
+	}
+
+	return titles
+}
+
+func TestMemoryRepoQuery(t *testing.T) {
+	repo, _ := newTestNotes(t, false, false,
+		testNote{Title: "Groceries", Priority: 1},
+		testNote{Title: "Gym", Priority: 3},
+		testNote{Title: "Taxes", Priority: 5},
+		testNote{Title: "Garden", Priority: 2},
+	)
+
+	tests := []struct {
+		name  string
+		query *QueryBuilder[testNote]
+		want  []string
+	}{
+		{"no conditions", Query[testNote]().Sort("title"), []string{"Garden", "Groceries", "Gym", "Taxes"}},
+		{"eq", Query[testNote]().Where("title", Eq, "Gym"), []string{"Gym"}},
+		{"like is case insensitive", Query[testNote]().Where("title", Like, "AR").Sort("title"), []string{"Garden"}},
+		{"start", Query[testNote]().Where("title", Start, "g").Sort("title"), []string{"Garden", "Groceries", "Gym"}},
+		{"end", Query[testNote]().Where("title", End, "es").Sort("title"), []string{"Groceries", "Taxes"}},
+		{"range", Query[testNote]().Where("priority", Gte, 2).Where("priority", Lte, 3).Sort("priority"), []string{"Garden", "Gym"}},
+		{"or group", Query[testNote]().Or(Where("title", Eq, "Taxes"), Where("priority", Lte, 1)).Sort("-priority"), []string{"Taxes", "Groceries"}},
+		{"nested and within or", Query[testNote]().Or(And(Where("title", Start, "G"), Where("priority", Gte, 3)), Where("title", Eq, "Taxes")).Sort("title"), []string{"Gym", "Taxes"}},
+		{"descending sort and limit", Query[testNote]().Sort("-priority").Limit(2), []string{"Taxes", "Gym"}},
+		{"no match", Query[testNote]().Where("title", Eq, "Nothing"), []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notes, err := test.query.All(repo)
+
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+
+			if got := noteTitles(notes); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMemoryRepoPagination(t *testing.T) {
+	repo, _ := newTestNotes(t, false, false,
+		testNote{Title: "a", Priority: 1},
+		testNote{Title: "b", Priority: 2},
+		testNote{Title: "c", Priority: 3},
+	)
+
+	filters := &types.GetAllFiltersAndSorts{QueryParamsSortFields: []types.QueryParamsSortField{{Field: "priority"}}}
+
+	tests := []struct {
+		page    int
+		perPage int
+		want    []string
+	}{
+		{1, 2, []string{"a", "b"}},
+		{2, 2, []string{"c"}},
+		{3, 2, []string{}},
+	}
+
+	for _, test := range tests {
+		results, err := repo.GetAll(true, filters, &types.PaginationParams{Page: test.page, PerPage: test.perPage})
+
+		if err != nil {
+			t.Fatalf("page %d: %v", test.page, err)
+		}
+
+		if got := noteTitles(results.Records); !slices.Equal(got, test.want) {
+			t.Errorf("page %d: got %v, want %v", test.page, got, test.want)
+		}
+
+		if results.Total != 3 {
+			t.Errorf("page %d: got total %d, want 3", test.page, results.Total)
+		}
+	}
+}
+
+func TestMatchFilters(t *testing.T) {
+	owner := bson.NewObjectID()
+	doc := bson.M{"ownerId": owner, "status": "open", "amount": int32(10)}
+
+	tests := []struct {
+		name    string
+		filters []types.QueryParamsFilter
+		want    bool
+	}{
+		{"no filters", nil, true},
+		{"object id by hex", []types.QueryParamsFilter{{Field: "ownerId", Operator: Eq, Value: owner.Hex()}}, true},
+		{"object id by value", []types.QueryParamsFilter{{Field: "ownerId", Operator: Eq, Value: owner}}, true},
+		{"other owner", []types.QueryParamsFilter{{Field: "ownerId", Operator: Eq, Value: bson.NewObjectID().Hex()}}, false},
+		{"every filter must match", []types.QueryParamsFilter{{Field: "status", Operator: Eq, Value: "open"}, {Field: "amount", Operator: Gte, Value: 11}}, false},
+		{"missing field", []types.QueryParamsFilter{{Field: "title", Operator: Like, Value: "x"}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := MatchFilters(doc, test.filters); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMemoryRepoSoftDelete(t *testing.T) {
+	repo, notes := newTestNotes(t, true, false, testNote{Title: "kept"}, testNote{Title: "trashed"})
+	trashed := notes[1]
+
+	if deleted, err := repo.DeleteByID(trashed.ID); err != nil || !deleted {
+		t.Fatalf("delete: %v %v", deleted, err)
+	}
+
+	if deleted, err := repo.DeleteByID(trashed.ID); err != nil || deleted {
+		t.Errorf("second delete: got %v %v, want false", deleted, err)
+	}
+
+	tests := []struct {
+		mode types.DeletedMode
+		want []string
+	}{
+		{types.DeletedExclude, []string{"kept"}},
+		{types.DeletedWith, []string{"kept", "trashed"}},
+		{types.DeletedOnly, []string{"trashed"}},
+	}
+
+	for _, test := range tests {
+		notes, err := Query[testNote]().Deleted(test.mode).Sort("title").All(repo)
+
+		if err != nil {
+			t.Fatalf("mode %q: %v", test.mode, err)
+		}
+
+		if got := noteTitles(notes); !slices.Equal(got, test.want) {
+			t.Errorf("mode %q: got %v, want %v", test.mode, got, test.want)
+		}
+	}
+
+	if record, err := repo.GetByID(trashed.ID); err != nil || record != nil {
+		t.Errorf("get deleted: got %v %v, want nil", record, err)
+	}
+
+	if record, err := repo.GetByIDWithDeleted(trashed.ID); err != nil || record == nil || record.DeletedAt == nil {
+		t.Errorf("get with deleted: got %v %v, want deleted record", record, err)
+	}
+
+	restored, err := repo.Restore(trashed.ID)
+
+	if err != nil || restored == nil || restored.DeletedAt != nil {
+		t.Fatalf("restore: got %v %v, want restored record", restored, err)
+	}
+
+	if restored, err := repo.Restore(trashed.ID); err != nil || restored != nil {
+		t.Errorf("restore of visible record: got %v %v, want nil", restored, err)
+	}
+
+	if count, err := repo.Count(nil); err != nil || count != 2 {
+		t.Errorf("count after restore: got %d %v, want 2", count, err)
+	}
+}
+
+func TestMemoryRepoPurge(t *testing.T) {
+	repo, notes := newTestNotes(t, true, false, testNote{Title: "a"}, testNote{Title: "b"})
+
+	if _, err := repo.DeleteByID(notes[0].ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if purged, err := repo.Purge(time.Hour); err != nil || purged != 0 {
+		t.Errorf("purge of recent deletes: got %d %v, want 0", purged, err)
+	}
+
+	if purged, err := repo.Purge(0); err != nil || purged != 1 {
+		t.Errorf("purge: got %d %v, want 1", purged, err)
+	}
+
+	if count, err := repo.Count(nil); err != nil || count != 1 {
+		t.Errorf("count after purge: got %d %v, want 1", count, err)
+	}
+}
+
+func TestMemoryRepoVersionConflict(t *testing.T) {
+	tests := []struct {
+		name        string
+		update      func(repo *MemoryRepo[testNote], id bson.ObjectID) (*testNote, error)
+		wantErr     error
+		wantVersion int64
+	}{
+		{"without version", func(repo *MemoryRepo[testNote], id bson.ObjectID) (*testNote, error) {
+			return repo.UpdateByID(id, map[string]any{"title": "new"})
+		}, nil, 2},
+		{"current version in data", func(repo *MemoryRepo[testNote], id bson.ObjectID) (*testNote, error) {
+			return repo.UpdateByID(id, map[string]any{"title": "new", "version": 1})
+		}, nil, 2},
+		{"stale version in data", func(repo *MemoryRepo[testNote], id bson.ObjectID) (*testNote, error) {
+			return repo.UpdateByID(id, map[string]any{"title": "new", "version": 7})
+		}, ErrVersionConflict, 1},
+		{"current expected version", func(repo *MemoryRepo[testNote], id bson.ObjectID) (*testNote, error) {
+			return repo.UpdateByIDIfVersion(id, 1, map[string]any{"title": "new"})
+		}, nil, 2},
+		{"stale expected version", func(repo *MemoryRepo[testNote], id bson.ObjectID) (*testNote, error) {
+			return repo.UpdateByIDIfVersion(id, 0, map[string]any{"title": "new"})
+		}, ErrVersionConflict, 1},
+		{"stale patch", func(repo *MemoryRepo[testNote], id bson.ObjectID) (*testNote, error) {
+			version := int64(3)
+			return repo.PatchByID(id, &version, types.PatchUpdate{Set: map[string]any{"title": "new"}})
+		}, ErrVersionConflict, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo, notes := newTestNotes(t, false, true, testNote{Title: "old"})
+
+			if notes[0].Version != 1 {
+				t.Fatalf("created version: got %d, want 1", notes[0].Version)
+			}
+
+			if _, err := test.update(repo, notes[0].ID); !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+
+			stored, err := repo.GetByID(notes[0].ID)
+
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+
+			if stored.Version != test.wantVersion {
+				t.Errorf("got version %d, want %d", stored.Version, test.wantVersion)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTransaction(t *testing.T) {
+	errRollback := errors.New("rollback")
+
+	tests := []struct {
+		name    string
+		fnErr   error
+		wantErr error
+		want    int64
+	}{
+		{"commit", nil, nil, 2},
+		{"rollback", errRollback, errRollback, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo, _ := newTestNotes(t, false, false)
+
+			err := repo.Store.WithTransaction(context.Background(), func(ctx context.Context) error {
+				txRepo := repo.WithContext(ctx)
+
+				for _, title := range []string{"a", "b"} {
+					if _, err := txRepo.Create(testNote{Title: title}); err != nil {
+						return err
+					}
+				}
+
+				// Writes of the transaction are not visible outside of it until committed
+				if count, _ := repo.Count(nil); count != 0 {
+					t.Errorf("count outside transaction: got %d, want 0", count)
+				}
+
+				return test.fnErr
+			}, 1)
+
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+
+			if count, _ := repo.Count(nil); count != test.want {
+				t.Errorf("got count %d, want %d", count, test.want)
+			}
+		})
+	}
+}
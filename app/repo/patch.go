@@ -0,0 +1,1064 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Returned when a JSON Patch "test" operation does not match the current document
+var ErrPatchTestFailed = errors.New("patch test operation failed")
+
+// Returned when a patch can not be applied to the current document
+type ErrInvalidPatch struct {
+	Message string
+}
+
+func (e ErrInvalidPatch) Error() string {
+	return e.Message
+}
+
+// Fields managed by the repo which patches are not allowed to touch
+var protectedPatchFields = []string{"_id", "version", "createdAt", "updatedAt", "deletedAt"}
+
+// RFC 6902 JSON Patch operation
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Repository able to apply field level patches, array changes are applied without rewriting the whole document
+type PatchRepository[T any] interface {
+	Repository[T]
+	PatchByID(id any, expectedVersion *int64, patch types.PatchUpdate) (*T, error) // Nil expected version skips the version check
+}
+
+type patchChange struct {
+	kind     string // set, unset, push or pull
+	path     string
+	values   []any
+	position *int
+}
+
+// Resulting document and its changes, changes on overlapping paths are collapsed into a $set of the top level field
+type patchBuilder struct {
+	doc     map[string]any
+	changes []patchChange
+}
+
+// Apply RFC 7396 merge patch onto document (JSON form of the current record), null removes the field
+func MergePatch(doc map[string]any, patch map[string]any) (map[string]any, types.PatchUpdate, error) {
+	builder := &patchBuilder{doc: deepCopy(doc).(map[string]any)}
+
+	if err := builder.merge(builder.doc, patch, ""); err != nil {
+		return nil, types.PatchUpdate{}, err
+	}
+
+	update, err := builder.build()
+
+	return builder.doc, update, err
+}
+
+func (b *patchBuilder) merge(target map[string]any, patch map[string]any, prefix string) error {
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := patch[key]
+		path := joinPath(prefix, key)
+
+		if strings.Contains(key, ".") {
+			return ErrInvalidPatch{Message: fmt.Sprintf("field name %q must not contain dot", key)}
+		}
+
+		if err := checkPatchPath(path); err != nil {
+			return err
+		}
+
+		if value == nil {
+			delete(target, key)
+			b.changes = append(b.changes, patchChange{kind: "unset", path: path})
+			continue
+		}
+
+		if patchObject, ok := value.(map[string]any); ok {
+			if targetObject, ok := target[key].(map[string]any); ok {
+				if err := b.merge(targetObject, patchObject, path); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			value = withoutNulls(patchObject)
+		}
+
+		target[key] = value
+		b.changes = append(b.changes, patchChange{kind: "set", path: path})
+	}
+
+	return nil
+}
+
+// Apply RFC 6902 JSON Patch operations onto document (JSON form of the current record)
+func JSONPatch(doc map[string]any, operations []PatchOperation) (map[string]any, types.PatchUpdate, error) {
+	builder := &patchBuilder{doc: deepCopy(doc).(map[string]any)}
+
+	for i, operation := range operations {
+		if err := builder.apply(operation); err != nil {
+			if errors.Is(err, ErrPatchTestFailed) {
+				return nil, types.PatchUpdate{}, err
+			}
+
+			return nil, types.PatchUpdate{}, ErrInvalidPatch{Message: fmt.Sprintf("operation %d (%s %s): %v", i, operation.Op, operation.Path, err)}
+		}
+	}
+
+	update, err := builder.build()
+
+	return builder.doc, update, err
+}
+
+func (b *patchBuilder) apply(operation PatchOperation) error {
+	tokens, err := pointerTokens(operation.Path)
+
+	if err != nil {
+		return err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if len(operation.Value) == 0 {
+			return fmt.Errorf("value is required")
+		}
+
+		value, err := decodePatchValue(operation.Value)
+
+		if err != nil {
+			return err
+		}
+
+		switch operation.Op {
+		case "add":
+			return b.add(tokens, value)
+		case "replace":
+			return b.replace(tokens, value)
+		default:
+			current, err := valueAt(b.doc, tokens)
+
+			if err != nil {
+				return err
+			}
+
+			if !jsonEqual(current, value) {
+				return fmt.Errorf("%w at %s", ErrPatchTestFailed, operation.Path)
+			}
+
+			return nil
+		}
+	case "remove":
+		_, err := b.remove(tokens)
+		return err
+	case "move", "copy":
+		fromTokens, err := pointerTokens(operation.From)
+
+		if err != nil {
+			return err
+		}
+
+		var value any
+
+		if operation.Op == "move" {
+			if strings.HasPrefix(operation.Path+"/", operation.From+"/") && operation.Path != operation.From {
+				return fmt.Errorf("can not move into own child")
+			}
+
+			value, err = b.remove(fromTokens)
+		} else {
+			value, err = valueAt(b.doc, fromTokens)
+			value = deepCopy(value)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return b.add(tokens, value)
+	default:
+		return fmt.Errorf("unsupported op %q", operation.Op)
+	}
+}
+
+func (b *patchBuilder) add(tokens []string, value any) error {
+	if err := checkPatchPath(strings.Join(tokens, ".")); err != nil {
+		return err
+	}
+
+	parentPath := strings.Join(tokens[:len(tokens)-1], ".")
+
+	updated, err := patchNode(b.doc, tokens, func(parent any, key string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			p[key] = value
+			b.changes = append(b.changes, patchChange{kind: "set", path: joinPath(parentPath, key)})
+			return p, nil
+		case []any:
+			if key == "-" {
+				b.changes = append(b.changes, patchChange{kind: "push", path: parentPath, values: []any{value}})
+				return append(p, value), nil
+			}
+
+			index, err := arrayIndex(key, len(p)+1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			b.changes = append(b.changes, patchChange{kind: "push", path: parentPath, values: []any{value}, position: &index})
+
+			return append(p[:index:index], append([]any{value}, p[index:]...)...), nil
+		default:
+			return nil, fmt.Errorf("parent of %s is not a container", key)
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	b.doc = updated.(map[string]any)
+
+	return nil
+}
+
+func (b *patchBuilder) replace(tokens []string, value any) error {
+	if err := checkPatchPath(strings.Join(tokens, ".")); err != nil {
+		return err
+	}
+
+	updated, err := patchNode(b.doc, tokens, func(parent any, key string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			if _, ok := p[key]; !ok {
+				return nil, fmt.Errorf("path does not exist")
+			}
+
+			p[key] = value
+			return p, nil
+		case []any:
+			index, err := arrayIndex(key, len(p))
+
+			if err != nil {
+				return nil, err
+			}
+
+			p[index] = value
+			return p, nil
+		default:
+			return nil, fmt.Errorf("parent of %s is not a container", key)
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+
+	b.doc = updated.(map[string]any)
+	b.changes = append(b.changes, patchChange{kind: "set", path: strings.Join(tokens, ".")})
+
+	return nil
+}
+
+func (b *patchBuilder) remove(tokens []string) (any, error) {
+	if err := checkPatchPath(strings.Join(tokens, ".")); err != nil {
+		return nil, err
+	}
+
+	parentPath := strings.Join(tokens[:len(tokens)-1], ".")
+
+	var removed any
+
+	updated, err := patchNode(b.doc, tokens, func(parent any, key string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			value, ok := p[key]
+
+			if !ok {
+				return nil, fmt.Errorf("path does not exist")
+			}
+
+			removed = value
+			delete(p, key)
+			b.changes = append(b.changes, patchChange{kind: "unset", path: joinPath(parentPath, key)})
+
+			return p, nil
+		case []any:
+			index, err := arrayIndex(key, len(p))
+
+			if err != nil {
+				return nil, err
+			}
+
+			removed = p[index]
+
+			// $pull removes every equal element, duplicated values fall back to setting the whole array
+			duplicates := 0
+			for _, item := range p {
+				if jsonEqual(item, removed) {
+					duplicates++
+				}
+			}
+
+			if duplicates == 1 {
+				b.changes = append(b.changes, patchChange{kind: "pull", path: parentPath, values: []any{removed}})
+			} else {
+				b.changes = append(b.changes, patchChange{kind: "set", path: parentPath})
+			}
+
+			return append(p[:index:index], p[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("parent of %s is not a container", key)
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	b.doc = updated.(map[string]any)
+
+	return removed, nil
+}
+
+// Translate recorded changes into update, set values are taken from the resulting document
+func (b *patchBuilder) build() (types.PatchUpdate, error) {
+	update := types.PatchUpdate{
+		Set:  map[string]any{},
+		Push: map[string]types.PatchPush{},
+		Pull: map[string][]any{},
+	}
+
+	collapsed := map[string]bool{}
+
+	for i, change := range b.changes {
+		for _, other := range b.changes[i+1:] {
+			if pathsOverlap(change.path, other.path) {
+				collapsed[rootOf(change.path)] = true
+			}
+		}
+	}
+
+	for root := range collapsed {
+		if value, ok := b.doc[root]; ok {
+			update.Set[root] = value
+		} else {
+			update.Unset = append(update.Unset, root)
+		}
+	}
+
+	for _, change := range b.changes {
+		if collapsed[rootOf(change.path)] {
+			continue
+		}
+
+		switch change.kind {
+		case "set":
+			value, err := valueAt(b.doc, strings.Split(change.path, "."))
+
+			if err != nil {
+				return types.PatchUpdate{}, ErrInvalidPatch{Message: err.Error()}
+			}
+
+			update.Set[change.path] = value
+		case "unset":
+			update.Unset = append(update.Unset, change.path)
+		case "push":
+			update.Push[change.path] = types.PatchPush{Values: change.values, Position: change.position}
+		case "pull":
+			update.Pull[change.path] = change.values
+		}
+	}
+
+	sort.Strings(update.Unset)
+
+	return update, nil
+}
+
+// Apply update onto document in place, used by backends without native field level updates
+func applyPatchUpdate(doc map[string]any, patch types.PatchUpdate) error {
+	for path, value := range patch.Set {
+		if err := setPath(doc, strings.Split(path, "."), value); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range patch.Unset {
+		tokens := strings.Split(path, ".")
+
+		if parent, ok := lookupPath(doc, tokens[:len(tokens)-1]).(map[string]any); ok {
+			delete(parent, tokens[len(tokens)-1])
+		}
+	}
+
+	for path, push := range patch.Push {
+		current, _ := lookupPath(doc, strings.Split(path, ".")).([]any)
+
+		position := len(current)
+		if push.Position != nil && *push.Position < position {
+			position = *push.Position
+		}
+
+		updated := append(append(append([]any{}, current[:position]...), push.Values...), current[position:]...)
+
+		if err := setPath(doc, strings.Split(path, "."), updated); err != nil {
+			return err
+		}
+	}
+
+	for path, values := range patch.Pull {
+		current, _ := lookupPath(doc, strings.Split(path, ".")).([]any)
+
+		kept := []any{}
+
+		for _, item := range current {
+			pulled := false
+
+			for _, value := range values {
+				if jsonEqual(item, value) {
+					pulled = true
+					break
+				}
+			}
+
+			if !pulled {
+				kept = append(kept, item)
+			}
+		}
+
+		if err := setPath(doc, strings.Split(path, "."), kept); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Top level fields touched by update with their patched values, nil for removed fields
+func patchedFields(current any, patch types.PatchUpdate) (map[string]any, error) {
+	var doc map[string]any
+
+	if err := bindData(current, &doc); err != nil {
+		return nil, err
+	}
+
+	if err := applyPatchUpdate(doc, patch); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{}
+
+	var paths []string
+
+	for path := range patch.Set {
+		paths = append(paths, path)
+	}
+
+	for path := range patch.Push {
+		paths = append(paths, path)
+	}
+
+	for path := range patch.Pull {
+		paths = append(paths, path)
+	}
+
+	for _, path := range append(paths, patch.Unset...) {
+		fields[rootOf(path)] = doc[rootOf(path)]
+	}
+
+	return fields, nil
+}
+
+func (r *BaseRepo[T]) PatchByID(id any, expectedVersion *int64, patch types.PatchUpdate) (*T, error) {
+	if err := checkPatchUpdate(patch); err != nil {
+		return nil, err
+	}
+
+	patch, err := typedPatchUpdate[T](patch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	objectId, err := parseObjectID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{}
+	for path, value := range patch.Set {
+		set[path] = value
+	}
+
+	if r.UpdatedAt {
+		set["updatedAt"] = time.Now()
+	}
+
+	filter := bson.D{{Key: "_id", Value: objectId}}
+	update := bson.D{{Key: "$set", Value: set}}
+
+	if len(patch.Unset) > 0 {
+		unset := bson.M{}
+		for _, path := range patch.Unset {
+			unset[path] = ""
+		}
+
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+
+	if len(patch.Push) > 0 {
+		push := bson.M{}
+		for path, item := range patch.Push {
+			each := bson.M{"$each": item.Values}
+
+			if item.Position != nil {
+				each["$position"] = *item.Position
+			}
+
+			push[path] = each
+		}
+
+		update = append(update, bson.E{Key: "$push", Value: push})
+	}
+
+	if len(patch.Pull) > 0 {
+		pull := bson.M{}
+		for path, values := range patch.Pull {
+			pull[path] = bson.M{"$in": values}
+		}
+
+		update = append(update, bson.E{Key: "$pull", Value: pull})
+	}
+
+	if r.Versioned {
+		if expectedVersion != nil {
+			filter = append(filter, bson.E{Key: "version", Value: *expectedVersion})
+		}
+
+		update = append(update, bson.E{Key: "$inc", Value: bson.M{"version": 1}})
+	}
+
+	result, err := r.DB.MongoDB.Collection(r.Collection).UpdateOne(r.getContext(), filter, update)
+
+	if err != nil {
+		return nil, mongoError(err)
+	}
+
+	if result.MatchedCount == 0 && len(filter) > 1 {
+		if existing, err := r.GetByIDWithDeleted(objectId); err != nil {
+			return nil, err
+		} else if existing != nil {
+			return nil, ErrVersionConflict
+		}
+	}
+
+	return r.GetByIDWithDeleted(objectId)
+}
+
+func (r *SQLRepo[T]) PatchByID(id any, expectedVersion *int64, patch types.PatchUpdate) (*T, error) {
+	if err := checkPatchUpdate(patch); err != nil {
+		return nil, err
+	}
+
+	patch, err := typedPatchUpdate[T](patch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.GetByIDWithDeleted(id)
+
+	if err != nil || current == nil {
+		return nil, err
+	}
+
+	// Columns are rewritten as a whole, nested paths land inside json columns
+	fields, err := patchedFields(current, patch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.updateByID(id, expectedVersion, fields)
+}
+
+func (r *MemoryRepo[T]) PatchByID(id any, expectedVersion *int64, patch types.PatchUpdate) (*T, error) {
+	if err := checkPatchUpdate(patch); err != nil {
+		return nil, err
+	}
+
+	patch, err := typedPatchUpdate[T](patch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.GetByIDWithDeleted(id)
+
+	if err != nil || current == nil {
+		return nil, err
+	}
+
+	fields, err := patchedFields(current, patch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.updateByID(id, expectedVersion, fields)
+}
+
+func pointerTokens(pointer string) ([]string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		if strings.Contains(tokens[i], ".") {
+			return nil, fmt.Errorf("field name %q must not contain dot", tokens[i])
+		}
+	}
+
+	return tokens, nil
+}
+
+// Reject paths not addressable as dotted field paths or touching repo managed fields
+func checkPatchPath(path string) error {
+	for _, protected := range protectedPatchFields {
+		if rootOf(path) == protected {
+			return ErrInvalidPatch{Message: fmt.Sprintf("%s can not be patched", protected)}
+		}
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" || strings.HasPrefix(segment, "$") {
+			return ErrInvalidPatch{Message: fmt.Sprintf("invalid field path %q", path)}
+		}
+	}
+
+	return nil
+}
+
+// Reject updates built by callers touching repo managed fields, e.g: a version set would collide with its increment
+func checkPatchUpdate(patch types.PatchUpdate) error {
+	paths := slices.Collect(maps.Keys(patch.Set))
+	paths = append(paths, slices.Collect(maps.Keys(patch.Push))...)
+	paths = append(paths, slices.Collect(maps.Keys(patch.Pull))...)
+	paths = append(paths, patch.Unset...)
+
+	for _, path := range paths {
+		if err := checkPatchPath(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Convert values of update into Go types of the matching fields of T, e.g: time.Time or bson.ObjectID instead of their JSON strings
+func typedPatchUpdate[T any](patch types.PatchUpdate) (types.PatchUpdate, error) {
+	var zero T
+
+	model := reflect.TypeOf(zero)
+	typed := types.PatchUpdate{Set: map[string]any{}, Unset: patch.Unset, Push: map[string]types.PatchPush{}, Pull: map[string][]any{}}
+
+	for path, value := range patch.Set {
+		converted, err := typedPatchValue(path, pathType(model, path), value)
+
+		if err != nil {
+			return types.PatchUpdate{}, err
+		}
+
+		typed.Set[path] = converted
+	}
+
+	for path, push := range patch.Push {
+		values, err := typedPatchValues(path, pathType(model, path+".0"), push.Values)
+
+		if err != nil {
+			return types.PatchUpdate{}, err
+		}
+
+		typed.Push[path] = types.PatchPush{Values: values, Position: push.Position}
+	}
+
+	for path, values := range patch.Pull {
+		converted, err := typedPatchValues(path, pathType(model, path+".0"), values)
+
+		if err != nil {
+			return types.PatchUpdate{}, err
+		}
+
+		typed.Pull[path] = converted
+	}
+
+	return typed, nil
+}
+
+func typedPatchValues(path string, valueType reflect.Type, values []any) ([]any, error) {
+	converted := make([]any, len(values))
+
+	for i, value := range values {
+		typed, err := typedPatchValue(path, valueType, value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		converted[i] = typed
+	}
+
+	return converted, nil
+}
+
+// Decode JSON form of value into value type, value is kept as is when its type is unknown e.g: fields of map[string]any
+func typedPatchValue(path string, valueType reflect.Type, value any) (any, error) {
+	if valueType == nil || valueType.Kind() == reflect.Interface || value == nil {
+		return value, nil
+	}
+
+	bytes, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	typed := reflect.New(valueType)
+
+	if err := json.Unmarshal(bytes, typed.Interface()); err != nil {
+		return nil, ErrInvalidPatch{Message: fmt.Sprintf("invalid value of %s: %v", path, err)}
+	}
+
+	return typed.Elem().Interface(), nil
+}
+
+// Type at dotted json path within model type, nil when the path can not be resolved
+func pathType(model reflect.Type, path string) reflect.Type {
+	current := model
+
+	for _, segment := range strings.Split(path, ".") {
+		for current != nil && current.Kind() == reflect.Pointer {
+			current = current.Elem()
+		}
+
+		if current == nil {
+			return nil
+		}
+
+		switch current.Kind() {
+		case reflect.Struct:
+			current = jsonFieldType(current, segment)
+		case reflect.Slice, reflect.Array:
+			if _, err := strconv.Atoi(segment); err != nil {
+				return nil
+			}
+
+			current = current.Elem()
+		case reflect.Map:
+			if current.Key().Kind() != reflect.String {
+				return nil
+			}
+
+			current = current.Elem()
+		default:
+			return nil
+		}
+	}
+
+	return current
+}
+
+// Type of struct field named by json tag, fields of embedded structs are promoted like encoding/json does
+func jsonFieldType(structType reflect.Type, name string) reflect.Type {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && tag == "" && fieldType.Kind() == reflect.Struct {
+			if found := jsonFieldType(fieldType, name); found != nil {
+				return found
+			}
+
+			continue
+		}
+
+		if tag == "" {
+			tag = field.Name
+		}
+
+		if tag == name {
+			return field.Type
+		}
+	}
+
+	return nil
+}
+
+// Walk to the parent of the last token and let apply update it, containers on the way are reassigned
+func patchNode(node any, tokens []string, apply func(parent any, key string) (any, error)) (any, error) {
+	if len(tokens) == 1 {
+		return apply(node, tokens[0])
+	}
+
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[tokens[0]]
+
+		if !ok {
+			return nil, fmt.Errorf("path %s does not exist", tokens[0])
+		}
+
+		updated, err := patchNode(child, tokens[1:], apply)
+
+		if err != nil {
+			return nil, err
+		}
+
+		n[tokens[0]] = updated
+
+		return n, nil
+	case []any:
+		index, err := arrayIndex(tokens[0], len(n))
+
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := patchNode(n[index], tokens[1:], apply)
+
+		if err != nil {
+			return nil, err
+		}
+
+		n[index] = updated
+
+		return n, nil
+	default:
+		return nil, fmt.Errorf("path %s is not a container", tokens[0])
+	}
+}
+
+func valueAt(node any, tokens []string) (any, error) {
+	for _, token := range tokens {
+		switch n := node.(type) {
+		case map[string]any:
+			value, ok := n[token]
+
+			if !ok {
+				return nil, fmt.Errorf("path %s does not exist", token)
+			}
+
+			node = value
+		case []any:
+			index, err := arrayIndex(token, len(n))
+
+			if err != nil {
+				return nil, err
+			}
+
+			node = n[index]
+		default:
+			return nil, fmt.Errorf("path %s does not exist", token)
+		}
+	}
+
+	return node, nil
+}
+
+func lookupPath(node any, tokens []string) any {
+	value, _ := valueAt(node, tokens)
+	return value
+}
+
+// Set value at dotted path tokens, missing objects on the way are created like MongoDB $set does
+func setPath(node map[string]any, tokens []string, value any) error {
+	if len(tokens) == 1 {
+		node[tokens[0]] = value
+		return nil
+	}
+
+	child, ok := node[tokens[0]]
+
+	if !ok || child == nil {
+		child = map[string]any{}
+		node[tokens[0]] = child
+	}
+
+	switch c := child.(type) {
+	case map[string]any:
+		return setPath(c, tokens[1:], value)
+	case []any:
+		index, err := arrayIndex(tokens[1], len(c))
+
+		if err != nil {
+			return err
+		}
+
+		if len(tokens) == 2 {
+			c[index] = value
+			return nil
+		}
+
+		nested, ok := c[index].(map[string]any)
+
+		if !ok {
+			return fmt.Errorf("path %s is not an object", strings.Join(tokens[:2], "."))
+		}
+
+		return setPath(nested, tokens[2:], value)
+	default:
+		return fmt.Errorf("path %s is not a container", tokens[0])
+	}
+}
+
+func arrayIndex(token string, length int) (int, error) {
+	index, err := strconv.Atoi(token)
+
+	if err != nil || index < 0 || index >= length || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	return index, nil
+}
+
+func decodePatchValue(raw json.RawMessage) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value any
+
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return NormalizeJSONNumbers(value), nil
+}
+
+// Convert json.Number values decoded with UseNumber into int64 when integral, float64 otherwise
+func NormalizeJSONNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = NormalizeJSONNumbers(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = NormalizeJSONNumbers(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func jsonEqual(a any, b any) bool {
+	aBytes, aErr := json.Marshal(a)
+	bBytes, bErr := json.Marshal(b)
+
+	return aErr == nil && bErr == nil && bytes.Equal(aBytes, bBytes)
+}
+
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+func withoutNulls(object map[string]any) map[string]any {
+	cleaned := map[string]any{}
+
+	for key, value := range object {
+		if value == nil {
+			continue
+		}
+
+		if nested, ok := value.(map[string]any); ok {
+			value = withoutNulls(nested)
+		}
+
+		cleaned[key] = value
+	}
+
+	return cleaned
+}
+
+func joinPath(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+func rootOf(path string) string {
+	return strings.Split(path, ".")[0]
+}
+
+func pathsOverlap(a string, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
+var _ PatchRepository[any] = (*BaseRepo[any])(nil)
+var _ PatchRepository[any] = (*SQLRepo[any])(nil)
+var _ PatchRepository[any] = (*MemoryRepo[any])(nil)
@@ -0,0 +1,265 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func decodeJSON[V any](t *testing.T, raw string) V {
+	t.Helper()
+
+	var value V
+
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+
+	return value
+}
+
+func isInvalidPatch(err error) bool {
+	return errors.As(err, new(ErrInvalidPatch))
+}
+
+const patchTestDoc = `{"title": "a", "tags": ["x", "y"], "address": {"city": "KL", "zip": "50000"}, "version": 2}`
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		patch     string
+		want      string
+		wantSet   []string
+		wantUnset []string
+		wantErr   bool
+	}{
+		{"set field", `{"title": "b"}`, `{"title": "b", "tags": ["x", "y"], "address": {"city": "KL", "zip": "50000"}, "version": 2}`, []string{"title"}, nil, false},
+		{"null removes field", `{"tags": null}`, `{"title": "a", "address": {"city": "KL", "zip": "50000"}, "version": 2}`, nil, []string{"tags"}, false},
+		{"nested merge", `{"address": {"city": "Penang", "zip": null}}`, `{"title": "a", "tags": ["x", "y"], "address": {"city": "Penang"}, "version": 2}`, []string{"address.city"}, []string{"address.zip"}, false},
+		{"new object drops nulls", `{"owner": {"name": "n", "email": null}}`, `{"title": "a", "tags": ["x", "y"], "address": {"city": "KL", "zip": "50000"}, "version": 2, "owner": {"name": "n"}}`, []string{"owner"}, nil, false},
+		{"arrays are replaced", `{"tags": ["z"]}`, `{"title": "a", "tags": ["z"], "address": {"city": "KL", "zip": "50000"}, "version": 2}`, []string{"tags"}, nil, false},
+		{"protected field", `{"version": 3}`, "", nil, nil, true},
+		{"protected id", `{"_id": "x"}`, "", nil, nil, true},
+		{"dotted field name", `{"address.city": "x"}`, "", nil, nil, true},
+		{"operator field name", `{"$set": {"title": "x"}}`, "", nil, nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, update, err := MergePatch(decodeJSON[map[string]any](t, patchTestDoc), decodeJSON[map[string]any](t, test.patch))
+
+			if test.wantErr {
+				if !isInvalidPatch(err) {
+					t.Fatalf("got error %v, want ErrInvalidPatch", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("merge patch: %v", err)
+			}
+
+			if want := decodeJSON[map[string]any](t, test.want); !reflect.DeepEqual(doc, want) {
+				t.Errorf("got document %v, want %v", doc, want)
+			}
+
+			if set := slices.Sorted(maps.Keys(update.Set)); !slices.Equal(set, test.wantSet) {
+				t.Errorf("got set %v, want %v", set, test.wantSet)
+			}
+
+			if !slices.Equal(update.Unset, test.wantUnset) {
+				t.Errorf("got unset %v, want %v", update.Unset, test.wantUnset)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations string
+		want       string
+		wantErr    error
+	}{
+		{"replace", `[{"op": "replace", "path": "/title", "value": "b"}]`, `{"title": "b", "tags": ["x", "y"], "address": {"city": "KL", "zip": "50000"}, "version": 2}`, nil},
+		{"append to array", `[{"op": "add", "path": "/tags/-", "value": "z"}]`, `{"title": "a", "tags": ["x", "y", "z"], "address": {"city": "KL", "zip": "50000"}, "version": 2}`, nil},
+		{"insert into array", `[{"op": "add", "path": "/tags/0", "value": "w"}]`, `{"title": "a", "tags": ["w", "x", "y"], "address": {"city": "KL", "zip": "50000"}, "version": 2}`, nil},
+		{"remove from array", `[{"op": "remove", "path": "/tags/0"}]`, `{"title": "a", "tags": ["y"], "address": {"city": "KL", "zip": "50000"}, "version": 2}`, nil},
+		{"move", `[{"op": "move", "from": "/address/zip", "path": "/zip"}]`, `{"title": "a", "tags": ["x", "y"], "address": {"city": "KL"}, "zip": "50000", "version": 2}`, nil},
+		{"copy", `[{"op": "copy", "from": "/title", "path": "/address/label"}]`, `{"title": "a", "tags": ["x", "y"], "address": {"city": "KL", "zip": "50000", "label": "a"}, "version": 2}`, nil},
+		{"passing test", `[{"op": "test", "path": "/title", "value": "a"}, {"op": "remove", "path": "/address"}]`, `{"title": "a", "tags": ["x", "y"], "version": 2}`, nil},
+		{"failing test", `[{"op": "test", "path": "/title", "value": "b"}, {"op": "remove", "path": "/address"}]`, "", ErrPatchTestFailed},
+		{"replace missing path", `[{"op": "replace", "path": "/missing", "value": 1}]`, "", ErrInvalidPatch{}},
+		{"array index out of range", `[{"op": "add", "path": "/tags/5", "value": "z"}]`, "", ErrInvalidPatch{}},
+		{"move into own child", `[{"op": "move", "from": "/address", "path": "/address/home"}]`, "", ErrInvalidPatch{}},
+		{"protected field", `[{"op": "replace", "path": "/version", "value": 3}]`, "", ErrInvalidPatch{}},
+		{"unsupported op", `[{"op": "merge", "path": "/title", "value": "b"}]`, "", ErrInvalidPatch{}},
+		{"missing value", `[{"op": "add", "path": "/title"}]`, "", ErrInvalidPatch{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, _, err := JSONPatch(decodeJSON[map[string]any](t, patchTestDoc), decodeJSON[[]PatchOperation](t, test.operations))
+
+			switch {
+			case test.wantErr == nil && err != nil:
+				t.Fatalf("json patch: %v", err)
+			case errors.Is(test.wantErr, ErrPatchTestFailed):
+				if !errors.Is(err, ErrPatchTestFailed) {
+					t.Fatalf("got error %v, want %v", err, test.wantErr)
+				}
+
+				return
+			case test.wantErr != nil:
+				if !isInvalidPatch(err) {
+					t.Fatalf("got error %v, want ErrInvalidPatch", err)
+				}
+
+				return
+			}
+
+			if want := decodeJSON[map[string]any](t, test.want); !reflect.DeepEqual(doc, want) {
+				t.Errorf("got document %v, want %v", doc, want)
+			}
+		})
+	}
+}
+
+func TestMemoryRepoPatchByID(t *testing.T) {
+	tests := []struct {
+		name     string
+		update   types.PatchUpdate
+		wantErr  bool
+		wantTags []string
+	}{
+		{"push", types.PatchUpdate{Push: map[string]types.PatchPush{"tags": {Values: []any{"c"}}}}, false, []string{"a", "b", "c"}},
+		{"push at position", types.PatchUpdate{Push: map[string]types.PatchPush{"tags": {Values: []any{"c"}, Position: new(int)}}}, false, []string{"c", "a", "b"}},
+		{"pull", types.PatchUpdate{Pull: map[string][]any{"tags": {"a"}}}, false, []string{"b"}},
+		{"unset", types.PatchUpdate{Unset: []string{"tags"}}, false, nil},
+		{"set version", types.PatchUpdate{Set: map[string]any{"version": 5}}, true, []string{"a", "b"}},
+		{"unset deletedAt", types.PatchUpdate{Unset: []string{"deletedAt"}}, true, []string{"a", "b"}},
+		{"push to _id", types.PatchUpdate{Push: map[string]types.PatchPush{"_id": {Values: []any{"x"}}}}, true, []string{"a", "b"}},
+		{"operator path", types.PatchUpdate{Set: map[string]any{"tags.$": "x"}}, true, []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo, notes := newTestNotes(t, false, true, testNote{Title: "n", Tags: []string{"a", "b"}})
+
+			_, err := repo.PatchByID(notes[0].ID, nil, test.update)
+
+			if test.wantErr != isInvalidPatch(err) || !test.wantErr && err != nil {
+				t.Fatalf("got error %v, want invalid patch %v", err, test.wantErr)
+			}
+
+			stored, err := repo.GetByID(notes[0].ID)
+
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+
+			if !slices.Equal(stored.Tags, test.wantTags) {
+				t.Errorf("got tags %v, want %v", stored.Tags, test.wantTags)
+			}
+
+			wantVersion := int64(2)
+			if test.wantErr {
+				wantVersion = 1
+			}
+
+			if stored.Version != wantVersion {
+				t.Errorf("got version %d, want %d", stored.Version, wantVersion)
+			}
+		})
+	}
+}
+
+type typedPatchDoc struct {
+	OwnerID  bson.ObjectID   `json:"ownerId"`
+	Members  []bson.ObjectID `json:"members"`
+	DueAt    *time.Time      `json:"dueAt"`
+	Extra    map[string]any  `json:"extra"`
+	Schedule struct {
+		StartAt time.Time `json:"startAt"`
+	} `json:"schedule"`
+}
+
+func TestTypedPatchUpdate(t *testing.T) {
+	owner := bson.NewObjectID()
+	dueAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		update  types.PatchUpdate
+		want    types.PatchUpdate
+		wantErr bool
+	}{
+		{
+			"object id and time",
+			types.PatchUpdate{Set: map[string]any{"ownerId": owner.Hex(), "dueAt": dueAt.Format(time.RFC3339), "schedule.startAt": dueAt.Format(time.RFC3339)}},
+			types.PatchUpdate{Set: map[string]any{"ownerId": owner, "dueAt": &dueAt, "schedule.startAt": dueAt}},
+			false,
+		},
+		{
+			"array items",
+			types.PatchUpdate{Set: map[string]any{"members.0": owner.Hex()}, Push: map[string]types.PatchPush{"members": {Values: []any{owner.Hex()}}}, Pull: map[string][]any{"members": {owner.Hex()}}},
+			types.PatchUpdate{Set: map[string]any{"members.0": owner}, Push: map[string]types.PatchPush{"members": {Values: []any{owner}}}, Pull: map[string][]any{"members": {owner}}},
+			false,
+		},
+		{
+			"untyped fields kept as is",
+			types.PatchUpdate{Set: map[string]any{"extra.ownerId": owner.Hex(), "unknown": "x"}, Unset: []string{"dueAt"}},
+			types.PatchUpdate{Set: map[string]any{"extra.ownerId": owner.Hex(), "unknown": "x"}, Unset: []string{"dueAt"}},
+			false,
+		},
+		{"invalid object id", types.PatchUpdate{Set: map[string]any{"ownerId": "x"}}, types.PatchUpdate{}, true},
+		{"invalid array item", types.PatchUpdate{Push: map[string]types.PatchPush{"members": {Values: []any{1}}}}, types.PatchUpdate{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := typedPatchUpdate[typedPatchDoc](test.update)
+
+			if test.wantErr {
+				if !isInvalidPatch(err) {
+					t.Fatalf("got error %v, want invalid patch", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+
+			if !reflect.DeepEqual(got.Set, test.want.Set) {
+				t.Errorf("got set %#v, want %#v", got.Set, test.want.Set)
+			}
+
+			if !reflect.DeepEqual(got.Unset, test.want.Unset) {
+				t.Errorf("got unset %v, want %v", got.Unset, test.want.Unset)
+			}
+
+			for path, push := range test.want.Push {
+				if !reflect.DeepEqual(got.Push[path].Values, push.Values) {
+					t.Errorf("got push %v, want %v", got.Push[path].Values, push.Values)
+				}
+			}
+
+			for path, values := range test.want.Pull {
+				if !reflect.DeepEqual(got.Pull[path], values) {
+					t.Errorf("got pull %v, want %v", got.Pull[path], values)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"strings"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+)
+
+// Filter operators usable with Query and Where
+const (
+	Eq    = types.OpEq
+	Like  = types.OpLike
+	Gte   = types.OpGte
+	Lte   = types.OpLte
+	Start = types.OpStart
+	End   = types.OpEnd
+)
+
+// Fluent query over records of T, accepted by GetAll through Build
+// e.g: repo.Query[Note]().Where("title", repo.Like, "x").Or(repo.Where("pinned", repo.Eq, true), repo.Where("priority", repo.Gte, 3)).Sort("-createdAt").Limit(10)
+type QueryBuilder[T any] struct {
+	root   types.QueryGroup
+	sorts  []types.QueryParamsSortField
+	mode   types.DeletedMode
+	limit  int
+	search string
+}
+
+func Query[T any]() *QueryBuilder[T] {
+	return &QueryBuilder[T]{root: types.QueryGroup{Logic: types.LogicAnd}}
+}
+
+// Single filter condition to nest within And / Or
+func Where(field string, operator types.QueryParamsFilterOp, value any) types.QueryGroup {
+	return types.QueryGroup{Logic: types.LogicAnd, Filters: []types.QueryParamsFilter{{Field: field, Operator: operator, Value: value}}}
+}
+
+// Group matching when every condition matches
+func And(conditions ...types.QueryGroup) types.QueryGroup {
+	return types.QueryGroup{Logic: types.LogicAnd, Groups: conditions}
+}
+
+// Group matching when any condition matches
+func Or(conditions ...types.QueryGroup) types.QueryGroup {
+	return types.QueryGroup{Logic: types.LogicOr, Groups: conditions}
+}
+
+func (q *QueryBuilder[T]) Where(field string, operator types.QueryParamsFilterOp, value any) *QueryBuilder[T] {
+	q.root.Filters = append(q.root.Filters, types.QueryParamsFilter{Field: field, Operator: operator, Value: value})
+	return q
+}
+
+// Require every condition to match
+func (q *QueryBuilder[T]) And(conditions ...types.QueryGroup) *QueryBuilder[T] {
+	q.root.Groups = append(q.root.Groups, And(conditions...))
+	return q
+}
+
+// Require at least one condition to match
+func (q *QueryBuilder[T]) Or(conditions ...types.QueryGroup) *QueryBuilder[T] {
+	q.root.Groups = append(q.root.Groups, Or(conditions...))
+	return q
+}
+
+// Sort by fields in order, prefix with "-" for descending e.g: Sort("-createdAt", "title")
+func (q *QueryBuilder[T]) Sort(fields ...string) *QueryBuilder[T] {
+	for _, field := range fields {
+		q.sorts = append(q.sorts, types.QueryParamsSortField{Field: strings.TrimPrefix(field, "-"), Descending: strings.HasPrefix(field, "-")})
+	}
+
+	return q
+}
+
+// Maximum records returned when not paginated
+func (q *QueryBuilder[T]) Limit(limit int) *QueryBuilder[T] {
+	q.limit = limit
+	return q
+}
+
+// Soft deleted records visibility, only applied when repo soft delete is enabled
+func (q *QueryBuilder[T]) Deleted(mode types.DeletedMode) *QueryBuilder[T] {
+	q.mode = mode
+	return q
+}
+
+// Full-text search over text index fields, combine with Sort("score") for relevance order
+func (q *QueryBuilder[T]) Search(search string) *QueryBuilder[T] {
+	q.search = search
+	return q
+}
+
+func (q *QueryBuilder[T]) Build() *types.GetAllFiltersAndSorts {
+	return &types.GetAllFiltersAndSorts{
+		QueryGroups:           []types.QueryGroup{q.root},
+		QueryParamsSortFields: q.sorts,
+		DeletedMode:           q.mode,
+		Limit:                 q.limit,
+		Search:                q.search,
+	}
+}
+
+// Run query without pagination
+func (q *QueryBuilder[T]) All(repo Repository[T]) ([]T, error) {
+	results, err := repo.GetAll(false, q.Build(), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results.Records, nil
+}
+
+// First record matching query, nil when none matches
+func (q *QueryBuilder[T]) First(repo Repository[T]) (*T, error) {
+	query := q.Build()
+	query.Limit = 1
+
+	results, err := repo.GetAll(false, query, nil)
+
+	if err != nil || len(results.Records) == 0 {
+		return nil, err
+	}
+
+	return &results.Records[0], nil
+}
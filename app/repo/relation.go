@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Include refers to unknown relation or is not supported by the repo
+type ErrInvalidInclude struct {
+	Message string
+}
+
+func (e ErrInvalidInclude) Error() string {
+	return e.Message
+}
+
+var (
+	relations      = map[string][]types.Relation{}
+	relationsMutex sync.RWMutex
+)
+
+// Register relations available to includes of collection/table, registering again replaces them
+func RegisterRelations(collection string, collectionRelations ...types.Relation) {
+	relationsMutex.Lock()
+	defer relationsMutex.Unlock()
+
+	relations[collection] = collectionRelations
+}
+
+func RegisteredRelations(collection string) []types.Relation {
+	relationsMutex.RLock()
+	defer relationsMutex.RUnlock()
+
+	return relations[collection]
+}
+
+type includeNode struct {
+	relation types.Relation
+	children []includeNode
+}
+
+// Resolve dotted includes into relation tree, e.g: ["comments.author", "tags"]
+func resolveIncludes(collection string, includes []string) ([]includeNode, error) {
+	grouped := map[string][]string{}
+	var names []string
+
+	for _, include := range includes {
+		if include = strings.TrimSpace(include); include == "" {
+			continue
+		}
+
+		name, rest, _ := strings.Cut(include, ".")
+
+		if _, ok := grouped[name]; !ok {
+			names = append(names, name)
+			grouped[name] = nil
+		}
+
+		if rest != "" {
+			grouped[name] = append(grouped[name], rest)
+		}
+	}
+
+	sort.Strings(names)
+
+	var nodes []includeNode
+
+	for _, name := range names {
+		relation, ok := findRelation(collection, name)
+
+		if !ok {
+			return nil, ErrInvalidInclude{Message: fmt.Sprintf("Unknown include %s.", name)}
+		}
+
+		children, err := resolveIncludes(relation.Collection, grouped[name])
+
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, includeNode{relation: relation, children: children})
+	}
+
+	return nodes, nil
+}
+
+func findRelation(collection string, name string) (types.Relation, bool) {
+	for _, relation := range RegisteredRelations(collection) {
+		if relation.Name == name {
+			return relation, true
+		}
+	}
+
+	return types.Relation{}, false
+}
+
+// Translate include tree into $lookup stages, soft deleted related records are left out
+func lookupStages(nodes []includeNode) mongo.Pipeline {
+	var stages mongo.Pipeline
+
+	for _, node := range nodes {
+		localField, foreignField := node.relation.Field, "_id"
+
+		if node.relation.Kind == types.HasMany {
+			localField, foreignField = "_id", node.relation.Field
+		}
+
+		pipeline := append(mongo.Pipeline{
+			{{Key: "$match", Value: bson.D{
+				{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$" + foreignField, "$$localField"}}}},
+				{Key: "deletedAt", Value: nil},
+			}}},
+		}, lookupStages(node.children)...)
+
+		stages = append(stages, bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: node.relation.Collection},
+			{Key: "let", Value: bson.D{{Key: "localField", Value: "$" + localField}}},
+			{Key: "pipeline", Value: pipeline},
+			{Key: "as", Value: node.relation.Name},
+		}}})
+
+		if node.relation.Kind == types.BelongsTo {
+			stages = append(stages, bson.D{{Key: "$unwind", Value: bson.D{
+				{Key: "path", Value: "$" + node.relation.Name},
+				{Key: "preserveNullAndEmptyArrays", Value: true},
+			}}})
+		}
+	}
+
+	return stages
+}
+
+// Load related documents into docs in place, mirrors BaseRepo $lookup stages
+func (r *MemoryRepo[T]) includeDocs(docs []bson.M, nodes []includeNode) {
+	for _, node := range nodes {
+		localField, foreignField := node.relation.Field, "_id"
+
+		if node.relation.Kind == types.HasMany {
+			localField, foreignField = "_id", node.relation.Field
+		}
+
+		var related []bson.M
+
+		r.store().read(node.relation.Collection, func(relatedDocs []bson.M) {
+			for _, relatedDoc := range relatedDocs {
+				if relatedDoc["deletedAt"] == nil {
+					related = append(related, cloneDoc(relatedDoc))
+				}
+			}
+		})
+
+		r.includeDocs(related, node.children)
+
+		for _, doc := range docs {
+			matched := bson.A{}
+
+			for _, relatedDoc := range related {
+				if value, ok := doc[localField]; ok && value != nil && relationKey(relatedDoc[foreignField]) == relationKey(value) {
+					matched = append(matched, relatedDoc)
+				}
+			}
+
+			if node.relation.Kind == types.HasMany {
+				doc[node.relation.Name] = matched
+			} else if len(matched) > 0 {
+				doc[node.relation.Name] = matched[0]
+			}
+		}
+	}
+}
+
+func relationKey(value any) string {
+	if id, ok := value.(bson.ObjectID); ok {
+		return id.Hex()
+	}
+
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"fmt"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+)
+
+// Database handles of registered externals, refer externals.ResourceDB
+type ResourceDB struct {
+	types.AppDB
+	Memory *MemoryStore // Used by in-memory repo
+}
+
+type ResourceRepoOptions struct {
+	SoftDelete bool              // Mark records deleted instead of removing them
+	Versioned  bool              // Increment version on every update for optimistic concurrency
+	Indexes    []types.IndexSpec // Indexes on top of ones declared by T e.g: text index of searchable fields
+}
+
+// Build resource repo from database handles, preferring MongoDB, then SQL (creating the table), then in-memory
+func NewResourceRepo[T any](db ResourceDB, name string, options ResourceRepoOptions) (Repository[T], error) {
+	if db.MongoDB != nil {
+		return &BaseRepo[T]{
+			DB:         types.AppDB{MongoDB: db.MongoDB},
+			Collection: name,
+			UpdatedAt:  true,
+			CreatedAt:  true,
+			SoftDelete: options.SoftDelete,
+			Versioned:  options.Versioned,
+			Indexes:    options.Indexes,
+		}, nil
+	}
+
+	if db.SQL != nil {
+		sqlRepo := &SQLRepo[T]{
+			DB:         types.AppDB{SQL: db.SQL, SQLDriver: db.SQLDriver},
+			Table:      name,
+			UpdatedAt:  true,
+			CreatedAt:  true,
+			SoftDelete: options.SoftDelete,
+			Versioned:  options.Versioned,
+		}
+
+		if err := sqlRepo.CreateTable(); err != nil {
+			return nil, err
+		}
+
+		return sqlRepo, nil
+	}
+
+	if db.Memory != nil {
+		return &MemoryRepo[T]{
+			Store:      db.Memory,
+			Collection: name,
+			UpdatedAt:  true,
+			CreatedAt:  true,
+			SoftDelete: options.SoftDelete,
+			Versioned:  options.Versioned,
+			Indexes:    options.Indexes,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no database external registered for resource %s", name)
+}
@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Relevance of full-text search results, set on every matched record
+const SearchScoreField = "score"
+
+var ErrSearchUnsupported = errors.New("full-text search is not supported by this repository")
+
+// Sort by score orders by relevance, most relevant first
+func relevanceSorts(sortFields []types.QueryParamsSortField, search string) []types.QueryParamsSortField {
+	if search == "" {
+		return sortFields
+	}
+
+	sorts := make([]types.QueryParamsSortField, len(sortFields))
+
+	for i, item := range sortFields {
+		sorts[i] = item
+
+		if item.Field == SearchScoreField {
+			sorts[i].Descending = true
+		}
+	}
+
+	return sorts
+}
+
+// Fields of the text index, see declaredIndexes
+func textIndexFields(specs []types.IndexSpec) []string {
+	for _, spec := range specs {
+		if spec.Text {
+			return spec.Fields
+		}
+	}
+
+	return nil
+}
+
+// Lowercase words and numbers of text
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Fallback for $text search, number of query term occurrences across fields, 0 when nothing matches
+func searchScore(doc bson.M, fields []string, search string) float64 {
+	terms := map[string]bool{}
+
+	for _, term := range tokenize(search) {
+		terms[term] = true
+	}
+
+	score := 0.0
+
+	for _, field := range fields {
+		value, ok := doc[strings.TrimPrefix(field, "-")]
+
+		if !ok || value == nil {
+			continue
+		}
+
+		for _, token := range tokenize(searchableText(value)) {
+			if terms[token] {
+				score++
+			}
+		}
+	}
+
+	return score
+}
+
+func searchableText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bson.A:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = searchableText(item)
+		}
+		return strings.Join(parts, " ")
+	default:
+		return ""
+	}
+}
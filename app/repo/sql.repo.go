@@ -0,0 +1,900 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// SQL implementation of Repository, table columns are mapped from T struct tags (db, then bson, then json)
+type SQLRepo[T any] struct {
+	DB         types.AppDB
+	Table      string
+	UpdatedAt  bool
+	CreatedAt  bool
+	SoftDelete bool              // Mark deletedAt column instead of deleting the row, default is false
+	Versioned  bool              // Increment version column on every update for optimistic concurrency, default is false
+	Indexes    []types.IndexSpec // Indexes on top of ones declared by T, created by SyncIndexes
+	ctx        context.Context
+}
+
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type sqlTxContextKey struct{}
+
+// Attach SQL transaction to context, SQLRepo calls made with this context run within the transaction
+func ContextWithSQLTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, sqlTxContextKey{}, tx)
+}
+
+// Get SQL transaction attached to context, nil when there is none
+func SQLTxFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(sqlTxContextKey{}).(*sql.Tx)
+
+	return tx
+}
+
+// Bind repo calls to context, calls made with a WithTransaction context join the transaction automatically
+func (r *SQLRepo[T]) WithContext(ctx context.Context) *SQLRepo[T] {
+	clone := *r
+	clone.ctx = ctx
+	return &clone
+}
+
+func (r *SQLRepo[T]) getContext() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+
+	return context.TODO()
+}
+
+func (r *SQLRepo[T]) executor() sqlExecutor {
+	if tx := SQLTxFromContext(r.getContext()); tx != nil {
+		return tx
+	}
+
+	return r.DB.SQL
+}
+
+type sqlColumn struct {
+	Name    string       // Table column name
+	JSONKey string       // Json key used when binding row into T
+	Type    reflect.Type // Go type of the mapped struct field
+}
+
+var sqlIdentifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(bson.ObjectID{})
+)
+
+func tagName(tag string) string {
+	return strings.Split(tag, ",")[0]
+}
+
+// Resolve table columns from T struct fields, empty when T is not a struct (e.g: map)
+func sqlColumns[T any]() []sqlColumn {
+	var zero T
+	t := reflect.TypeOf(zero)
+
+	if t == nil {
+		return nil
+	}
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var columns []sqlColumn
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		jsonKey := tagName(field.Tag.Get("json"))
+		if jsonKey == "" {
+			jsonKey = field.Name
+		}
+
+		name := tagName(field.Tag.Get("db"))
+		if name == "" {
+			name = tagName(field.Tag.Get("bson"))
+		}
+		if name == "" {
+			name = jsonKey
+		}
+
+		if name == "-" || jsonKey == "-" {
+			continue
+		}
+
+		columns = append(columns, sqlColumn{Name: name, JSONKey: jsonKey, Type: field.Type})
+	}
+
+	return columns
+}
+
+func (r *SQLRepo[T]) isPostgres() bool {
+	return r.DB.SQLDriver == "pgx" || r.DB.SQLDriver == "postgres"
+}
+
+func (r *SQLRepo[T]) placeholder(n int) string {
+	if r.isPostgres() {
+		return fmt.Sprintf("$%d", n)
+	}
+
+	return "?"
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + name + `"`
+}
+
+// Validate column against T mapping, or against identifier format when T is not a struct
+func (r *SQLRepo[T]) column(name string) (*sqlColumn, error) {
+	columns := sqlColumns[T]()
+
+	if len(columns) == 0 {
+		if !sqlIdentifierRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid column name: %s", name)
+		}
+
+		return &sqlColumn{Name: name, JSONKey: name}, nil
+	}
+
+	for _, column := range columns {
+		if column.Name == name {
+			return &column, nil
+		}
+	}
+
+	// Columns maintained by the repo itself even when T does not map them
+	if (name == "deletedAt" && r.SoftDelete) || (name == "version" && r.Versioned) {
+		return &sqlColumn{Name: name, JSONKey: name}, nil
+	}
+
+	return nil, fmt.Errorf("unknown column %s for table %s", name, r.Table)
+}
+
+// Whether updatedAt is set by soft delete and restore, skipped when T does not map the column
+func (r *SQLRepo[T]) stampsUpdatedAt() bool {
+	if !r.UpdatedAt {
+		return false
+	}
+
+	_, err := r.column("updatedAt")
+
+	return err == nil
+}
+
+func sqlID(id any) (string, error) {
+	switch v := id.(type) {
+	case bson.ObjectID:
+		return v.Hex(), nil
+	case string:
+		if _, err := bson.ObjectIDFromHex(v); err != nil {
+			return "", err
+		}
+
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid id type %T", id)
+	}
+}
+
+// Convert bson value into database/sql compatible argument
+func sqlValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bson.ObjectID:
+		return v.Hex(), nil
+	case bson.DateTime:
+		return v.Time().UTC(), nil
+	case time.Time:
+		return v.UTC(), nil
+	case int32:
+		return int64(v), nil
+	case bson.D, bson.M, bson.A, map[string]any, []any:
+		bytes, err := json.Marshal(jsonCompatible(v))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return string(bytes), nil
+	default:
+		return v, nil
+	}
+}
+
+// Recursively convert bson documents into plain maps so they marshal as json objects
+func jsonCompatible(value any) any {
+	switch v := value.(type) {
+	case bson.D:
+		converted := map[string]any{}
+		for _, e := range v {
+			converted[e.Key] = jsonCompatible(e.Value)
+		}
+		return converted
+	case bson.M:
+		converted := map[string]any{}
+		for key, item := range v {
+			converted[key] = jsonCompatible(item)
+		}
+		return converted
+	case map[string]any:
+		converted := map[string]any{}
+		for key, item := range v {
+			converted[key] = jsonCompatible(item)
+		}
+		return converted
+	case bson.A:
+		converted := make([]any, len(v))
+		for i, item := range v {
+			converted[i] = jsonCompatible(item)
+		}
+		return converted
+	case []any:
+		converted := make([]any, len(v))
+		for i, item := range v {
+			converted[i] = jsonCompatible(item)
+		}
+		return converted
+	case bson.DateTime:
+		return v.Time().UTC()
+	default:
+		return v
+	}
+}
+
+// Convert scanned column value into json friendly value according to mapped field type
+func fromSQLValue(value any, fieldType reflect.Type) any {
+	if bytes, ok := value.([]byte); ok {
+		value = string(bytes)
+	}
+
+	if fieldType == nil || value == nil {
+		return value
+	}
+
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Bool:
+		if i, ok := value.(int64); ok {
+			return i != 0
+		}
+	case reflect.Struct, reflect.Map, reflect.Slice:
+		if fieldType == timeType || fieldType == objectIDType {
+			return value
+		}
+
+		if s, ok := value.(string); ok && json.Valid([]byte(s)) {
+			return json.RawMessage(s)
+		}
+	}
+
+	return value
+}
+
+func (r *SQLRepo[T]) scanRows(rows *sql.Rows) ([]T, error) {
+	columnNames, err := rows.Columns()
+
+	if err != nil {
+		return nil, err
+	}
+
+	mapped := map[string]sqlColumn{}
+	for _, column := range sqlColumns[T]() {
+		mapped[column.Name] = column
+	}
+
+	var results []T
+
+	for rows.Next() {
+		values := make([]any, len(columnNames))
+		pointers := make([]any, len(columnNames))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		record := map[string]any{}
+		for i, name := range columnNames {
+			if column, ok := mapped[name]; ok {
+				record[column.JSONKey] = fromSQLValue(values[i], column.Type)
+			} else {
+				record[name] = fromSQLValue(values[i], nil)
+			}
+		}
+
+		var typed T
+
+		if bindErr := bindData(record, &typed); bindErr != nil {
+			return nil, bindErr
+		}
+
+		results = append(results, typed)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Soft deleted visibility condition, empty when soft delete is disabled or all rows are visible
+func (r *SQLRepo[T]) deletedCondition(mode types.DeletedMode) string {
+	if !r.SoftDelete {
+		return ""
+	}
+
+	switch mode {
+	case types.DeletedWith:
+		return ""
+	case types.DeletedOnly:
+		return fmt.Sprintf("%s IS NOT NULL", quoteIdentifier("deletedAt"))
+	default:
+		return fmt.Sprintf("%s IS NULL", quoteIdentifier("deletedAt"))
+	}
+}
+
+// Translate query params filters into parameterized WHERE clause
+func (r *SQLRepo[T]) buildWhere(filters []types.QueryParamsFilter, mode types.DeletedMode, args []any) (string, []any, error) {
+	return r.buildGroupWhere(types.QueryGroup{Logic: types.LogicAnd, Filters: filters}, mode, args)
+}
+
+// Translate nested query group into parameterized WHERE clause
+func (r *SQLRepo[T]) buildGroupWhere(group types.QueryGroup, mode types.DeletedMode, args []any) (string, []any, error) {
+	var conditions []string
+
+	if condition := r.deletedCondition(mode); condition != "" {
+		conditions = append(conditions, condition)
+	}
+
+	condition, args, err := r.buildGroup(group, args)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	if condition != "" {
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) == 0 {
+		return "", args, nil
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+func (r *SQLRepo[T]) buildGroup(group types.QueryGroup, args []any) (string, []any, error) {
+	var conditions []string
+
+	for _, item := range group.Filters {
+		column, err := r.column(item.Field)
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		name := quoteIdentifier(column.Name)
+
+		value, err := sqlValue(item.Value)
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch item.Operator {
+		case types.OpLike, types.OpStart, types.OpEnd:
+			// Wildcards of the value match literally like the $regex of BaseRepo quotes them
+			pattern := likeEscaper.Replace(strings.ToLower(fmt.Sprint(item.Value)))
+
+			if item.Operator != types.OpStart {
+				pattern = "%" + pattern
+			}
+
+			if item.Operator != types.OpEnd {
+				pattern += "%"
+			}
+
+			args = append(args, pattern)
+			conditions = append(conditions, fmt.Sprintf(`LOWER(%s) LIKE %s ESCAPE '\'`, name, r.placeholder(len(args))))
+		case types.OpGte:
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s >= %s", name, r.placeholder(len(args))))
+		case types.OpLte:
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s <= %s", name, r.placeholder(len(args))))
+		case types.OpEq:
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("%s = %s", name, r.placeholder(len(args))))
+		}
+	}
+
+	for _, subGroup := range group.Groups {
+		condition, subArgs, err := r.buildGroup(subGroup, args)
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		args = subArgs
+
+		if condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", args, nil
+	}
+
+	separator := " AND "
+
+	if group.Logic == types.LogicOr {
+		separator = " OR "
+	}
+
+	return "(" + strings.Join(conditions, separator) + ")", args, nil
+}
+
+func (r *SQLRepo[T]) buildOrderBy(sortFields []types.QueryParamsSortField) (string, error) {
+	var orders []string
+
+	for _, item := range sortFields {
+		if item.Field == "" {
+			continue
+		}
+
+		column, err := r.column(item.Field)
+
+		if err != nil {
+			return "", err
+		}
+
+		direction := "ASC"
+
+		if item.Descending {
+			direction = "DESC"
+		}
+
+		orders = append(orders, fmt.Sprintf("%s %s", quoteIdentifier(column.Name), direction))
+	}
+
+	if len(orders) == 0 {
+		return "", nil
+	}
+
+	return " ORDER BY " + strings.Join(orders, ", "), nil
+}
+
+// Create table based on T struct mapping if it does not exist yet
+func (r *SQLRepo[T]) CreateTable() error {
+	columns := sqlColumns[T]()
+
+	if len(columns) == 0 {
+		return fmt.Errorf("unable to map table %s, type must be a struct", r.Table)
+	}
+
+	var definitions []string
+
+	hasID := false
+	for _, column := range columns {
+		definition := fmt.Sprintf("%s %s", quoteIdentifier(column.Name), r.columnType(column.Type))
+
+		if column.Name == "_id" {
+			definition = fmt.Sprintf("%s TEXT PRIMARY KEY", quoteIdentifier(column.Name))
+			hasID = true
+		}
+
+		definitions = append(definitions, definition)
+	}
+
+	if !hasID {
+		definitions = append([]string{fmt.Sprintf("%s TEXT PRIMARY KEY", quoteIdentifier("_id"))}, definitions...)
+	}
+
+	mapped := map[string]bool{}
+	for _, column := range columns {
+		mapped[column.Name] = true
+	}
+
+	if r.SoftDelete && !mapped["deletedAt"] {
+		definitions = append(definitions, fmt.Sprintf("%s %s", quoteIdentifier("deletedAt"), r.columnType(timeType)))
+	}
+
+	if r.Versioned && !mapped["version"] {
+		definitions = append(definitions, fmt.Sprintf("%s %s", quoteIdentifier("version"), r.columnType(reflect.TypeOf(int64(0)))))
+	}
+
+	_, err := r.executor().ExecContext(r.getContext(), fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quoteIdentifier(r.Table), strings.Join(definitions, ", ")))
+
+	return err
+}
+
+func (r *SQLRepo[T]) columnType(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		if r.isPostgres() {
+			return "TIMESTAMPTZ"
+		}
+
+		return "DATETIME"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "BOOLEAN"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if r.isPostgres() {
+			return "BIGINT"
+		}
+
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		if r.isPostgres() {
+			return "DOUBLE PRECISION"
+		}
+
+		return "REAL"
+	default:
+		return "TEXT"
+	}
+}
+
+func (r *SQLRepo[T]) Create(data any) (*T, error) {
+	parsed, parsedErr := bindBson(data)
+
+	if parsedErr != nil {
+		return nil, parsedErr
+	}
+
+	stampTimestamps(parsed, r.CreatedAt, r.UpdatedAt)
+
+	if id, ok := parsed["_id"]; !ok || id == nil {
+		parsed["_id"] = bson.NewObjectID()
+	}
+
+	if r.Versioned {
+		parsed["version"] = 1
+	}
+
+	var (
+		columns      []string
+		placeholders []string
+		args         []any
+	)
+
+	for key, value := range parsed {
+		column, err := r.column(key)
+
+		if err != nil {
+			// Skip properties which are not part of the table mapping
+			continue
+		}
+
+		arg, err := sqlValue(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg)
+		columns = append(columns, quoteIdentifier(column.Name))
+		placeholders = append(placeholders, r.placeholder(len(args)))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdentifier(r.Table), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	if _, err := r.executor().ExecContext(r.getContext(), query, args...); err != nil {
+		return nil, sqlError(err)
+	}
+
+	return r.GetByID(parsed["_id"])
+}
+
+func (r *SQLRepo[T]) GetAll(paginated bool, filtersAndSorts *types.GetAllFiltersAndSorts, paginationParams *types.PaginationParams) (*types.PaginatedRecords[T], error) {
+	var (
+		where   string
+		orderBy string
+		args    []any
+		page    = 1
+		perPage = 10
+		total   = 0
+	)
+
+	if filtersAndSorts == nil {
+		filtersAndSorts = &types.GetAllFiltersAndSorts{}
+	}
+
+	if len(filtersAndSorts.Includes) > 0 {
+		return nil, ErrInvalidInclude{Message: "Includes are not supported by SQL repositories."}
+	}
+
+	if filtersAndSorts.Search != "" {
+		return nil, ErrSearchUnsupported
+	}
+
+	var err error
+
+	if where, args, err = r.buildGroupWhere(filtersAndSorts.Conditions(), filtersAndSorts.DeletedMode, args); err != nil {
+		return nil, err
+	}
+
+	if orderBy, err = r.buildOrderBy(filtersAndSorts.QueryParamsSortFields); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s%s%s", r.selectColumns(projectedFields(filtersAndSorts.Fields, nil)), quoteIdentifier(r.Table), where, orderBy)
+
+	if paginated {
+		if paginationParams.Page != 0 {
+			page = paginationParams.Page
+		}
+
+		if paginationParams.PerPage != 0 {
+			perPage = paginationParams.PerPage
+		}
+
+		query = fmt.Sprintf("%s LIMIT %d OFFSET %d", query, perPage, (page-1)*perPage)
+
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", quoteIdentifier(r.Table), where)
+
+		if err := r.executor().QueryRowContext(r.getContext(), countQuery, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+	} else if filtersAndSorts.Limit > 0 {
+		query = fmt.Sprintf("%s LIMIT %d", query, filtersAndSorts.Limit)
+	}
+
+	rows, err := r.executor().QueryContext(r.getContext(), query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	typedResults, err := r.scanRows(rows)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if typedResults == nil {
+		typedResults = []T{}
+	}
+
+	return &types.PaginatedRecords[T]{
+		Records:    typedResults,
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}, nil
+}
+
+func (r *SQLRepo[T]) GetByID(id any) (*T, error) {
+	return r.getByID(id, types.DeletedExclude)
+}
+
+func (r *SQLRepo[T]) GetByIDWithDeleted(id any) (*T, error) {
+	return r.getByID(id, types.DeletedWith)
+}
+
+func (r *SQLRepo[T]) getByID(id any, mode types.DeletedMode) (*T, error) {
+	return r.GetByIDWithOptions(id, types.GetByIDOptions{DeletedMode: mode})
+}
+
+func (r *SQLRepo[T]) UpdateByID(id any, data any) (*T, error) {
+	return r.updateByID(id, nil, data)
+}
+
+func (r *SQLRepo[T]) UpdateByIDIfVersion(id any, version int64, data any) (*T, error) {
+	return r.updateByID(id, &version, data)
+}
+
+func (r *SQLRepo[T]) updateByID(id any, expectedVersion *int64, data any) (*T, error) {
+	idString, err := sqlID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, parsedErr := bindBson(data)
+
+	if parsedErr != nil {
+		return nil, parsedErr
+	}
+
+	delete(parsed, "_id")
+
+	if r.UpdatedAt {
+		parsed["updatedAt"] = time.Now()
+	}
+
+	var (
+		assignments []string
+		args        []any
+		version     *int64
+	)
+
+	if r.Versioned {
+		if version, err = takeVersion(parsed, expectedVersion); err != nil {
+			return nil, err
+		}
+
+		assignments = append(assignments, fmt.Sprintf("%s = %s + 1", quoteIdentifier("version"), quoteIdentifier("version")))
+	}
+
+	for key, value := range parsed {
+		column, err := r.column(key)
+
+		if err != nil {
+			continue
+		}
+
+		arg, err := sqlValue(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg)
+		assignments = append(assignments, fmt.Sprintf("%s = %s", quoteIdentifier(column.Name), r.placeholder(len(args))))
+	}
+
+	if len(assignments) > 0 {
+		args = append(args, idString)
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", quoteIdentifier(r.Table), strings.Join(assignments, ", "), quoteIdentifier("_id"), r.placeholder(len(args)))
+
+		if version != nil {
+			args = append(args, *version)
+			query = fmt.Sprintf("%s AND %s = %s", query, quoteIdentifier("version"), r.placeholder(len(args)))
+		}
+
+		result, err := r.executor().ExecContext(r.getContext(), query, args...)
+
+		if err != nil {
+			return nil, sqlError(err)
+		}
+
+		if affected, err := result.RowsAffected(); err != nil {
+			return nil, err
+		} else if affected == 0 && version != nil {
+			if existing, err := r.GetByIDWithDeleted(idString); err != nil {
+				return nil, err
+			} else if existing != nil {
+				return nil, ErrVersionConflict
+			}
+		}
+	}
+
+	return r.GetByIDWithDeleted(idString)
+}
+
+func (r *SQLRepo[T]) DeleteByID(id any) (bool, error) {
+	idString, err := sqlID(id)
+
+	if err != nil {
+		return false, err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", quoteIdentifier(r.Table), quoteIdentifier("_id"), r.placeholder(1))
+	args := []any{idString}
+
+	if r.SoftDelete {
+		now := time.Now().UTC()
+		assignments := fmt.Sprintf("%s = %s", quoteIdentifier("deletedAt"), r.placeholder(1))
+		args = []any{now}
+
+		if r.stampsUpdatedAt() {
+			args = append(args, now)
+			assignments = fmt.Sprintf("%s, %s = %s", assignments, quoteIdentifier("updatedAt"), r.placeholder(len(args)))
+		}
+
+		args = append(args, idString)
+		query = fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s AND %s", quoteIdentifier(r.Table), assignments, quoteIdentifier("_id"), r.placeholder(len(args)), r.deletedCondition(types.DeletedExclude))
+	}
+
+	result, err := r.executor().ExecContext(r.getContext(), query, args...)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *SQLRepo[T]) Restore(id any) (*T, error) {
+	idString, err := sqlID(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := fmt.Sprintf("%s = NULL", quoteIdentifier("deletedAt"))
+	var args []any
+
+	if r.stampsUpdatedAt() {
+		args = append(args, time.Now().UTC())
+		assignments = fmt.Sprintf("%s, %s = %s", assignments, quoteIdentifier("updatedAt"), r.placeholder(len(args)))
+	}
+
+	args = append(args, idString)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s AND %s", quoteIdentifier(r.Table), assignments, quoteIdentifier("_id"), r.placeholder(len(args)), r.deletedCondition(types.DeletedOnly))
+
+	result, err := r.executor().ExecContext(r.getContext(), query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, nil
+	}
+
+	return r.GetByID(idString)
+}
+
+func (r *SQLRepo[T]) Purge(olderThan time.Duration) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s IS NOT NULL AND %s <= %s", quoteIdentifier(r.Table), quoteIdentifier("deletedAt"), quoteIdentifier("deletedAt"), r.placeholder(1))
+
+	result, err := r.executor().ExecContext(r.getContext(), query, time.Now().UTC().Add(-olderThan))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+var _ Repository[any] = (*SQLRepo[any])(nil)
+var _ SoftDeleteRepository[any] = (*SQLRepo[any])(nil)
+var _ VersionedRepository[any] = (*SQLRepo[any])(nil)
@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
+
+	_ "modernc.org/sqlite"
+)
+
+var sqliteTestDatabases int
+
+// SQLite backed notes table in its own in-memory database
+func newTestSQLNotes(t *testing.T, softDelete bool, versioned bool, notes ...testNote) (*SQLRepo[testNote], []*testNote) {
+	t.Helper()
+
+	sqliteTestDatabases++
+
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:/sql-repo-test-%d?vfs=memdb&_pragma=busy_timeout(1000)&_txlock=immediate", sqliteTestDatabases))
+
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	repo := &SQLRepo[testNote]{DB: types.AppDB{SQL: db, SQLDriver: "sqlite"}, Table: "notes", SoftDelete: softDelete, Versioned: versioned}
+
+	if err := repo.CreateTable(); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	var created []*testNote
+
+	for _, note := range notes {
+		record, err := repo.Create(note)
+
+		if err != nil {
+			t.Fatalf("create %s: %v", note.Title, err)
+		}
+
+		created = append(created, record)
+	}
+
+	return repo, created
+}
+
+func TestSQLRepoQuery(t *testing.T) {
+	repo, _ := newTestSQLNotes(t, false, false,
+		testNote{Title: "Groceries", Priority: 1},
+		testNote{Title: "Gym", Priority: 3},
+		testNote{Title: "Taxes 100%", Priority: 5},
+		testNote{Title: "Garden_2", Priority: 2},
+		testNote{Title: "Garden 2", Priority: 4},
+	)
+
+	tests := []struct {
+		name  string
+		query *QueryBuilder[testNote]
+		want  []string
+	}{
+		{"eq", Query[testNote]().Where("title", Eq, "Gym"), []string{"Gym"}},
+		{"gte and lte", Query[testNote]().Where("priority", Gte, 2).Where("priority", Lte, 3).Sort("priority"), []string{"Garden_2", "Gym"}},
+		{"like is case insensitive", Query[testNote]().Where("title", Like, "GAR").Sort("title"), []string{"Garden 2", "Garden_2"}},
+		{"start", Query[testNote]().Where("title", Start, "g").Sort("-priority"), []string{"Garden 2", "Gym", "Garden_2", "Groceries"}},
+		{"end", Query[testNote]().Where("title", End, "es"), []string{"Groceries"}},
+		{"percent matches literally", Query[testNote]().Where("title", Like, "%"), []string{"Taxes 100%"}},
+		{"underscore matches literally", Query[testNote]().Where("title", Like, "n_2"), []string{"Garden_2"}},
+		{"or group", Query[testNote]().Or(Where("title", Eq, "Gym"), Where("priority", Gte, 5)).Sort("title"), []string{"Gym", "Taxes 100%"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notes, err := test.query.All(repo)
+
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+
+			if got := noteTitles(notes); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSQLRepoSoftDelete(t *testing.T) {
+	repo, notes := newTestSQLNotes(t, true, false, testNote{Title: "kept"}, testNote{Title: "trashed"})
+	trashed := notes[1]
+
+	if deleted, err := repo.DeleteByID(trashed.ID); err != nil || !deleted {
+		t.Fatalf("delete: %v %v", deleted, err)
+	}
+
+	tests := []struct {
+		mode types.DeletedMode
+		want []string
+	}{
+		{types.DeletedExclude, []string{"kept"}},
+		{types.DeletedWith, []string{"kept", "trashed"}},
+		{types.DeletedOnly, []string{"trashed"}},
+	}
+
+	for _, test := range tests {
+		notes, err := Query[testNote]().Deleted(test.mode).Sort("title").All(repo)
+
+		if err != nil {
+			t.Fatalf("mode %q: %v", test.mode, err)
+		}
+
+		if got := noteTitles(notes); !slices.Equal(got, test.want) {
+			t.Errorf("mode %q: got %v, want %v", test.mode, got, test.want)
+		}
+	}
+
+	if restored, err := repo.Restore(trashed.ID); err != nil || restored == nil || restored.DeletedAt != nil {
+		t.Fatalf("restore: got %v %v, want restored record", restored, err)
+	}
+
+	if purged, err := repo.Purge(0); err != nil || purged != 0 {
+		t.Errorf("purge after restore: got %d %v, want 0", purged, err)
+	}
+}
+
+func TestSQLRepoVersionConflict(t *testing.T) {
+	repo, notes := newTestSQLNotes(t, false, true, testNote{Title: "old"})
+
+	if notes[0].Version != 1 {
+		t.Fatalf("created version: got %d, want 1", notes[0].Version)
+	}
+
+	if _, err := repo.UpdateByIDIfVersion(notes[0].ID, 0, map[string]any{"title": "stale"}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("stale version: got error %v, want %v", err, ErrVersionConflict)
+	}
+
+	updated, err := repo.UpdateByIDIfVersion(notes[0].ID, 1, map[string]any{"title": "new"})
+
+	if err != nil || updated.Title != "new" || updated.Version != 2 {
+		t.Errorf("current version: got %v %v, want new title at version 2", updated, err)
+	}
+}
+
+func TestSQLRepoSyncIndexes(t *testing.T) {
+	repo, _ := newTestSQLNotes(t, false, false, testNote{Title: "a"})
+	repo.Indexes = []types.IndexSpec{{Fields: []string{"title"}, Unique: true}, {Fields: []string{"title"}, Text: true}}
+
+	if err := repo.SyncIndexes(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	var indexes []string
+
+	rows, err := repo.DB.SQL.Query("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'notes' AND sql IS NOT NULL")
+
+	if err != nil {
+		t.Fatalf("list indexes: %v", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		indexes = append(indexes, name)
+	}
+
+	if want := []string{"notes_title"}; !slices.Equal(indexes, want) {
+		t.Errorf("got indexes %v, want %v", indexes, want)
+	}
+
+	var conflict ErrConflict
+
+	if _, err := repo.Create(testNote{Title: "a"}); !errors.As(err, &conflict) || conflict.Field != "title" {
+		t.Errorf("duplicate title: got error %v, want conflict on title", err)
+	}
+}
+
+func TestSQLRepoTransaction(t *testing.T) {
+	errRollback := errors.New("rollback")
+
+	tests := []struct {
+		name  string
+		fnErr error
+		want  int64
+	}{
+		{"commit", nil, 2},
+		{"rollback", errRollback, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo, _ := newTestSQLNotes(t, false, false)
+
+			tx, err := repo.DB.SQL.BeginTx(context.Background(), nil)
+
+			if err != nil {
+				t.Fatalf("begin: %v", err)
+			}
+
+			txRepo := repo.WithContext(ContextWithSQLTx(context.Background(), tx))
+
+			for _, title := range []string{"a", "b"} {
+				if _, err := txRepo.Create(testNote{Title: title}); err != nil {
+					t.Fatalf("create %s: %v", title, err)
+				}
+			}
+
+			if count, err := txRepo.Count(nil); err != nil || count != 2 {
+				t.Errorf("count within transaction: got %d %v, want 2", count, err)
+			}
+
+			if test.fnErr != nil {
+				tx.Rollback()
+			} else if err := tx.Commit(); err != nil {
+				t.Fatalf("commit: %v", err)
+			}
+
+			if count, err := repo.Count(nil); err != nil || count != test.want {
+				t.Errorf("got count %d %v, want %d", count, err, test.want)
+			}
+		})
+	}
+}
@@ -4,9 +4,6 @@ import (
 	"context"
 
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
-
-	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 type UserRepo[T any] struct {
@@ -24,22 +21,10 @@ func NewUserRepo[T any](DB types.AppDB, collection string) *UserRepo[T] {
 	}
 }
 
-func (up *UserRepo[T]) GetUserByUsernameOrEmail(usernameOrEmail string) (*T, error) {
-	filters := bson.D{{
-		Key: "$or", Value: bson.A{
-			bson.D{{Key: "username", Value: usernameOrEmail}}, bson.D{{Key: "email", Value: usernameOrEmail}},
-		},
-	}}
-
-	var result T
-
-	if err := up.DB.MongoDB.Collection(up.Collection).FindOne(context.TODO(), filters).Decode(&result); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		} else {
-			return nil, err
-		}
-	}
+func (up *UserRepo[T]) WithContext(ctx context.Context) *UserRepo[T] {
+	return &UserRepo[T]{BaseRepo: up.BaseRepo.WithContext(ctx)}
+}
 
-	return &result, nil
+func (up *UserRepo[T]) GetUserByUsernameOrEmail(usernameOrEmail string) (*T, error) {
+	return Query[T]().Or(Where("username", Eq, usernameOrEmail), Where("email", Eq, usernameOrEmail)).First(up)
 }
@@ -0,0 +1,175 @@
+package seeders
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/services"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prepare fixture record before insert, keyed by collection name
+type Transformer func(record map[string]any) error
+
+var transformers = map[string]Transformer{
+	"users": hashPassword,
+}
+
+type insertFunc func(record map[string]any) error
+
+// Typed repo factories keyed by collection, collections without model insert as map (MongoDB and memory only)
+var fixtureModels = map[string]func(appExternals *externals.AllAppExternals, collection string) (insertFunc, error){}
+
+func init() {
+	RegisterModel[models.User]("users")
+}
+
+// Insert fixtures of collection through repo typed by T, required by SQL backend which maps struct fields to columns
+func RegisterModel[T any](collection string) {
+	fixtureModels[collection] = newInsertFunc[T]
+}
+
+func newInsertFunc[T any](appExternals *externals.AllAppExternals, collection string) (insertFunc, error) {
+	// Resolves MongoDB, SQL or memory backend the same way generated resources do
+	collectionRepo, err := repo.NewResourceRepo[T](externals.ResourceDB(appExternals), collection, repo.ResourceRepoOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(record map[string]any) error {
+		_, err := collectionRepo.Create(record)
+		return err
+	}, nil
+}
+
+// Register transformer for collection, replacing existing one e.g: the default users password hashing
+func RegisterTransformer(collection string, transformer Transformer) {
+	transformers[collection] = transformer
+}
+
+// Hash plain password of users fixture through the same argon2 path as registration
+func hashPassword(record map[string]any) error {
+	password, ok := record["password"].(string)
+
+	if !ok || password == "" {
+		return nil
+	}
+
+	hashed, err := services.HashPassword(password)
+
+	if err != nil {
+		return err
+	}
+
+	record["password"] = hashed
+
+	return nil
+}
+
+// Insert records from .json/.yaml/.yml files in dir (sorted by file name), each file maps collection name to records e.g:
+//
+//	users:
+//	  - username: demo
+//	    password: secret
+func LoadFixtures(appExternals *externals.AllAppExternals, dir string) error {
+	if err := CheckEnv(false); err != nil {
+		return err
+	}
+
+	return loadFixtures(appExternals, dir)
+}
+
+func loadFixtures(appExternals *externals.AllAppExternals, dir string) error {
+	entries, err := os.ReadDir(dir)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var files []string
+
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	sort.Strings(files)
+
+	for _, file := range files {
+		if err := loadFixtureFile(appExternals, file); err != nil {
+			return fmt.Errorf("fixture %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+func loadFixtureFile(appExternals *externals.AllAppExternals, file string) error {
+	content, err := os.ReadFile(file)
+
+	if err != nil {
+		return err
+	}
+
+	var fixture map[string][]map[string]any
+
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		err = json.Unmarshal(content, &fixture)
+	} else {
+		err = yaml.Unmarshal(content, &fixture)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	collections := make([]string, 0, len(fixture))
+	for collection := range fixture {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		newInsert, ok := fixtureModels[collection]
+		if !ok {
+			newInsert = newInsertFunc[map[string]any]
+		}
+
+		insert, err := newInsert(appExternals, collection)
+
+		if err != nil {
+			return err
+		}
+
+		for index, record := range fixture[collection] {
+			if transformer, ok := transformers[collection]; ok {
+				if err := transformer(record); err != nil {
+					return fmt.Errorf("%s[%d]: %w", collection, index, err)
+				}
+			}
+
+			if err := insert(record); err != nil {
+				return fmt.Errorf("%s[%d]: %w", collection, index, err)
+			}
+		}
+	}
+
+	return nil
+}
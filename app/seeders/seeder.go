@@ -0,0 +1,95 @@
+package seeders
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/utils"
+)
+
+// Environment names where seeding is allowed, matched against APP_ENV case insensitively, unset APP_ENV is refused
+var AllowedEnvs = []string{"development", "dev", "test", "local"}
+
+// Environment names where seeding is refused even with force, matched like AllowedEnvs
+var ProductionEnvs = []string{"production", "prod"}
+
+// Returned when seeding outside allowed environments without force
+var ErrProtectedEnv = fmt.Errorf("seeding is only allowed when APP_ENV is development, test or local, use --force to seed anyway")
+
+// Returned when seeding in a production environment, force does not apply
+var ErrProductionEnv = fmt.Errorf("seeding is never allowed when APP_ENV is production")
+
+type Seeder struct {
+	Name string                                                                   // Unique seeder name e.g: demo_notes
+	Run  func(ctx context.Context, appExternals *externals.AllAppExternals) error // Seeding logic, use repos to insert data
+}
+
+var registered []Seeder
+
+// Register Go seeder, seeders run in registration order, usually called from init()
+func Register(seeder Seeder) {
+	for _, existing := range registered {
+		if existing.Name == seeder.Name {
+			panic(fmt.Sprintf("seeder %s already registered", seeder.Name))
+		}
+	}
+
+	registered = append(registered, seeder)
+}
+
+// Registered seeders in registration order
+func Registered() []Seeder {
+	return append([]Seeder(nil), registered...)
+}
+
+// Fail with ErrProductionEnv in production, otherwise with ErrProtectedEnv unless APP_ENV is an allowed environment or force is set
+func CheckEnv(force bool) error {
+	env := strings.ToLower(strings.TrimSpace(utils.GetAppConfig("APP_ENV")))
+
+	if contains(ProductionEnvs, env) {
+		return ErrProductionEnv
+	}
+
+	if force || contains(AllowedEnvs, env) {
+		return nil
+	}
+
+	return ErrProtectedEnv
+}
+
+// Load fixtures from fixturesDir (skipped when empty or missing) then run registered seeders, names limits seeders to run, force skips the APP_ENV check except for production
+func Run(ctx context.Context, appExternals *externals.AllAppExternals, fixturesDir string, force bool, names ...string) error {
+	if err := CheckEnv(force); err != nil {
+		return err
+	}
+
+	if fixturesDir != "" {
+		if err := loadFixtures(appExternals, fixturesDir); err != nil {
+			return err
+		}
+	}
+
+	for _, seeder := range registered {
+		if len(names) > 0 && !contains(names, seeder.Name) {
+			continue
+		}
+
+		if err := seeder.Run(ctx, appExternals); err != nil {
+			return fmt.Errorf("seeder %s: %w", seeder.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,145 @@
+package seeders
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
+)
+
+func TestCheckEnv(t *testing.T) {
+	tests := []struct {
+		env     string
+		force   bool
+		wantErr error
+	}{
+		{"development", false, nil},
+		{"Test", false, nil},
+		{"", false, ErrProtectedEnv},
+		{"staging", false, ErrProtectedEnv},
+		{"staging", true, nil},
+		{"production", false, ErrProductionEnv},
+		{"production", true, ErrProductionEnv},
+		{" PROD ", true, ErrProductionEnv},
+	}
+
+	for _, test := range tests {
+		t.Run(test.env, func(t *testing.T) {
+			t.Setenv("APP_ENV", test.env)
+
+			if err := CheckEnv(test.force); !errors.Is(err, test.wantErr) {
+				t.Errorf("force %v: got %v, want %v", test.force, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func newTestExternals(t *testing.T) *externals.AllAppExternals {
+	t.Helper()
+
+	allExternals, err := externals.RegisterExternals([]externals.BaseExternal{externals.NewMemoryExternal()})
+
+	if err != nil {
+		t.Fatalf("register externals: %v", err)
+	}
+
+	return allExternals
+}
+
+func TestLoadFixtures(t *testing.T) {
+	t.Setenv("APP_ENV", "test")
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"01_users.yaml": "users:\n  - username: demo\n    email: demo@example.com\n    password: secret\n",
+		"02_notes.json": `{"notes": [{"title": "first"}, {"title": "second"}]}`,
+		"readme.txt":    "not a fixture",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	allExternals := newTestExternals(t)
+
+	if err := LoadFixtures(allExternals, dir); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	users, err := repo.NewResourceRepo[models.User](externals.ResourceDB(allExternals), "users", repo.ResourceRepoOptions{})
+
+	if err != nil {
+		t.Fatalf("users repo: %v", err)
+	}
+
+	user, err := repo.Query[models.User]().Where("username", repo.Eq, "demo").First(users)
+
+	if err != nil || user == nil {
+		t.Fatalf("get user: got %v %v, want demo user", user, err)
+	}
+
+	if user.Password == "secret" || !strings.Contains(user.Password, "$") {
+		t.Errorf("got password %q, want salted hash", user.Password)
+	}
+
+	notes, err := repo.NewResourceRepo[map[string]any](externals.ResourceDB(allExternals), "notes", repo.ResourceRepoOptions{})
+
+	if err != nil {
+		t.Fatalf("notes repo: %v", err)
+	}
+
+	if all, err := repo.Query[map[string]any]().All(notes); err != nil || len(all) != 2 {
+		t.Errorf("got %d notes %v, want 2", len(all), err)
+	}
+
+	if err := LoadFixtures(allExternals, filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("missing dir: got %v, want nil", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("APP_ENV", "test")
+
+	previous := registered
+	t.Cleanup(func() { registered = previous })
+
+	var ran []string
+
+	registered = nil
+
+	for _, name := range []string{"first", "second", "third"} {
+		Register(Seeder{Name: name, Run: func(ctx context.Context, appExternals *externals.AllAppExternals) error {
+			ran = append(ran, name)
+			return nil
+		}})
+	}
+
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{nil, []string{"first", "second", "third"}},
+		{[]string{"third", "first"}, []string{"first", "third"}},
+	}
+
+	for _, test := range tests {
+		ran = nil
+
+		if err := Run(context.Background(), newTestExternals(t), "", false, test.names...); err != nil {
+			t.Fatalf("run %v: %v", test.names, err)
+		}
+
+		if !slices.Equal(ran, test.want) {
+			t.Errorf("names %v: got %v, want %v", test.names, ran, test.want)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
 	"time"
 
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/externals"
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/jobs"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/repo"
 	"github.com/ahmadfirdaus06/go-boilerplate-app/app/types"
@@ -18,6 +20,7 @@ import (
 
 type AuthService struct {
 	UserRepo *repo.UserRepo[models.User]
+	Jobs     *jobs.Queue // Nil when no job queue is registered
 }
 
 var JwtSecret = "Abcd1234"
@@ -30,8 +33,11 @@ func NewAuthService(appExternals *externals.AllAppExternals) *AuthService {
 		return nil
 	}
 
+	queue, _ := externals.GetExternal[*jobs.Queue](appExternals)
+
 	return &AuthService{
 		UserRepo: repo.NewUserRepo[models.User](types.AppDB{MongoDB: mongoExt.DB}, "users"),
+		Jobs:     queue,
 	}
 }
 
@@ -79,11 +85,40 @@ func (as *AuthService) SendVerificationCode(user *models.User) (*time.Time, erro
 		return nil, updateErr
 	}
 
-	//TODO: send email using queue
+	if as.Jobs == nil {
+		log.Printf("No job queue registered, verification code of %s is not sent.", user.Email)
+	} else if _, err := as.Jobs.Enqueue(context.Background(), SendVerificationEmailJob, VerificationEmail{Email: user.Email, Username: user.Username, Code: code}); err != nil {
+		return nil, err
+	}
 
 	return updatedUser.EmailVerificationCodeExpiredAt, nil
 }
 
+const SendVerificationEmailJob = "auth.send-verification-email"
+
+type VerificationEmail struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Code     string `json:"code"`
+}
+
+// Deliver email, replace with SMTP or email provider client, default only logs it
+var SendMail = func(ctx context.Context, to string, subject string, body string) error {
+	log.Printf("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+func init() {
+	jobs.Register(jobs.Definition{
+		Name: SendVerificationEmailJob,
+		Handler: jobs.Typed(func(ctx context.Context, payload VerificationEmail) error {
+			body := fmt.Sprintf("Hi %s,\n\nYour verification code is %s.", payload.Username, payload.Code)
+
+			return SendMail(ctx, payload.Email, "Verify your email", body)
+		}),
+	})
+}
+
 func generateVerificationCode() string {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	code := r.Intn(900000) + 100000
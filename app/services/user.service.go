@@ -5,6 +5,7 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -37,6 +38,13 @@ func NewUserService(appExternals *externals.AllAppExternals) *UserService {
 	}
 }
 
+// Register unique indexes of users to be created by repo.SyncAllIndexes, called once at boot instead of per repo
+func RegisterUserIndexes(appExternals *externals.AllAppExternals) {
+	if mongoExt, err := externals.GetExternal[*externals.MongoDBExternal](appExternals); err == nil {
+		repo.RegisterIndexes("users", repo.NewUserRepo[models.User](types.AppDB{MongoDB: mongoExt.DB}, "users"))
+	}
+}
+
 func (s *UserService) RegisterUser(inputs any) (any, error) {
 	data, err := json.Marshal(inputs)
 	if err != nil {
@@ -48,31 +56,11 @@ func (s *UserService) RegisterUser(inputs any) (any, error) {
 		return nil, err
 	}
 
-	if usersWithExistingUsername, err := s.UserRepo.GetAll(false, &types.GetAllFiltersAndSorts{
-		QueryParamsFilters: []types.QueryParamsFilter{
-			{Field: "username", Operator: types.OpEq, Value: raw["username"].(string)},
-		},
-	}, nil); err != nil {
-		return nil, err
-	} else if len(usersWithExistingUsername.Records) > 0 {
-		return nil, echo.NewHTTPError(400, "Username already taken.")
-	}
-
-	if usersWithExistingEmail, err := s.UserRepo.GetAll(false, &types.GetAllFiltersAndSorts{
-		QueryParamsFilters: []types.QueryParamsFilter{
-			{Field: "email", Operator: types.OpEq, Value: raw["email"].(string)},
-		},
-	}, nil); err != nil {
-		return nil, err
-	} else if len(usersWithExistingEmail.Records) > 0 {
-		return nil, echo.NewHTTPError(400, "Email already taken.")
-	}
-
 	delete(raw, "confirmPassword")
 
 	raw["emailVerifiedAt"] = nil
 
-	hashedPassword, hashPasswordErr := generateHash(raw["password"].(string), nil)
+	hashedPassword, hashPasswordErr := HashPassword(raw["password"].(string))
 
 	if hashPasswordErr != nil {
 		return nil, hashPasswordErr
@@ -80,8 +68,34 @@ func (s *UserService) RegisterUser(inputs any) (any, error) {
 
 	raw["password"] = hashedPassword
 
+	if exists, err := s.UserRepo.Exists([]types.QueryParamsFilter{
+		{Field: "username", Operator: types.OpEq, Value: raw["username"].(string)},
+	}); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, echo.NewHTTPError(400, "Username already taken.")
+	}
+
+	if exists, err := s.UserRepo.Exists([]types.QueryParamsFilter{
+		{Field: "email", Operator: types.OpEq, Value: raw["email"].(string)},
+	}); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, echo.NewHTTPError(400, "Email already taken.")
+	}
+
 	created, createErr := s.UserRepo.Create(raw)
 
+	// Unique indexes of username and email catch concurrent registrations passing the checks above, no transaction needed
+	if conflict := (repo.ErrConflict{}); errors.As(createErr, &conflict) {
+		switch conflict.Field {
+		case "username":
+			return nil, echo.NewHTTPError(400, "Username already taken.")
+		case "email":
+			return nil, echo.NewHTTPError(400, "Email already taken.")
+		}
+	}
+
 	if createErr != nil {
 		return nil, createErr
 	}
@@ -105,6 +119,11 @@ var DefaultParams = &ArgonParams{
 	KeyLength:   32,
 }
 
+// Hash password with DefaultParams, same format stored on registration
+func HashPassword(password string) (string, error) {
+	return generateHash(password, nil)
+}
+
 func generateHash(password string, p *ArgonParams) (string, error) {
 	if p == nil {
 		p = DefaultParams
@@ -1,6 +1,9 @@
 package types
 
 import (
+	"database/sql"
+	"time"
+
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
@@ -10,7 +13,17 @@ type RepoTimestampConfig struct {
 }
 
 type AppDB struct {
-	MongoDB *mongo.Database
+	MongoDB   *mongo.Database
+	SQL       *sql.DB // Used by SQL based repo
+	SQLDriver string  // database/sql driver name of SQL, decides placeholder and column type dialect
+}
+
+type IndexSpec struct {
+	Name        string        // Index name, generated from fields when empty
+	Fields      []string      // Indexed fields in order, prefix with "-" for descending e.g: []string{"userId", "-createdAt"}
+	Unique      bool          // Reject duplicate values, violations are returned as repo.ErrConflict
+	Text        bool          // Full-text index over all Fields
+	ExpireAfter time.Duration // TTL index, documents are removed once the date field is older than this, MongoDB only
 }
 
 type QueryParamsFilterOp string
@@ -27,7 +40,21 @@ const (
 type QueryParamsFilter struct {
 	Field    string
 	Operator QueryParamsFilterOp
-	Value    string
+	Value    any // String when parsed from query params, typed when built from Go code
+}
+
+type QueryLogic string
+
+const (
+	LogicAnd QueryLogic = "and"
+	LogicOr  QueryLogic = "or"
+)
+
+// Nested filters, every filter and sub group is combined using Logic
+type QueryGroup struct {
+	Logic   QueryLogic // Default is and
+	Filters []QueryParamsFilter
+	Groups  []QueryGroup
 }
 
 type QueryParamsSortField struct {
@@ -40,9 +67,117 @@ type PaginationParams struct {
 	PerPage int
 }
 
+type DeletedMode string
+
+const (
+	DeletedExclude DeletedMode = ""     // Exclude soft deleted records, default
+	DeletedWith    DeletedMode = "with" // Include soft deleted records
+	DeletedOnly    DeletedMode = "only" // Only soft deleted records (trash)
+)
+
+type PatchPush struct {
+	Values   []any // Values inserted into the array
+	Position *int  // Insert position, appended at the end when nil
+}
+
+// Field level changes of a patch, paths are dotted e.g: "address.city" or "tags.0"
+type PatchUpdate struct {
+	Set   map[string]any       // Set path to value
+	Unset []string             // Remove path
+	Push  map[string]PatchPush // Insert values into array at path
+	Pull  map[string][]any     // Remove every array element equal to one of the values
+}
+
+type RelationKind string
+
+const (
+	BelongsTo RelationKind = "belongsTo" // Record holds related id in Field e.g: notes.authorId -> users._id
+	HasMany   RelationKind = "hasMany"   // Related records hold this record id in Field e.g: comments.noteId -> notes._id
+)
+
+// Relation loaded with includes, model of T should declare a field named after it to receive the related record(s)
+type Relation struct {
+	Name       string // Include name and field the related record(s) are loaded into e.g: "author"
+	Kind       RelationKind
+	Collection string // Related collection / table
+	Field      string // Foreign key field, on this record for BelongsTo, on related records for HasMany
+}
+
 type GetAllFiltersAndSorts struct {
 	QueryParamsFilters    []QueryParamsFilter
+	QueryGroups           []QueryGroup // Nested and/or filters combined with QueryParamsFilters using and, see repo.Query
 	QueryParamsSortFields []QueryParamsSortField
+	DeletedMode           DeletedMode // Soft deleted records visibility, only applied when repo soft delete is enabled
+	Limit                 int         // Maximum records returned when not paginated, default is no limit
+	Includes              []string    // Relations to eager load, dotted for nested e.g: "comments.author", see repo.RegisterRelations
+	Fields                []string    // Top level fields to return, _id and included relations are always returned, default is every field
+	Search                string      // Full-text query over text index fields, records matching any term are returned with "score" field and sort field "score" orders by relevance
+}
+
+// Options of single record lookup, see GetAllFiltersAndSorts
+type GetByIDOptions struct {
+	DeletedMode DeletedMode
+	Includes    []string
+	Fields      []string
+}
+
+// Filters and groups as a single and group
+func (f *GetAllFiltersAndSorts) Conditions() QueryGroup {
+	return QueryGroup{Logic: LogicAnd, Filters: f.QueryParamsFilters, Groups: f.QueryGroups}
+}
+
+type AggregateOp string
+
+const (
+	AggCount AggregateOp = "count"
+	AggSum   AggregateOp = "sum"
+	AggAvg   AggregateOp = "avg"
+	AggMin   AggregateOp = "min"
+	AggMax   AggregateOp = "max"
+)
+
+type AggregateMetric struct {
+	Op    AggregateOp
+	Field string // Empty for count
+}
+
+// Result key of metric e.g: "count" or "sum_amount"
+func (m AggregateMetric) Key() string {
+	if m.Op == AggCount {
+		return string(m.Op)
+	}
+
+	return string(m.Op) + "_" + m.Field
+}
+
+type AggregateInterval struct {
+	Unit  string // hour, day, week (starting sunday), month or year
+	Field string // Date field truncated to unit, returned as "period"
+}
+
+type AggregateQuery struct {
+	GroupBy  []string
+	Metrics  []AggregateMetric
+	Interval *AggregateInterval
+	Limit    int // Maximum groups returned, default is no limit
+}
+
+type ChangeOp string
+
+const (
+	ChangeCreated  ChangeOp = "created"
+	ChangeUpdated  ChangeOp = "updated"
+	ChangeDeleted  ChangeOp = "deleted"
+	ChangeRestored ChangeOp = "restored"
+)
+
+// Write made to a record, see repo.PublishChange
+type ChangeEvent struct {
+	Collection string
+	Op         ChangeOp
+	ID         string
+	Record     any            // Record after the change, record before hard deletes when known otherwise nil
+	Document   map[string]any // Record as stored document, matched against parent and scope filters, nil when Record is nil
 }
 
 type PaginatedRecords[T any] struct {
@@ -0,0 +1,148 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Broadcast relayed between hub replicas
+type BackplaneMessage struct {
+	ID      string          `json:"id"`               // Unique message id, deliveries seen before are ignored
+	Origin  string          `json:"origin"`           // Id of publishing hub, hubs ignore their own messages
+	UserID  string          `json:"userId,omitempty"` // Set for EmitToUser, otherwise delivered to namespace / room of message
+	Message WSMessage       `json:"message"`
+	Meta    json.RawMessage `json:"meta,omitempty"` // Meta of Message, not part of its JSON
+}
+
+// Pub/sub shared by hub replicas so broadcasts reach clients connected to any of them
+type Backplane interface {
+	Publish(ctx context.Context, msg BackplaneMessage) error
+	Subscribe(ctx context.Context, handler func(msg BackplaneMessage)) error // Start delivering messages of every replica to handler until ctx is done
+}
+
+func randomID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+
+	return hex.EncodeToString(bytes)
+}
+
+// Publish message of this replica, failures only cost other replicas the message
+func (h *Hub) relay(msg BackplaneMessage) {
+	if h.config.Backplane == nil {
+		return
+	}
+
+	msg.ID = randomID()
+	msg.Origin = h.origin
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.WriteTimeout)
+	defer cancel()
+
+	if err := h.config.Backplane.Publish(ctx, msg); err != nil {
+		log.Println("backplane publish error:", err)
+	}
+}
+
+// Deliver message of another replica to clients of this one
+func (h *Hub) receive(msg BackplaneMessage) {
+	if msg.Origin == h.origin || !h.seen.add(msg.ID) {
+		return
+	}
+
+	if msg.UserID != "" {
+		h.deliverToUser(msg.UserID, msg.Message)
+		return
+	}
+
+	msg.Message.Meta = msg.Meta
+
+	h.deliver(msg.Message, nil)
+}
+
+// Bounded set of recently seen message ids, oldest are forgotten first
+type seenMessages struct {
+	ids   map[string]bool
+	order []string
+	next  int
+	mutex sync.Mutex
+}
+
+func newSeenMessages(size int) *seenMessages {
+	return &seenMessages{ids: make(map[string]bool, size), order: make([]string, size)}
+}
+
+// Remember id, false when already seen
+func (s *seenMessages) add(id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.ids[id] {
+		return false
+	}
+
+	delete(s.ids, s.order[s.next])
+	s.order[s.next] = id
+	s.next = (s.next + 1) % len(s.order)
+	s.ids[id] = true
+
+	return true
+}
+
+// In-process backplane, relays between hubs of the same process e.g: tests
+type MemoryBackplane struct {
+	subscribers map[*memorySubscriber]bool
+	mutex       sync.RWMutex
+}
+
+type memorySubscriber struct {
+	handler func(msg BackplaneMessage)
+}
+
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{subscribers: make(map[*memorySubscriber]bool)}
+}
+
+func (b *MemoryBackplane) Publish(ctx context.Context, msg BackplaneMessage) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for subscriber := range b.subscribers {
+		subscriber.handler(msg)
+	}
+
+	return nil
+}
+
+func (b *MemoryBackplane) Subscribe(ctx context.Context, handler func(msg BackplaneMessage)) error {
+	subscriber := &memorySubscriber{handler: handler}
+
+	b.mutex.Lock()
+	b.subscribers[subscriber] = true
+	b.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mutex.Lock()
+		delete(b.subscribers, subscriber)
+		b.mutex.Unlock()
+	}()
+
+	return nil
+}
+
+// Wait before retrying a lost subscription, false when ctx is done meanwhile
+func backoff(ctx context.Context, delay time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// In-process subscriber of hub namespace receiving broadcasts accepted by filter
+func addTestClient(hub *Hub, namespace string, filter func(msg WSMessage) bool) *Client {
+	client := &Client{hub: hub, namespace: namespace, rooms: map[string]bool{}, send: make(chan WSMessage, 10), readOnly: true, filter: filter, done: make(chan struct{})}
+
+	hub.mutex.Lock()
+	hub.addClient(client)
+	hub.mutex.Unlock()
+
+	return client
+}
+
+// Events read from conn up to and including last, presence events are skipped
+func eventsUntil(t *testing.T, conn *websocket.Conn, last string) []string {
+	t.Helper()
+
+	var events []string
+
+	for len(events) == 0 || events[len(events)-1] != last {
+		if msg := readTest(t, conn); msg.Event != PresenceJoinEvent && msg.Event != PresenceLeaveEvent {
+			events = append(events, msg.Event)
+		}
+	}
+
+	return events
+}
+
+func TestHubBackplane(t *testing.T) {
+	backplane := NewMemoryBackplane()
+
+	first, firstServer := newTestHub(t, HubConfig{Backplane: backplane})
+	second, secondServer := newTestHub(t, HubConfig{Backplane: backplane})
+
+	local := dialTest(t, firstServer, "/ws/chat", "alice")
+	remote := dialTest(t, secondServer, "/ws/chat", "bob")
+	waitClients(t, first, "chat", 1)
+	waitClients(t, second, "chat", 1)
+
+	first.Emit("chat", "", "news", nil)
+	first.EmitToUser(testUser("bob").ID.Hex(), "notice", nil)
+	first.Emit("chat", "", "after", nil)
+
+	tests := []struct {
+		name       string
+		conn       *websocket.Conn
+		wantEvents []string
+	}{
+		{"client of publishing replica", local, []string{"news", "after"}},
+		{"client of other replica", remote, []string{"news", "notice", "after"}},
+	}
+
+	for _, test := range tests {
+		if got := eventsUntil(t, test.conn, "after"); fmt.Sprint(got) != fmt.Sprint(test.wantEvents) {
+			t.Errorf("%s: got events %v, want %v", test.name, got, test.wantEvents)
+		}
+	}
+}
+
+func TestHubBackplaneMeta(t *testing.T) {
+	backplane := NewMemoryBackplane()
+
+	first, _ := newTestHub(t, HubConfig{Backplane: backplane})
+	second, _ := newTestHub(t, HubConfig{Backplane: backplane})
+
+	// Filters of other replicas decide on the meta of the original message
+	subscriber := addTestClient(second, "notes", func(msg WSMessage) bool {
+		return string(msg.Meta) == `{"owner":"alice"}`
+	})
+
+	first.Broadcast(WSMessage{Namespace: "notes", Event: "hidden", Meta: json.RawMessage(`{"owner":"bob"}`)})
+	first.Broadcast(WSMessage{Namespace: "notes", Event: "shown", Meta: json.RawMessage(`{"owner":"alice"}`)})
+
+	select {
+	case msg := <-subscriber.send:
+		if msg.Event != "shown" {
+			t.Errorf("got %s, want shown", msg.Event)
+		}
+	default:
+		t.Fatal("nothing relayed to subscriber")
+	}
+
+	if len(subscriber.send) != 0 {
+		t.Errorf("got %d more messages, want none", len(subscriber.send))
+	}
+}
+
+func TestHubReceive(t *testing.T) {
+	hub := NewHub(HubConfig{})
+	client := addTestClient(hub, "chat", nil)
+
+	messages := []BackplaneMessage{
+		{ID: "1", Origin: "other", Message: WSMessage{Namespace: "chat", Event: "first"}},
+		{ID: "1", Origin: "other", Message: WSMessage{Namespace: "chat", Event: "redelivered"}},
+		{ID: "2", Origin: hub.origin, Message: WSMessage{Namespace: "chat", Event: "own"}},
+		{ID: "3", Origin: "other", Message: WSMessage{Namespace: "chat", Event: "second"}},
+	}
+
+	for _, msg := range messages {
+		hub.receive(msg)
+	}
+
+	var events []string
+	for len(client.send) > 0 {
+		events = append(events, (<-client.send).Event)
+	}
+
+	if want := "[first second]"; fmt.Sprint(events) != want {
+		t.Errorf("got events %v, want %s", events, want)
+	}
+}
+
+func TestSeenMessages(t *testing.T) {
+	seen := newSeenMessages(2)
+
+	steps := []struct {
+		id   string
+		want bool
+	}{
+		{"a", true},
+		{"a", false},
+		{"b", true},
+		{"c", true}, // Forgets a
+		{"b", false},
+		{"a", true},
+	}
+
+	for i, step := range steps {
+		if got := seen.add(step.id); got != step.want {
+			t.Errorf("step %d add %s: got %v, want %v", i, step.id, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Backplane over MongoDB capped collection read with tailable cursor, needs no infrastructure besides the app database
+type MongoBackplane struct {
+	DB         *mongo.Database
+	Collection string
+	SizeBytes  int64
+}
+
+type mongoBackplaneDoc struct {
+	ID      bson.ObjectID    `bson:"_id"`
+	Message BackplaneMessage `bson:"message"`
+}
+
+// Capped collection is created when missing, default name is "websocket_backplane" of 16MB
+func NewMongoBackplane(db *mongo.Database, collection string, sizeBytes int64) *MongoBackplane {
+	if collection == "" {
+		collection = "websocket_backplane"
+	}
+
+	if sizeBytes <= 0 {
+		sizeBytes = 16 * 1024 * 1024
+	}
+
+	return &MongoBackplane{DB: db, Collection: collection, SizeBytes: sizeBytes}
+}
+
+func (b *MongoBackplane) Publish(ctx context.Context, msg BackplaneMessage) error {
+	_, err := b.DB.Collection(b.Collection).InsertOne(ctx, mongoBackplaneDoc{ID: bson.NewObjectID(), Message: msg})
+
+	return err
+}
+
+func (b *MongoBackplane) Subscribe(ctx context.Context, handler func(msg BackplaneMessage)) error {
+	err := b.DB.CreateCollection(ctx, b.Collection, options.CreateCollection().SetCapped(true).SetSizeInBytes(b.SizeBytes))
+
+	var commandErr mongo.CommandError
+	if err != nil && !(errors.As(err, &commandErr) && commandErr.Name == "NamespaceExists") {
+		return err
+	}
+
+	collection := b.DB.Collection(b.Collection)
+
+	// Only messages published from now on are delivered
+	since := bson.NewObjectIDFromTimestamp(time.Now())
+
+	go func() {
+		for ctx.Err() == nil {
+			cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$gte": since}}, options.Find().SetCursorType(options.TailableAwait).SetMaxAwaitTime(time.Second))
+
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Println("backplane tail error:", err)
+				}
+
+				if !backoff(ctx, time.Second) {
+					return
+				}
+
+				continue
+			}
+
+			for cursor.Next(ctx) {
+				var doc mongoBackplaneDoc
+
+				if err := cursor.Decode(&doc); err != nil {
+					log.Println("invalid backplane message:", err)
+					continue
+				}
+
+				// Ids of other replicas are ordered by second only, resuming a second earlier relies on dedup of message ids
+				since = bson.NewObjectIDFromTimestamp(doc.ID.Timestamp().Add(-time.Second))
+
+				handler(doc.Message)
+			}
+
+			cursor.Close(context.Background())
+
+			// Tailable cursor dies on empty collection or when overtaken by the capped collection
+			if !backoff(ctx, 200*time.Millisecond) {
+				return
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package websocket
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	JoinRoomEvent      = "room.join"      // Client joins room, payload {"room": "..."}, acked with room members
+	LeaveRoomEvent     = "room.leave"     // Client leaves room, payload {"room": "..."}
+	PresenceJoinEvent  = "presence.join"  // User connected to namespace or joined room with their first connection
+	PresenceLeaveEvent = "presence.leave" // Last connection of user disconnected from namespace or left room
+)
+
+// Authenticated user connected to namespace or room
+type PresenceMember struct {
+	UserID      string `json:"userId"`
+	Username    string `json:"username"`
+	Connections int    `json:"connections,omitempty"` // Connections of the user e.g: multiple tabs or devices
+}
+
+type RoomPayload struct {
+	Room string `json:"room" validate:"required"`
+}
+
+// Whether client is the only connection of its user in room ("" for the namespace), anonymous and read only clients are not tracked, caller holds the lock
+func (h *Hub) isFirstConnection(client *Client, room string) bool {
+	if client.user == nil || client.readOnly {
+		return false
+	}
+
+	for other := range h.clients[client.namespace] {
+		if other != client && !other.readOnly && other.user != nil && other.user.ID == client.user.ID && (room == "" || other.rooms[room]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (h *Hub) emitPresence(client *Client, room string, event string) {
+	h.Emit(client.namespace, room, event, PresenceMember{UserID: client.user.ID.Hex(), Username: client.user.Username})
+}
+
+func (h *Hub) authorizeRoom(client *Client, room string) bool {
+	return h.config.AuthorizeRoom == nil || h.config.AuthorizeRoom(client.user, client.namespace, room)
+}
+
+// Add client to room of its namespace
+func (h *Hub) Join(client *Client, room string) {
+	h.mutex.Lock()
+
+	if client.rooms[room] {
+		h.mutex.Unlock()
+		return
+	}
+
+	first := h.isFirstConnection(client, room)
+	client.rooms[room] = true
+
+	h.mutex.Unlock()
+
+	if first {
+		h.emitPresence(client, room, PresenceJoinEvent)
+	}
+}
+
+// Remove client from room of its namespace
+func (h *Hub) Leave(client *Client, room string) {
+	h.mutex.Lock()
+
+	if !client.rooms[room] {
+		h.mutex.Unlock()
+		return
+	}
+
+	delete(client.rooms, room)
+	last := h.isFirstConnection(client, room)
+
+	h.mutex.Unlock()
+
+	if last {
+		h.emitPresence(client, room, PresenceLeaveEvent)
+	}
+}
+
+// Authenticated users connected to room of namespace, room empty for the whole namespace
+func (h *Hub) Members(namespace string, room string) []PresenceMember {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	members := map[string]*PresenceMember{}
+
+	for client := range h.clients[namespace] {
+		if client.user == nil || client.readOnly || (room != "" && !client.rooms[room]) {
+			continue
+		}
+
+		id := client.user.ID.Hex()
+
+		if members[id] == nil {
+			members[id] = &PresenceMember{UserID: id, Username: client.user.Username}
+		}
+
+		members[id].Connections++
+	}
+
+	result := []PresenceMember{}
+	for _, member := range members {
+		result = append(result, *member)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UserID < result[j].UserID
+	})
+
+	return result
+}
+
+func (h *Hub) handleJoin(ctx *WSContext, payload RoomPayload) error {
+	if payload.Room == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Room is required.")
+	}
+
+	if !h.authorizeRoom(ctx.Client, payload.Room) {
+		return echo.NewHTTPError(http.StatusForbidden, "Not allowed to join this room.")
+	}
+
+	h.Join(ctx.Client, payload.Room)
+
+	return ctx.Ack(h.Members(ctx.Client.namespace, payload.Room))
+}
+
+func (h *Hub) handleLeave(ctx *WSContext, payload RoomPayload) error {
+	h.Leave(ctx.Client, payload.Room)
+
+	return nil
+}
+
+// Online members route handler e.g: GET /ws/:namespace/rooms/:room/members, namespace members without room param
+func (h *Hub) HandleMembers(c echo.Context) error {
+	user, _ := c.Get("auth").(*models.User)
+
+	if h.Reserved(c.Param("namespace")) {
+		return echo.NewHTTPError(http.StatusForbidden, "Namespace is reserved.")
+	}
+
+	if h.config.AuthorizeRoom != nil && !h.config.AuthorizeRoom(user, c.Param("namespace"), c.Param("room")) {
+		return echo.NewHTTPError(http.StatusForbidden)
+	}
+
+	return c.JSON(200, echo.Map{"data": h.Members(c.Param("namespace"), c.Param("room"))})
+}
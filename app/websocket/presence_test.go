@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// Ask to join room, the reply is an ack or error event
+func joinTest(t *testing.T, conn *websocket.Conn, room string) {
+	t.Helper()
+
+	if err := conn.WriteJSON(WSMessage{Event: JoinRoomEvent, ID: "join-" + room, Payload: json.RawMessage(`{"room":"` + room + `"}`)}); err != nil {
+		t.Fatalf("join %s: %v", room, err)
+	}
+}
+
+func TestHubPresence(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{})
+
+	bob := dialTest(t, server, "/ws/chat", "bob")
+
+	if msg := nextEvent(t, bob, PresenceJoinEvent); decodeMember(t, msg).Username != "bob" {
+		t.Errorf("got %+v, want presence.join of bob himself", msg)
+	}
+
+	aliceFirst := dialTest(t, server, "/ws/chat", "alice")
+	waitClients(t, hub, "chat", 2)
+
+	if msg := nextEvent(t, bob, PresenceJoinEvent); decodeMember(t, msg).Username != "alice" {
+		t.Errorf("got %+v, want presence.join of alice", msg)
+	}
+
+	// Anonymous and further connections of the same user do not change presence
+	dialTest(t, server, "/ws/chat", "")
+	aliceSecond := dialTest(t, server, "/ws/chat", "alice")
+	waitClients(t, hub, "chat", 4)
+
+	if got := hub.Members("chat", ""); len(got) != 2 || got[0].Username != "alice" || got[0].Connections != 2 || got[1].Connections != 1 {
+		t.Errorf("got members %+v, want alice with 2 connections and bob with 1", got)
+	}
+
+	aliceSecond.Close()
+	waitClients(t, hub, "chat", 3)
+	aliceFirst.Close()
+	waitClients(t, hub, "chat", 2)
+
+	hub.Emit("chat", "", "after", nil)
+
+	if msg := nextEvent(t, bob, PresenceJoinEvent, PresenceLeaveEvent, "after"); msg.Event != PresenceLeaveEvent || decodeMember(t, msg).Username != "alice" {
+		t.Errorf("got %+v, want a single presence.leave of alice once her last connection closed", msg)
+	}
+
+	if msg := nextEvent(t, bob, PresenceJoinEvent, PresenceLeaveEvent, "after"); msg.Event != "after" {
+		t.Errorf("got %+v, want after", msg)
+	}
+}
+
+func TestHubRooms(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{
+		AuthorizeRoom: func(user *models.User, namespace string, room string) bool {
+			return room != "secret"
+		},
+	})
+
+	alice := dialTest(t, server, "/ws/chat", "alice")
+	bob := dialTest(t, server, "/ws/chat", "bob")
+	waitClients(t, hub, "chat", 2)
+
+	joinTest(t, alice, "a")
+
+	ack := nextEvent(t, alice, AckEvent, ErrorEvent)
+
+	var members []PresenceMember
+	if err := json.Unmarshal(ack.Payload, &members); err != nil || ack.Event != AckEvent || len(members) != 1 || members[0].Username != "alice" {
+		t.Fatalf("join: got %+v, want ack with alice as member", ack)
+	}
+
+	joinTest(t, bob, "secret")
+
+	if msg := nextEvent(t, bob, AckEvent, ErrorEvent); msg.Error == nil || msg.Error.Code != http.StatusForbidden {
+		t.Errorf("join unauthorized room: got %+v, want 403", msg)
+	}
+
+	hub.Emit("chat", "a", "room.news", nil)
+	hub.Emit("chat", "", "after", nil)
+
+	if msg := nextEvent(t, alice, "room.news", "after"); msg.Event != "room.news" || msg.Room != "a" {
+		t.Errorf("alice: got %+v, want room.news of room a", msg)
+	}
+
+	if msg := nextEvent(t, bob, "room.news", "after"); msg.Event != "after" {
+		t.Errorf("bob: got %s, want after as he is not in room a", msg.Event)
+	}
+
+	alice.WriteJSON(WSMessage{Event: LeaveRoomEvent, ID: "leave", Payload: json.RawMessage(`{"room":"a"}`)})
+	nextEvent(t, alice, AckEvent)
+
+	if got := hub.Members("chat", "a"); len(got) != 0 {
+		t.Errorf("got members %+v after leaving, want none", got)
+	}
+}
+
+func TestHubHandleMembers(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{
+		AuthorizeRoom: func(user *models.User, namespace string, room string) bool {
+			return user != nil
+		},
+	})
+	hub.Reserve("private-*")
+
+	alice := dialTest(t, server, "/ws/chat", "alice")
+	waitClients(t, hub, "chat", 1)
+	joinTest(t, alice, "a")
+	nextEvent(t, alice, AckEvent)
+
+	tests := []struct {
+		name       string
+		path       string
+		user       string
+		wantStatus int
+		wantUsers  int
+	}{
+		{"namespace members", "/ws/chat/members", "bob", http.StatusOK, 1},
+		{"room members", "/ws/chat/rooms/a/members", "bob", http.StatusOK, 1},
+		{"empty room", "/ws/chat/rooms/b/members", "bob", http.StatusOK, 0},
+		{"not authorized", "/ws/chat/members", "", http.StatusForbidden, 0},
+		{"reserved namespace", "/ws/private-1/members", "bob", http.StatusForbidden, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+test.path, nil)
+			if test.user != "" {
+				req.Header.Set("X-User", test.user)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+
+			defer res.Body.Close()
+
+			if res.StatusCode != test.wantStatus {
+				t.Fatalf("got status %d, want %d", res.StatusCode, test.wantStatus)
+			}
+
+			var body struct {
+				Data []PresenceMember `json:"data"`
+			}
+
+			json.NewDecoder(res.Body).Decode(&body)
+
+			if len(body.Data) != test.wantUsers {
+				t.Errorf("got members %+v, want %d", body.Data, test.wantUsers)
+			}
+		})
+	}
+}
+
+func decodeMember(t *testing.T, msg WSMessage) PresenceMember {
+	t.Helper()
+
+	var member PresenceMember
+
+	if err := json.Unmarshal(msg.Payload, &member); err != nil {
+		t.Fatalf("decode member of %+v: %v", msg, err)
+	}
+
+	return member
+}
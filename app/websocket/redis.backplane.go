@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Backplane over Redis pub/sub channel, messages published while a replica is disconnected are lost for it
+type RedisBackplane struct {
+	Client  *redis.Client
+	Channel string
+}
+
+// Connect to Redis url e.g: redis://localhost:6379/0, channel is shared by every replica, default is "websocket"
+func NewRedisBackplane(url string, channel string) (*RedisBackplane, error) {
+	options, err := redis.ParseURL(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if channel == "" {
+		channel = "websocket"
+	}
+
+	return &RedisBackplane{Client: redis.NewClient(options), Channel: channel}, nil
+}
+
+func (b *RedisBackplane) Publish(ctx context.Context, msg BackplaneMessage) error {
+	payload, err := json.Marshal(msg)
+
+	if err != nil {
+		return err
+	}
+
+	return b.Client.Publish(ctx, b.Channel, payload).Err()
+}
+
+func (b *RedisBackplane) Subscribe(ctx context.Context, handler func(msg BackplaneMessage)) error {
+	pubsub := b.Client.Subscribe(ctx, b.Channel)
+
+	// Wait for subscription confirmation so connection failures surface on boot
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+
+	go func() {
+		defer pubsub.Close()
+
+		// Subscription is restored by the client after reconnecting
+		messages := pubsub.Channel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var msg BackplaneMessage
+
+				if err := json.Unmarshal([]byte(message.Payload), &msg); err != nil {
+					log.Println("invalid backplane message:", err)
+					continue
+				}
+
+				handler(msg)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	AckEvent   = "ack"   // Reply of message with id, payload is set with WSContext.Ack
+	ErrorEvent = "error" // Reply of failed or invalid message, carries WSError
+)
+
+// Structured error event, mirrors http error responses
+type WSError struct {
+	Code    int               `json:"code"`             // Http status equivalent
+	Message string            `json:"message"`          // Readable message
+	Field   string            `json:"field,omitempty"`  // Conflicting field of unique violation
+	Errors  map[string]string `json:"errors,omitempty"` // Validation errors keyed by field
+}
+
+// Handler of client event with raw payload, see Typed for decoded and validated payloads
+type EventHandler func(ctx *WSContext, payload json.RawMessage) error
+
+// Event being handled
+type WSContext struct {
+	Hub     *Hub
+	Client  *Client
+	Message WSMessage
+	acked   bool
+}
+
+// Handler of event with JSON payload decoded into T, struct payloads are validated by Hub.Validator
+func Typed[T any](handler func(ctx *WSContext, payload T) error) EventHandler {
+	return func(ctx *WSContext, raw json.RawMessage) error {
+		var payload T
+
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid payload.")
+			}
+		}
+
+		if err := ctx.Validate(payload); err != nil {
+			return err
+		}
+
+		return handler(ctx, payload)
+	}
+}
+
+// Register handler of client event e.g: hub.On("chat.send", websocket.Typed(func(ctx *websocket.WSContext, payload ChatMessage) error {...}))
+func (h *Hub) On(event string, handler EventHandler) {
+	h.handlersMutex.Lock()
+	defer h.handlersMutex.Unlock()
+
+	h.handlers[event] = handler
+}
+
+// Send event to clients of namespace, room empty for every client of the namespace
+func (h *Hub) Emit(namespace string, room string, event string, payload any) error {
+	raw, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	h.broadcast(WSMessage{Namespace: namespace, Room: room, Event: event, Payload: raw}, nil)
+
+	return nil
+}
+
+// Send event to every connection of user, in whichever namespace and replica they are connected
+func (h *Hub) EmitToUser(userID string, event string, payload any) error {
+	raw, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	msg := WSMessage{Event: event, Payload: raw}
+
+	h.deliverToUser(userID, msg)
+	h.relay(BackplaneMessage{UserID: userID, Message: msg})
+
+	return nil
+}
+
+func (h *Hub) deliverToUser(userID string, msg WSMessage) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for namespace, clients := range h.clients {
+		for client := range clients {
+			if client.user != nil && client.user.ID.Hex() == userID {
+				msg.Namespace = namespace
+				client.enqueue(msg)
+			}
+		}
+	}
+}
+
+// Run handler of message, replies error event on failure and ack when client asked for one
+func (h *Hub) dispatch(client *Client, msg WSMessage) {
+	ctx := &WSContext{Hub: h, Client: client, Message: msg}
+
+	h.handlersMutex.RLock()
+	handler, ok := h.handlers[msg.Event]
+	h.handlersMutex.RUnlock()
+
+	var err error
+
+	if ok {
+		err = runHandler(handler, ctx, msg.Payload)
+	} else {
+		err = echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Unknown event: %s", msg.Event))
+	}
+
+	if err != nil {
+		client.enqueue(WSMessage{Namespace: client.namespace, Event: ErrorEvent, ID: msg.ID, Error: h.errorFrame(err)})
+		return
+	}
+
+	if !ctx.acked {
+		ctx.Ack(nil)
+	}
+}
+
+func runHandler(handler EventHandler, ctx *WSContext, payload json.RawMessage) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("websocket handler of %s panicked: %v", ctx.Message.Event, recovered)
+		}
+	}()
+
+	return handler(ctx, payload)
+}
+
+func (h *Hub) errorFrame(err error) *WSError {
+	if h.ErrorHandler != nil {
+		return h.ErrorHandler(err)
+	}
+
+	if he, ok := err.(*echo.HTTPError); ok && he.Code < 500 {
+		return &WSError{Code: he.Code, Message: fmt.Sprintf("%v", he.Message)}
+	}
+
+	log.Println("websocket handler error:", err)
+
+	return &WSError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)}
+}
+
+// Authenticated user of client, nil for anonymous clients
+func (ctx *WSContext) User() *models.User {
+	return ctx.Client.user
+}
+
+func (ctx *WSContext) Namespace() string {
+	return ctx.Client.namespace
+}
+
+// Validate struct payload with Hub.Validator, other payloads are left as is
+func (ctx *WSContext) Validate(payload any) error {
+	value := reflect.ValueOf(payload)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+
+	if ctx.Hub.Validator == nil || value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return ctx.Hub.Validator.Validate(payload)
+}
+
+// Reply ack event with payload to message asking for one, once per message
+func (ctx *WSContext) Ack(payload any) error {
+	if ctx.Message.ID == "" || ctx.acked {
+		return nil
+	}
+
+	raw, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	ctx.acked = true
+	ctx.Client.enqueue(WSMessage{Namespace: ctx.Client.namespace, Event: AckEvent, ID: ctx.Message.ID, Payload: raw})
+
+	return nil
+}
+
+// Send event to the client of message
+func (ctx *WSContext) Emit(event string, payload any) error {
+	raw, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	ctx.Client.enqueue(WSMessage{Namespace: ctx.Client.namespace, Event: event, Payload: raw})
+
+	return nil
+}
+
+// Send event to the other clients of namespace, or of room when given
+func (ctx *WSContext) Broadcast(room string, event string, payload any) error {
+	raw, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	ctx.Hub.broadcast(WSMessage{Namespace: ctx.Client.namespace, Room: room, Event: event, Payload: raw}, ctx.Client)
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+type testChatMessage struct {
+	Text string `json:"text" validate:"required"`
+}
+
+// Validation errors are reported as 422 like middlewares.WSErrorHandler does
+type testValidator struct {
+	validator *validator.Validate
+}
+
+func (v *testValidator) Validate(i any) error {
+	if err := v.validator.Struct(i); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	return nil
+}
+
+// Hub with chat.send acking its text and broadcasting it to the other clients of the namespace
+func newTestChatHub(t *testing.T) (*Hub, *httptest.Server) {
+	t.Helper()
+
+	hub, server := newTestHub(t, HubConfig{})
+	hub.Validator = &testValidator{validator: validator.New()}
+
+	hub.On("chat.send", Typed(func(ctx *WSContext, payload testChatMessage) error {
+		if err := ctx.Broadcast("", "chat.message", payload); err != nil {
+			return err
+		}
+
+		return ctx.Ack(map[string]string{"echo": payload.Text})
+	}))
+
+	hub.On("noop", func(ctx *WSContext, payload json.RawMessage) error {
+		return nil
+	})
+
+	hub.On("panic", func(ctx *WSContext, payload json.RawMessage) error {
+		panic("broken handler")
+	})
+
+	return hub, server
+}
+
+func TestHubEventHandlers(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     string
+		wantEvent   string
+		wantID      string
+		wantCode    int    // Code of error event
+		wantPayload string // Payload of ack event
+	}{
+		{"ack with handler payload", `{"event":"chat.send","id":"1","payload":{"text":"hi"}}`, AckEvent, "1", 0, `{"echo":"hi"}`},
+		{"ack of handler not acking", `{"event":"noop","id":"2"}`, AckEvent, "2", 0, `null`},
+		{"payload validation", `{"event":"chat.send","id":"3","payload":{}}`, ErrorEvent, "3", http.StatusUnprocessableEntity, ""},
+		{"payload of other type", `{"event":"chat.send","id":"4","payload":{"text":1}}`, ErrorEvent, "4", http.StatusBadRequest, ""},
+		{"unknown event", `{"event":"chat.edit","id":"5"}`, ErrorEvent, "5", http.StatusNotFound, ""},
+		{"handler panic", `{"event":"panic","id":"6"}`, ErrorEvent, "6", http.StatusInternalServerError, ""},
+		{"invalid message", `not json`, ErrorEvent, "", http.StatusBadRequest, ""},
+		{"message without event", `{"id":"7"}`, ErrorEvent, "", http.StatusBadRequest, ""},
+	}
+
+	_, server := newTestChatHub(t)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := dialTest(t, server, "/ws/chat", "")
+
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(test.message)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			msg := readTest(t, conn)
+
+			if msg.Event != test.wantEvent || msg.ID != test.wantID || msg.Namespace != "chat" {
+				t.Fatalf("got %+v, want %s of id %q in chat", msg, test.wantEvent, test.wantID)
+			}
+
+			if test.wantCode != 0 && (msg.Error == nil || msg.Error.Code != test.wantCode) {
+				t.Errorf("got error %+v, want code %d", msg.Error, test.wantCode)
+			}
+
+			if test.wantPayload != "" && string(msg.Payload) != test.wantPayload {
+				t.Errorf("got payload %s, want %s", msg.Payload, test.wantPayload)
+			}
+		})
+	}
+}
+
+func TestHubHandlerBroadcast(t *testing.T) {
+	hub, server := newTestChatHub(t)
+
+	sender := dialTest(t, server, "/ws/chat", "")
+	receiver := dialTest(t, server, "/ws/chat", "")
+	waitClients(t, hub, "chat", 2)
+
+	sender.WriteJSON(WSMessage{Event: "chat.send", Payload: json.RawMessage(`{"text":"hi"}`)})
+
+	if msg := readTest(t, receiver); msg.Event != "chat.message" || string(msg.Payload) != `{"text":"hi"}` {
+		t.Errorf("receiver: got %+v, want chat.message with text", msg)
+	}
+
+	// Sender is skipped by the broadcast and asked for no ack, the next message it gets is the emitted one
+	hub.Emit("chat", "", "after", nil)
+
+	if msg := readTest(t, sender); msg.Event != "after" {
+		t.Errorf("sender: got %s, want after", msg.Event)
+	}
+}
+
+func TestHubEmitToUser(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{})
+
+	aliceChat := dialTest(t, server, "/ws/chat", "alice")
+	aliceNews := dialTest(t, server, "/ws/news", "alice")
+	bob := dialTest(t, server, "/ws/chat", "bob")
+	waitClients(t, hub, "chat", 2)
+	waitClients(t, hub, "news", 1)
+
+	if err := hub.EmitToUser(testUser("alice").ID.Hex(), "notice", map[string]string{"text": "hi"}); err != nil {
+		t.Fatalf("emit: %v", err)
+	}
+
+	hub.Emit("chat", "", "after", nil)
+
+	for name, conn := range map[string]*websocket.Conn{"chat": aliceChat, "news": aliceNews} {
+		if msg := nextEvent(t, conn, "notice", "after"); msg.Event != "notice" || msg.Namespace != name {
+			t.Errorf("alice %s: got %+v, want notice of %s", name, msg, name)
+		}
+	}
+
+	if msg := nextEvent(t, bob, "notice", "after"); msg.Event != "after" {
+		t.Errorf("bob: got %s, want after as notice is only for alice", msg.Event)
+	}
+}
+
+// Next message of conn being one of events, presence events in between are skipped
+func nextEvent(t *testing.T, conn *websocket.Conn, events ...string) WSMessage {
+	t.Helper()
+
+	for {
+		msg := readTest(t, conn)
+
+		for _, event := range events {
+			if msg.Event == event {
+				return msg
+			}
+		}
+	}
+}
@@ -0,0 +1,623 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+// Engine.IO v4 packet types
+const (
+	engineOpen    = '0'
+	engineClose   = '1'
+	enginePing    = '2'
+	enginePong    = '3'
+	engineMessage = '4'
+	engineUpgrade = '5'
+	engineNoop    = '6'
+)
+
+// Socket.IO v4 packet types, carried by engine message packets
+const (
+	socketConnect      = '0'
+	socketDisconnect   = '1'
+	socketEvent        = '2'
+	socketAck          = '3'
+	socketConnectError = '4'
+	socketBinaryEvent  = '5'
+	socketBinaryAck    = '6'
+)
+
+// Separates packets of a long-polling payload
+const pollingSeparator = "\x1e"
+
+type engineHandshake struct {
+	SID          string   `json:"sid"`
+	Upgrades     []string `json:"upgrades"`
+	PingInterval int64    `json:"pingInterval"`
+	PingTimeout  int64    `json:"pingTimeout"`
+	MaxPayload   int64    `json:"maxPayload"`
+}
+
+// Engine.IO connection of socket.io client, each namespace it connects to is a hub client
+type engineSession struct {
+	hub       *Hub
+	id        string
+	user      *models.User // Authenticated user of handshake request, nil for anonymous clients
+	send      chan string  // Encoded packets waiting for the transport
+	pong      chan struct{}
+	pause     chan struct{} // Closed once websocket took over from long-polling
+	pauseOnce sync.Once
+	polling   sync.Mutex // Held by pending poll, clients poll one request at a time
+	sockets   map[string]*Client
+	mutex     sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type socketPacket struct {
+	Type      byte
+	Namespace string // Without leading slash, empty for the main namespace
+	ID        string // Ack id, empty when no ack is asked
+	Data      json.RawMessage
+}
+
+// Serve socket.io v4 clients e.g: GET/POST /socket.io/, socket.io namespaces map to hub namespaces ("/chat" is "chat"), events are dispatched to handlers registered with On
+func (h *Hub) HandleSocketIO(c echo.Context) error {
+	r := c.Request()
+
+	if !h.checkOrigin(r) {
+		return echo.NewHTTPError(http.StatusForbidden, "Origin not allowed.")
+	}
+
+	// Long-polling requests of browser clients served from other origins
+	if origin := r.Header.Get("Origin"); origin != "" {
+		c.Response().Header().Set("Access-Control-Allow-Origin", origin)
+		c.Response().Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if r.Method == http.MethodOptions {
+		c.Response().Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		c.Response().Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	if c.QueryParam("EIO") != "4" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unsupported protocol version.")
+	}
+
+	sid := c.QueryParam("sid")
+	transport := c.QueryParam("transport")
+
+	if transport != "polling" && transport != "websocket" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Transport unknown.")
+	}
+
+	if sid == "" {
+		return h.openSession(c, transport)
+	}
+
+	h.mutex.RLock()
+	session := h.sessions[sid]
+	h.mutex.RUnlock()
+
+	if session == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Session ID unknown.")
+	}
+
+	switch {
+	case transport == "websocket":
+		conn, err := h.upgrader.Upgrade(c.Response(), r, nil)
+		if err != nil {
+			log.Println("WebSocket upgrade failed:", err)
+			return nil
+		}
+
+		go session.upgrade(conn)
+
+		return nil
+	case r.Method == http.MethodGet:
+		return session.poll(c)
+	case r.Method == http.MethodPost:
+		return session.receive(c)
+	}
+
+	return echo.NewHTTPError(http.StatusMethodNotAllowed)
+}
+
+func (h *Hub) SocketIOEnabled() bool {
+	return h.config.SocketIO
+}
+
+// Handshake of new session, clients starting with websocket skip long-polling
+func (h *Hub) openSession(c echo.Context, transport string) error {
+	var conn *websocket.Conn
+
+	if transport == "websocket" {
+		upgraded, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
+		if err != nil {
+			log.Println("WebSocket upgrade failed:", err)
+			return nil
+		}
+
+		conn = upgraded
+	} else if c.Request().Method != http.MethodGet {
+		return echo.NewHTTPError(http.StatusBadRequest, "Session ID unknown.")
+	}
+
+	user, _ := c.Get("auth").(*models.User)
+
+	session := &engineSession{
+		hub:     h,
+		id:      randomID(),
+		user:    user,
+		send:    make(chan string, h.config.QueueSize),
+		pong:    make(chan struct{}, 1),
+		pause:   make(chan struct{}),
+		sockets: make(map[string]*Client),
+		done:    make(chan struct{}),
+	}
+
+	handshake := engineHandshake{
+		SID:          session.id,
+		Upgrades:     []string{},
+		PingInterval: h.config.PingInterval.Milliseconds(),
+		PingTimeout:  h.config.PongTimeout.Milliseconds(),
+		MaxPayload:   h.config.MaxMessageSize,
+	}
+
+	if conn == nil {
+		handshake.Upgrades = []string{"websocket"}
+	}
+
+	raw, _ := json.Marshal(handshake)
+	open := string(engineOpen) + string(raw)
+
+	h.mutex.Lock()
+
+	if h.closed {
+		h.mutex.Unlock()
+
+		if conn != nil {
+			conn.Close()
+			return nil
+		}
+
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Server is shutting down.")
+	}
+
+	h.sessions[session.id] = session
+	h.mutex.Unlock()
+
+	go session.heartbeat()
+
+	if conn != nil {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(open)); err != nil {
+			session.close()
+			conn.Close()
+			return nil
+		}
+
+		go session.serve(conn)
+
+		return nil
+	}
+
+	return c.String(http.StatusOK, open)
+}
+
+// Queue packet for the transport, blocks while the queue is full so slow namespaces apply SlowClientPolicy on their own queue
+func (s *engineSession) write(packet string) {
+	select {
+	case s.send <- packet:
+	case <-s.done:
+	}
+}
+
+// Close session and disconnect its namespaces
+func (s *engineSession) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		s.hub.mutex.Lock()
+		delete(s.hub.sessions, s.id)
+		s.hub.mutex.Unlock()
+
+		s.mutex.Lock()
+		for _, client := range s.sockets {
+			client.close()
+		}
+		s.mutex.Unlock()
+	})
+}
+
+// Ping client every PingInterval, session is closed when pong is not received within PongTimeout
+func (s *engineSession) heartbeat() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-time.After(s.hub.config.PingInterval):
+		}
+
+		s.write(string(enginePing))
+
+		select {
+		case <-s.done:
+			return
+		case <-s.pong:
+		case <-time.After(s.hub.config.PongTimeout):
+			log.Println("Socket.IO session timed out:", s.id)
+			s.close()
+			return
+		}
+	}
+}
+
+// Long-polling GET, waits for packets and returns every queued one
+func (s *engineSession) poll(c echo.Context) error {
+	if !s.polling.TryLock() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Overlapping polls.")
+	}
+	defer s.polling.Unlock()
+
+	var packets []string
+
+	select {
+	case packet := <-s.send:
+		packets = append(packets, packet)
+	case <-s.pause:
+		packets = append(packets, string(engineNoop))
+	case <-s.done:
+		packets = append(packets, string(engineClose))
+	case <-c.Request().Context().Done():
+		return nil
+	}
+
+drain:
+	for {
+		select {
+		case packet := <-s.send:
+			packets = append(packets, packet)
+		default:
+			break drain
+		}
+	}
+
+	return c.String(http.StatusOK, strings.Join(packets, pollingSeparator))
+}
+
+// Long-polling POST of client packets
+func (s *engineSession) receive(c echo.Context) error {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, s.hub.config.MaxMessageSize))
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Payload too large.")
+	}
+
+	for packet := range strings.SplitSeq(string(body), pollingSeparator) {
+		s.handle(packet)
+	}
+
+	return c.String(http.StatusOK, "ok")
+}
+
+// Take over long-polling session with probed websocket
+func (s *engineSession) upgrade(conn *websocket.Conn) {
+	conn.SetReadLimit(s.hub.config.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(s.hub.config.PongTimeout))
+
+	_, probe, err := conn.ReadMessage()
+	if err != nil || string(probe) != string(enginePing)+"probe" {
+		conn.Close()
+		return
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(s.hub.config.WriteTimeout))
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(string(enginePong)+"probe")); err != nil {
+		conn.Close()
+		return
+	}
+
+	// Release pending poll so the client can finish the upgrade
+	s.pauseOnce.Do(func() {
+		close(s.pause)
+	})
+
+	_, upgrade, err := conn.ReadMessage()
+	if err != nil || string(upgrade) != string(engineUpgrade) {
+		conn.Close()
+		s.close()
+		return
+	}
+
+	conn.SetReadDeadline(time.Time{})
+
+	s.serve(conn)
+}
+
+// Pump packets of session over websocket until either side closes
+func (s *engineSession) serve(conn *websocket.Conn) {
+	conn.SetReadLimit(s.hub.config.MaxMessageSize)
+
+	// Counted under the lock like clients so Shutdown never waits before it
+	s.hub.mutex.RLock()
+
+	if s.hub.closed {
+		s.hub.mutex.RUnlock()
+		s.close()
+		conn.Close()
+		return
+	}
+
+	s.hub.pumps.Add(1)
+	s.hub.mutex.RUnlock()
+
+	go func() {
+		defer s.hub.pumps.Done()
+		defer conn.Close()
+
+		for {
+			select {
+			case packet := <-s.send:
+				conn.SetWriteDeadline(time.Now().Add(s.hub.config.WriteTimeout))
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(packet)); err != nil {
+					log.Println("write error:", err)
+					s.close()
+					return
+				}
+			case <-s.done:
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(s.hub.config.WriteTimeout))
+				return
+			}
+		}
+	}()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("read error:", err)
+			}
+			s.close()
+			return
+		}
+
+		if messageType == websocket.TextMessage {
+			s.handle(string(data))
+		}
+	}
+}
+
+// Handle engine.io packet of client
+func (s *engineSession) handle(packet string) {
+	if packet == "" {
+		return
+	}
+
+	switch packet[0] {
+	case enginePong:
+		select {
+		case s.pong <- struct{}{}:
+		default:
+		}
+	case engineClose:
+		s.close()
+	case engineMessage:
+		socket, err := decodeSocketPacket(packet[1:])
+
+		// Malformed packets end the session like socket.io servers do
+		if err != nil {
+			log.Printf("Socket.IO session %s closed: %v\n", s.id, err)
+			s.close()
+			return
+		}
+
+		s.handleSocket(socket)
+	}
+}
+
+// Handle socket.io packet of client
+func (s *engineSession) handleSocket(packet socketPacket) {
+	s.mutex.Lock()
+	client := s.sockets[packet.Namespace]
+	s.mutex.Unlock()
+
+	switch packet.Type {
+	case socketConnect:
+		if client == nil {
+			s.connect(packet.Namespace)
+		}
+	case socketDisconnect:
+		if client != nil {
+			s.mutex.Lock()
+			delete(s.sockets, packet.Namespace)
+			s.mutex.Unlock()
+
+			client.close()
+		}
+	case socketEvent:
+		// Packets of namespaces not connected to are ignored
+		if client == nil {
+			return
+		}
+
+		var args []json.RawMessage
+		var event string
+
+		if err := json.Unmarshal(packet.Data, &args); err != nil || len(args) == 0 || json.Unmarshal(args[0], &event) != nil || event == "" {
+			client.enqueue(WSMessage{Namespace: client.namespace, Event: ErrorEvent, ID: packet.ID, Error: &WSError{Code: http.StatusBadRequest, Message: "Invalid message format."}})
+			return
+		}
+
+		msg := WSMessage{Namespace: client.namespace, Event: event, ID: packet.ID}
+
+		// Extra arguments are not supported by typed handlers, only the first one is the payload
+		if len(args) > 1 {
+			msg.Payload = args[1]
+		}
+
+		s.hub.dispatch(client, msg)
+	}
+}
+
+// Connect session to namespace as hub client
+func (s *engineSession) connect(namespace string) {
+	if s.hub.Reserved(namespace) {
+		s.write(encodeSocketPacket(socketConnectError, namespace, "", errorData("Namespace is reserved.")))
+		return
+	}
+
+	client := &Client{
+		hub:       s.hub,
+		namespace: namespace,
+		user:      s.user,
+		rooms:     make(map[string]bool),
+		send:      make(chan WSMessage, s.hub.config.QueueSize),
+		done:      make(chan struct{}),
+	}
+
+	s.mutex.Lock()
+
+	// Closing session already disconnected its namespaces
+	select {
+	case <-s.done:
+		s.mutex.Unlock()
+		return
+	default:
+	}
+
+	s.sockets[namespace] = client
+	s.mutex.Unlock()
+
+	if !s.hub.register(client, 1) {
+		s.mutex.Lock()
+		delete(s.sockets, namespace)
+		s.mutex.Unlock()
+
+		s.write(encodeSocketPacket(socketConnectError, namespace, "", errorData("Server is shutting down.")))
+		return
+	}
+
+	raw, _ := json.Marshal(map[string]string{"sid": randomID()})
+
+	// Connect reply goes out before anything queued for client e.g: its own presence
+	s.write(encodeSocketPacket(socketConnect, namespace, "", raw))
+
+	go s.forward(client)
+}
+
+// Move messages of namespace client into session until it disconnects
+func (s *engineSession) forward(client *Client) {
+	defer s.hub.pumps.Done()
+	defer s.hub.unregister(client)
+
+	for {
+		select {
+		case msg := <-client.send:
+			s.write(encodeMessage(client.namespace, msg))
+		case <-client.done:
+			s.mutex.Lock()
+			current := s.sockets[client.namespace] == client
+			if current {
+				delete(s.sockets, client.namespace)
+			}
+			s.mutex.Unlock()
+
+			// Still connected means server side disconnect e.g: slow client or shutdown
+			if current {
+				s.write(encodeSocketPacket(socketDisconnect, client.namespace, "", nil))
+			}
+			return
+		}
+	}
+}
+
+// Parse socket.io packet e.g: 2/chat,12["chat.send",{"text":"hi"}]
+func decodeSocketPacket(data string) (socketPacket, error) {
+	if data == "" {
+		return socketPacket{}, errors.New("empty packet")
+	}
+
+	packet := socketPacket{Type: data[0]}
+
+	if packet.Type == socketBinaryEvent || packet.Type == socketBinaryAck {
+		return packet, errors.New("binary packets are not supported")
+	}
+
+	rest := data[1:]
+
+	if strings.HasPrefix(rest, "/") {
+		namespace, remaining, _ := strings.Cut(rest, ",")
+		packet.Namespace = strings.TrimPrefix(namespace, "/")
+		rest = remaining
+	}
+
+	digits := 0
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+
+	packet.ID = rest[:digits]
+	packet.Data = json.RawMessage(rest[digits:])
+
+	return packet, nil
+}
+
+func encodeSocketPacket(packetType byte, namespace string, id string, data []byte) string {
+	var packet strings.Builder
+
+	packet.WriteByte(engineMessage)
+	packet.WriteByte(packetType)
+
+	if namespace != "" {
+		packet.WriteString("/" + namespace + ",")
+	}
+
+	packet.WriteString(id)
+	packet.Write(data)
+
+	return packet.String()
+}
+
+// Encode hub message as socket.io packet, acks carry the payload or {"error": {...}} of failed messages
+func encodeMessage(namespace string, msg WSMessage) string {
+	var args []any
+
+	switch {
+	case msg.ID != "" && msg.Event == AckEvent:
+		args = []any{msg.Payload}
+	case msg.ID != "" && msg.Error != nil:
+		args = []any{map[string]any{"error": msg.Error}}
+	case msg.Error != nil:
+		args = []any{ErrorEvent, msg.Error}
+	case len(msg.Payload) > 0:
+		args = []any{msg.Event, msg.Payload}
+	default:
+		args = []any{msg.Event}
+	}
+
+	raw, _ := json.Marshal(args)
+
+	if msg.ID != "" && (msg.Event == AckEvent || msg.Error != nil) {
+		return encodeSocketPacket(socketAck, namespace, msg.ID, raw)
+	}
+
+	return encodeSocketPacket(socketEvent, namespace, "", raw)
+}
+
+func errorData(message string) []byte {
+	raw, _ := json.Marshal(map[string]string{"message": message})
+
+	return raw
+}
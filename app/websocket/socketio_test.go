@@ -0,0 +1,266 @@
+package websocket
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDecodeSocketPacket(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    socketPacket
+		wantErr bool
+	}{
+		{"0", socketPacket{Type: socketConnect, Data: json.RawMessage("")}, false},
+		{"0/chat,", socketPacket{Type: socketConnect, Namespace: "chat", Data: json.RawMessage("")}, false},
+		{`2["chat.send",{"text":"hi"}]`, socketPacket{Type: socketEvent, Data: json.RawMessage(`["chat.send",{"text":"hi"}]`)}, false},
+		{`2/chat,12["chat.send"]`, socketPacket{Type: socketEvent, Namespace: "chat", ID: "12", Data: json.RawMessage(`["chat.send"]`)}, false},
+		{`51-["upload",{"_placeholder":true,"num":0}]`, socketPacket{}, true},
+		{"", socketPacket{}, true},
+	}
+
+	for _, test := range tests {
+		got, err := decodeSocketPacket(test.data)
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("%q: got error %v, want error %v", test.data, err, test.wantErr)
+			continue
+		}
+
+		if !test.wantErr && (got.Type != test.want.Type || got.Namespace != test.want.Namespace || got.ID != test.want.ID || string(got.Data) != string(test.want.Data)) {
+			t.Errorf("%q: got %+v, want %+v", test.data, got, test.want)
+		}
+	}
+}
+
+func TestEncodeMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		msg       WSMessage
+		want      string
+	}{
+		{"event", "chat", WSMessage{Event: "news", Payload: json.RawMessage(`{"a":1}`)}, `42/chat,["news",{"a":1}]`},
+		{"event without payload", "", WSMessage{Event: "news"}, `42["news"]`},
+		{"ack", "chat", WSMessage{Event: AckEvent, ID: "1", Payload: json.RawMessage(`{"echo":"hi"}`)}, `43/chat,1[{"echo":"hi"}]`},
+		{"failed message", "", WSMessage{Event: ErrorEvent, ID: "2", Error: &WSError{Code: 404, Message: "Unknown event: x"}}, `432[{"error":{"code":404,"message":"Unknown event: x"}}]`},
+		{"error event", "", WSMessage{Event: ErrorEvent, Error: &WSError{Code: 400, Message: "Invalid message format."}}, `42["error",{"code":400,"message":"Invalid message format."}]`},
+	}
+
+	for _, test := range tests {
+		if got := encodeMessage(test.namespace, test.msg); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+// Long-polling socket.io session of test server
+type testPollingSession struct {
+	t      *testing.T
+	server *httptest.Server
+	sid    string
+}
+
+func (s *testPollingSession) request(method string, body string) (int, string) {
+	s.t.Helper()
+
+	req, _ := http.NewRequest(method, s.server.URL+"/socket.io/?EIO=4&transport=polling&sid="+s.sid, strings.NewReader(body))
+	res, err := (&http.Client{Timeout: testTimeout}).Do(req)
+
+	if err != nil {
+		s.t.Fatalf("%s: %v", method, err)
+	}
+
+	defer res.Body.Close()
+
+	raw, _ := io.ReadAll(res.Body)
+
+	return res.StatusCode, string(raw)
+}
+
+// Packets of the next poll
+func (s *testPollingSession) poll() []string {
+	s.t.Helper()
+
+	status, body := s.request(http.MethodGet, "")
+
+	if status != http.StatusOK {
+		s.t.Fatalf("poll: got status %d %s", status, body)
+	}
+
+	return strings.Split(body, pollingSeparator)
+}
+
+func (s *testPollingSession) send(packets ...string) {
+	s.t.Helper()
+
+	if status, body := s.request(http.MethodPost, strings.Join(packets, pollingSeparator)); status != http.StatusOK {
+		s.t.Fatalf("send: got status %d %s", status, body)
+	}
+}
+
+// Open long-polling session with handshake
+func openTestPolling(t *testing.T, server *httptest.Server) *testPollingSession {
+	t.Helper()
+
+	session := &testPollingSession{t: t, server: server}
+
+	packets := session.poll()
+
+	var handshake engineHandshake
+
+	if len(packets) != 1 || !strings.HasPrefix(packets[0], string(engineOpen)) || json.Unmarshal([]byte(packets[0][1:]), &handshake) != nil {
+		t.Fatalf("handshake: got %v", packets)
+	}
+
+	if handshake.SID == "" || len(handshake.Upgrades) != 1 || handshake.Upgrades[0] != "websocket" {
+		t.Errorf("handshake: got %+v, want sid and websocket upgrade", handshake)
+	}
+
+	session.sid = handshake.SID
+
+	return session
+}
+
+func TestSocketIOPolling(t *testing.T) {
+	hub, server := newTestChatHub(t)
+	hub.Reserve("private-*")
+
+	session := openTestPolling(t, server)
+
+	session.send("40/chat,", "40/private-1,")
+
+	packets := session.poll()
+
+	if len(packets) != 2 || !strings.HasPrefix(packets[0], `40/chat,{"sid":`) || packets[1] != `44/private-1,{"message":"Namespace is reserved."}` {
+		t.Fatalf("connect: got %v, want connect of chat and error of reserved namespace", packets)
+	}
+
+	session.send(`42/chat,1["chat.send",{"text":"hi"}]`, `42/chat,2["chat.send",{}]`, `42/other,3["chat.send",{"text":"hi"}]`)
+
+	var got []string
+
+	for len(got) < 2 {
+		got = append(got, session.poll()...)
+	}
+
+	// Packets of namespaces not connected to are ignored
+	want := []string{`43/chat,1[{"echo":"hi"}]`, `43/chat,2[{"error":{"code":422,`}
+
+	for i, packet := range got {
+		if i >= len(want) || !strings.HasPrefix(packet, want[i]) {
+			t.Errorf("got packets %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestSocketIOHandshakeErrors(t *testing.T) {
+	_, server := newTestHub(t, HubConfig{})
+
+	tests := []struct {
+		name   string
+		method string
+		query  string
+	}{
+		{"unsupported version", http.MethodGet, "EIO=3&transport=polling"},
+		{"unknown transport", http.MethodGet, "EIO=4&transport=flashsocket"},
+		{"unknown session", http.MethodGet, "EIO=4&transport=polling&sid=missing"},
+		{"post without session", http.MethodPost, "EIO=4&transport=polling"},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, server.URL+"/socket.io/?"+test.query, nil)
+		res, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want 400", test.name, res.StatusCode)
+		}
+	}
+}
+
+// Write packet to conn and read the next one
+func exchangeTest(t *testing.T, conn *websocket.Conn, packet string) string {
+	t.Helper()
+
+	if packet != "" {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(packet)); err != nil {
+			t.Fatalf("write %s: %v", packet, err)
+		}
+	}
+
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+
+	_, data, err := conn.ReadMessage()
+
+	if err != nil {
+		t.Fatalf("read after %s: %v", packet, err)
+	}
+
+	return string(data)
+}
+
+func TestSocketIOWebsocket(t *testing.T) {
+	_, server := newTestChatHub(t)
+
+	conn := dialTest(t, server, "/socket.io/?EIO=4&transport=websocket", "")
+
+	if open := exchangeTest(t, conn, ""); !strings.HasPrefix(open, `0{"sid":`) || !strings.Contains(open, `"upgrades":[]`) {
+		t.Fatalf("handshake: got %s, want open packet without upgrades", open)
+	}
+
+	if got := exchangeTest(t, conn, "40"); !strings.HasPrefix(got, `40{"sid":`) {
+		t.Fatalf("connect: got %s", got)
+	}
+
+	if got := exchangeTest(t, conn, `421["chat.send",{"text":"hi"}]`); got != `431[{"echo":"hi"}]` {
+		t.Errorf("ack: got %s", got)
+	}
+}
+
+func TestSocketIOUpgrade(t *testing.T) {
+	_, server := newTestChatHub(t)
+
+	session := openTestPolling(t, server)
+
+	// Poll pending while upgrading is released with a noop
+	pending := make(chan []string, 1)
+	go func() { pending <- session.poll() }()
+
+	conn := dialTest(t, server, "/socket.io/?EIO=4&transport=websocket&sid="+session.sid, "")
+
+	if got := exchangeTest(t, conn, "2probe"); got != "3probe" {
+		t.Fatalf("probe: got %s, want 3probe", got)
+	}
+
+	select {
+	case packets := <-pending:
+		if len(packets) != 1 || packets[0] != string(engineNoop) {
+			t.Errorf("pending poll: got %v, want noop", packets)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("pending poll was not released")
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(string(engineUpgrade))); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+
+	// Session now talks over websocket
+	if got := exchangeTest(t, conn, "40/chat,"); !strings.HasPrefix(got, `40/chat,{"sid":`) {
+		t.Errorf("connect after upgrade: got %s", got)
+	}
+}
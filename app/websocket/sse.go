@@ -0,0 +1,183 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Sent to SSE client whose Last-Event-ID can not be resumed from e.g: evicted from replay buffer or issued by another replica, client should refetch its state
+const ResyncEvent = "resync"
+
+// Recent broadcasts of every namespace, ids are sequential per replica e.g: <origin>-42
+type replayBuffer struct {
+	origin   string
+	messages []WSMessage // Ring, message of sequence n is at n % len
+	next     uint64      // Sequence of next message
+	mutex    sync.Mutex
+}
+
+func newReplayBuffer(origin string, size int) *replayBuffer {
+	return &replayBuffer{origin: origin, messages: make([]WSMessage, size)}
+}
+
+// Keep message, returned with its event id
+func (b *replayBuffer) add(msg WSMessage) WSMessage {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	msg.eventID = fmt.Sprintf("%s-%d", b.origin, b.next)
+	b.messages[b.next%uint64(len(b.messages))] = msg
+	b.next++
+
+	return msg
+}
+
+// Namespace broadcasts after event id, false when some of them are no longer kept or id is unknown
+func (b *replayBuffer) since(namespace string, eventID string) ([]WSMessage, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	origin, sequence, _ := strings.Cut(eventID, "-")
+	last, err := strconv.ParseUint(sequence, 10, 64)
+	size := uint64(len(b.messages))
+
+	if err != nil || origin != b.origin || last >= b.next || b.next-(last+1) > size {
+		return nil, false
+	}
+
+	var messages []WSMessage
+
+	for seq := last + 1; seq < b.next; seq++ {
+		if msg := b.messages[seq%size]; msg.Namespace == namespace && msg.Room == "" {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages, true
+}
+
+// Server-Sent Events route handler e.g: GET /events?namespace=chat, streams broadcasts of namespace like /ws clients receive them, resumes after Last-Event-ID header or lastEventId query
+func (h *Hub) HandleEvents(c echo.Context) error {
+	if h.Reserved(c.QueryParam("namespace")) {
+		return echo.NewHTTPError(http.StatusForbidden, "Namespace is reserved.")
+	}
+
+	user, _ := c.Get("auth").(*models.User)
+
+	client := &Client{
+		hub:       h,
+		namespace: c.QueryParam("namespace"),
+		user:      user,
+		rooms:     make(map[string]bool),
+		send:      make(chan WSMessage, h.config.QueueSize),
+		readOnly:  true,
+		done:      make(chan struct{}),
+	}
+
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("lastEventId")
+	}
+
+	h.mutex.Lock()
+
+	if h.closed {
+		h.mutex.Unlock()
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Server is shutting down.")
+	}
+
+	// Replay is read while no broadcast is delivered so nothing is missed or sent twice
+	replay, resumed := []WSMessage{}, true
+	if lastEventID != "" {
+		replay, resumed = h.replay.since(client.namespace, lastEventID)
+	}
+
+	h.addClient(client)
+	h.pumps.Add(1)
+	h.mutex.Unlock()
+
+	defer h.pumps.Done()
+	defer func() {
+		h.unregister(client)
+		client.close()
+	}()
+
+	res := c.Response()
+	controller := http.NewResponseController(res)
+
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering e.g: nginx
+	res.WriteHeader(http.StatusOK)
+
+	write := func(frame string) bool {
+		controller.SetWriteDeadline(time.Now().Add(h.config.WriteTimeout))
+
+		if _, err := res.Write([]byte(frame)); err != nil {
+			return false
+		}
+
+		return controller.Flush() == nil
+	}
+
+	if !resumed {
+		replay = append([]WSMessage{{Namespace: client.namespace, Event: ResyncEvent}}, replay...)
+	}
+
+	for _, msg := range replay {
+		if !write(eventFrame(msg)) {
+			return nil
+		}
+	}
+
+	if !write(": connected\n\n") {
+		return nil
+	}
+
+	ticker := time.NewTicker(h.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-client.send:
+			if !write(eventFrame(msg)) {
+				return nil
+			}
+		case <-ticker.C:
+			// Comment line keeps proxies from closing idle stream
+			if !write(": ping\n\n") {
+				return nil
+			}
+		case <-client.done:
+			return nil
+		case <-c.Request().Context().Done():
+			return nil
+		}
+	}
+}
+
+// Encode message as event stream frame, data is the message as sent to websocket clients
+func eventFrame(msg WSMessage) string {
+	raw, _ := json.Marshal(msg)
+
+	var frame strings.Builder
+
+	if msg.eventID != "" {
+		frame.WriteString("id: " + msg.eventID + "\n")
+	}
+
+	frame.WriteString("event: " + msg.Event + "\n")
+	frame.WriteString("data: " + string(raw) + "\n\n")
+
+	return frame.String()
+}
@@ -0,0 +1,204 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayBufferSince(t *testing.T) {
+	buffer := newReplayBuffer("origin", 3)
+
+	var ids []string
+
+	for _, msg := range []WSMessage{
+		{Namespace: "chat", Event: "0"},
+		{Namespace: "chat", Event: "1"},
+		{Namespace: "news", Event: "2"},
+		{Namespace: "chat", Room: "a", Event: "3"},
+		{Namespace: "chat", Event: "4"},
+	} {
+		ids = append(ids, buffer.add(msg).eventID)
+	}
+
+	tests := []struct {
+		name        string
+		eventID     string
+		wantEvents  string
+		wantResumed bool
+	}{
+		{"namespace broadcasts after id", ids[1], "[4]", true},
+		{"latest id", ids[4], "[]", true},
+		{"evicted id", ids[0], "[]", false},
+		{"id of other replica", "other-3", "[]", false},
+		{"id not issued yet", "origin-5", "[]", false},
+		{"malformed id", "origin", "[]", false},
+	}
+
+	for _, test := range tests {
+		messages, resumed := buffer.since("chat", test.eventID)
+
+		events := []string{}
+		for _, msg := range messages {
+			events = append(events, msg.Event)
+		}
+
+		if resumed != test.wantResumed || fmt.Sprint(events) != test.wantEvents {
+			t.Errorf("%s: got %v %v, want %s %v", test.name, events, resumed, test.wantEvents, test.wantResumed)
+		}
+	}
+}
+
+// Event of stream, Event is ":connected" for the comment sent once the stream receives broadcasts
+type testEvent struct {
+	ID    string
+	Event string
+}
+
+// Events of namespace stream, opened after lastEventID when set
+func streamTest(t *testing.T, server *httptest.Server, namespace string, lastEventID string) <-chan testEvent {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?namespace="+namespace, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		res.Body.Close()
+		t.Fatalf("stream: got status %d of %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	events := make(chan testEvent, 10)
+
+	go func() {
+		defer res.Body.Close()
+		defer close(events)
+
+		var event testEvent
+
+		scanner := bufio.NewScanner(res.Body)
+
+		for scanner.Scan() {
+			switch line := scanner.Text(); {
+			case line == ": connected":
+				events <- testEvent{Event: ":connected"}
+			case strings.HasPrefix(line, "id: "):
+				event.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event.Event = strings.TrimPrefix(line, "event: ")
+			case line == "" && event.Event != "":
+				events <- event
+				event = testEvent{}
+			}
+		}
+	}()
+
+	return events
+}
+
+// Next events of stream up to count, fails the test when they do not arrive in time
+func nextEvents(t *testing.T, events <-chan testEvent, count int) []testEvent {
+	t.Helper()
+
+	var got []testEvent
+
+	for len(got) < count {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("stream closed after %v", got)
+			}
+
+			got = append(got, event)
+		case <-time.After(testTimeout):
+			t.Fatalf("got events %v, want %d", got, count)
+		}
+	}
+
+	return got
+}
+
+func eventNames(events []testEvent) string {
+	names := []string{}
+	for _, event := range events {
+		names = append(names, event.Event)
+	}
+
+	return fmt.Sprint(names)
+}
+
+func TestHandleEvents(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{})
+
+	stream := streamTest(t, server, "chat", "")
+	nextEvents(t, stream, 1)
+
+	hub.Emit("news", "", "weather", nil)
+	hub.Emit("chat", "room", "room.news", nil)
+	hub.Emit("chat", "", "first", nil)
+	hub.Emit("chat", "", "second", nil)
+
+	// Only broadcasts to the whole namespace are streamed
+	received := nextEvents(t, stream, 2)
+
+	if got := eventNames(received); got != "[first second]" {
+		t.Fatalf("got events %s, want [first second]", got)
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		want        string
+	}{
+		{"resume after last event id", received[0].ID, "[second :connected]"},
+		{"resume after latest event", received[1].ID, "[:connected]"},
+		{"resync unknown event id", "other-1", "[resync :connected]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resumed := streamTest(t, server, "chat", test.lastEventID)
+
+			var got []testEvent
+			for len(got) == 0 || got[len(got)-1].Event != ":connected" {
+				got = append(got, nextEvents(t, resumed, 1)...)
+			}
+
+			if eventNames(got) != test.want {
+				t.Errorf("got events %s, want %s", eventNames(got), test.want)
+			}
+		})
+	}
+}
+
+func TestHandleEventsReserved(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{})
+	hub.Reserve("private-*")
+
+	res, err := http.Get(server.URL + "/events?namespace=private-1")
+
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("got status %d, want 403", res.StatusCode)
+	}
+}
@@ -1,127 +1,497 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 )
 
 type WSMessage struct {
-	Namespace string `json:"namespace"`
-	Event     string `json:"event"`
-	Payload   string `json:"payload"`
+	Namespace string          `json:"namespace"`
+	Room      string          `json:"room,omitempty"` // Empty for every client of the namespace
+	Event     string          `json:"event"`
+	ID        string          `json:"id,omitempty"` // Correlation id set by client, replied with ack or error event of the same id
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Error     *WSError        `json:"error,omitempty"` // Set on error event
+	Meta      json.RawMessage `json:"-"`               // Server side data for subscriber filters e.g: record of change event, relayed between replicas, never sent to clients
+	eventID   string          // Replay id of broadcast, sent to SSE clients
+}
+
+// What happens to a client whose send queue is full
+type SlowClientPolicy string
+
+const (
+	DropMessages SlowClientPolicy = "drop"       // Messages are dropped until the client catches up
+	Disconnect   SlowClientPolicy = "disconnect" // Client is disconnected
+)
+
+type HubConfig struct {
+	AllowedOrigins   []string         // Browser origins allowed to connect e.g: https://app.example.com, "*" allows any, default only same host
+	RequireAuth      bool             // Authenticate /ws, /socket.io/ and /events routes with JWT bearer header or token cookie like middlewares.Auth, default is false
+	PingInterval     time.Duration    // Interval of pings sent to clients, default is 30s
+	PongTimeout      time.Duration    // Client is disconnected when nothing is read within it, default is 60s
+	WriteTimeout     time.Duration    // Deadline of a single write, default is 10s
+	MaxMessageSize   int64            // Maximum bytes of client message, default is 64KB
+	QueueSize        int              // Messages buffered per client, default is 256
+	SlowClientPolicy SlowClientPolicy // Applied when client queue is full, default is Disconnect
+	ReplayBufferSize int              // Broadcasts kept for SSE clients resuming with Last-Event-ID, default is 1024
+	SocketIO         bool             // Serve socket.io v4 clients (long-polling and websocket transports) at /socket.io/, default is false
+
+	Backplane Backplane // Relays broadcasts between hub replicas, see NewMemoryBackplane, NewRedisBackplane and NewMongoBackplane, presence stays per replica, default is none (single replica)
+
+	// Decide whether user (nil when anonymous) may join room of namespace or list its members, room is empty for the namespace itself, default allows everyone
+	AuthorizeRoom func(user *models.User, namespace string, room string) bool
+}
+
+// Websocket connections grouped by namespace, register with externals.RegisterExternals to enable /ws routes
+type Hub struct {
+	Validator    echo.Validator           // Validates typed event payloads, set to app validator by InitHttpApp
+	ErrorHandler func(err error) *WSError // Maps handler errors into error event, set to middlewares.WSErrorHandler by InitHttpApp
+
+	config        HubConfig
+	upgrader      websocket.Upgrader
+	clients       map[string]map[*Client]bool
+	mutex         sync.RWMutex
+	closed        bool
+	pumps         sync.WaitGroup
+	handlers      map[string]EventHandler
+	handlersMutex sync.RWMutex
+	origin        string
+	seen          *seenMessages
+	stopBackplane context.CancelFunc
+	sessions      map[string]*engineSession // Socket.IO sessions by id
+	replay        *replayBuffer
+	reserved      []string // Namespace patterns only joinable with Subscribe
+	reservedMutex sync.RWMutex
 }
 
 type Client struct {
-	conn      *websocket.Conn
+	hub       *Hub
+	conn      *websocket.Conn // Nil for socket.io clients, their session owns the connection
 	namespace string
+	user      *models.User // Authenticated user, nil for anonymous clients
+	rooms     map[string]bool
 	send      chan WSMessage
+	readOnly  bool                     // Subscribers only receive, messages they send are ignored
+	filter    func(msg WSMessage) bool // Decides which broadcasts subscriber receives, nil receives every one
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-var (
-	upgrader     = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
-	clients      = make(map[string]map[*Client]bool)
-	clientsMutex sync.Mutex
-)
+func NewHub(config HubConfig) *Hub {
+	if config.PingInterval <= 0 {
+		config.PingInterval = 30 * time.Second
+	}
+
+	if config.PongTimeout <= 0 {
+		config.PongTimeout = 60 * time.Second
+	}
+
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = 64 * 1024
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = 256
+	}
+
+	if config.SlowClientPolicy == "" {
+		config.SlowClientPolicy = Disconnect
+	}
+
+	if config.ReplayBufferSize <= 0 {
+		config.ReplayBufferSize = 1024
+	}
+
+	hub := &Hub{
+		config:   config,
+		clients:  make(map[string]map[*Client]bool),
+		handlers: make(map[string]EventHandler),
+		origin:   randomID(),
+		seen:     newSeenMessages(1024),
+		sessions: make(map[string]*engineSession),
+	}
 
-func HandleWebSocket(c echo.Context) error {
+	hub.replay = newReplayBuffer(hub.origin, config.ReplayBufferSize)
+
+	hub.upgrader = websocket.Upgrader{CheckOrigin: hub.checkOrigin}
+
+	hub.On(JoinRoomEvent, Typed(hub.handleJoin))
+	hub.On(LeaveRoomEvent, Typed(hub.handleLeave))
+
+	return hub
+}
+
+// Start receiving broadcasts of other replicas through backplane
+func (h *Hub) ConnectRaw() error {
+	if h.config.Backplane == nil || h.stopBackplane != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := h.config.Backplane.Subscribe(ctx, h.receive); err != nil {
+		cancel()
+		return err
+	}
+
+	h.stopBackplane = cancel
+
+	return nil
+}
+
+func (h *Hub) Healthcheck() error {
+	return nil
+}
+
+func (h *Hub) SuccessMessage() string {
+	return "Websocket hub ready."
+}
+
+func (h *Hub) RequireAuth() bool {
+	return h.config.RequireAuth
+}
+
+// Requests without Origin header are not from browsers and always allowed
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+
+	if origin == "" || slices.Contains(h.config.AllowedOrigins, "*") {
+		return true
+	}
+
+	if len(h.config.AllowedOrigins) == 0 {
+		parsed, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(parsed.Host, r.Host)
+	}
+
+	return slices.ContainsFunc(h.config.AllowedOrigins, func(allowed string) bool {
+		return strings.EqualFold(allowed, origin)
+	})
+}
+
+// Websocket route handler e.g: GET /ws/:namespace, messages are dispatched to event handlers, see On
+func (h *Hub) Handle(c echo.Context) error {
 	namespace := c.Param("namespace")
-	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+
+	if h.Reserved(namespace) {
+		return echo.NewHTTPError(http.StatusForbidden, "Namespace is reserved.")
+	}
+
+	return h.connect(c, namespace, false, nil)
+}
+
+// Upgrade request into read only client of namespace, see Broadcast
+func (h *Hub) Subscribe(c echo.Context, namespace string) error {
+	return h.connect(c, namespace, true, nil)
+}
+
+// Upgrade request into read only client of namespace receiving broadcasts accepted by filter
+func (h *Hub) SubscribeFiltered(c echo.Context, namespace string, filter func(msg WSMessage) bool) error {
+	return h.connect(c, namespace, true, filter)
+}
+
+// Reserve namespaces matching pattern (path.Match syntax) e.g: "users/*/apps", clients of /ws, /socket.io/ and /events are refused, only Subscribe and SubscribeFiltered join them
+func (h *Hub) Reserve(pattern string) {
+	h.reservedMutex.Lock()
+	defer h.reservedMutex.Unlock()
+
+	h.reserved = append(h.reserved, pattern)
+}
+
+// Whether namespace is reserved, see Reserve
+func (h *Hub) Reserved(namespace string) bool {
+	h.reservedMutex.RLock()
+	defer h.reservedMutex.RUnlock()
+
+	for _, pattern := range h.reserved {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Hub) connect(c echo.Context, namespace string, readOnly bool, filter func(msg WSMessage) bool) error {
+	h.mutex.RLock()
+	closed := h.closed
+	h.mutex.RUnlock()
+
+	if closed {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Server is shutting down.")
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		log.Println("WebSocket upgrade failed:", err)
-		return err
+		return nil
 	}
 
+	user, _ := c.Get("auth").(*models.User)
+
 	client := &Client{
+		hub:       h,
 		conn:      conn,
 		namespace: namespace,
-		send:      make(chan WSMessage),
+		user:      user,
+		rooms:     make(map[string]bool),
+		send:      make(chan WSMessage, h.config.QueueSize),
+		readOnly:  readOnly,
+		filter:    filter,
+		done:      make(chan struct{}),
 	}
 
-	registerClient(client)
+	if !h.register(client, 2) {
+		conn.Close()
+		return nil
+	}
 
-	go readPump(client)
-	go writePump(client)
+	go client.readPump()
+	go client.writePump()
 
 	return nil
 }
 
-func registerClient(client *Client) {
-	clientsMutex.Lock()
-	defer clientsMutex.Unlock()
+// Add client running pumps goroutines, counted while holding the lock so Shutdown never waits before they are
+func (h *Hub) register(client *Client, pumps int) bool {
+	h.mutex.Lock()
 
-	if clients[client.namespace] == nil {
-		clients[client.namespace] = make(map[*Client]bool)
+	if h.closed {
+		h.mutex.Unlock()
+		return false
 	}
-	clients[client.namespace][client] = true
+
+	first := h.isFirstConnection(client, "")
+	h.addClient(client)
+	h.pumps.Add(pumps)
+
+	h.mutex.Unlock()
+
+	if first {
+		h.emitPresence(client, "", PresenceJoinEvent)
+	}
+
+	return true
+}
+
+// Caller holds the lock
+func (h *Hub) addClient(client *Client) {
+	if h.clients[client.namespace] == nil {
+		h.clients[client.namespace] = make(map[*Client]bool)
+	}
+	h.clients[client.namespace][client] = true
 	log.Printf("Client connected to namespace: %s\n", client.namespace)
 }
 
-func unregisterClient(client *Client) {
-	clientsMutex.Lock()
-	defer clientsMutex.Unlock()
+func (h *Hub) unregister(client *Client) {
+	h.mutex.Lock()
 
-	if clients[client.namespace] != nil {
-		delete(clients[client.namespace], client)
-		if len(clients[client.namespace]) == 0 {
-			delete(clients, client.namespace)
-		}
+	if h.clients[client.namespace] == nil || !h.clients[client.namespace][client] {
+		h.mutex.Unlock()
+		return
+	}
+
+	delete(h.clients[client.namespace], client)
+	if len(h.clients[client.namespace]) == 0 {
+		delete(h.clients, client.namespace)
 	}
 	log.Printf("Client disconnected from namespace: %s\n", client.namespace)
-	client.conn.Close()
+
+	// Rooms the user is no longer connected to, the namespace included
+	var left []string
+	for _, room := range append(slices.Collect(maps.Keys(client.rooms)), "") {
+		if h.isFirstConnection(client, room) {
+			left = append(left, room)
+		}
+	}
+
+	h.mutex.Unlock()
+
+	for _, room := range left {
+		h.emitPresence(client, room, PresenceLeaveEvent)
+	}
+}
+
+// Send message to every client of its namespace, or of its room when set
+func (h *Hub) Broadcast(msg WSMessage) {
+	h.broadcast(msg, nil)
 }
 
-func readPump(client *Client) {
-	defer unregisterClient(client)
+// Deliver message to clients of this replica and relay it to the other ones
+func (h *Hub) broadcast(msg WSMessage, sender *Client) {
+	h.deliver(msg, sender)
+	h.relay(BackplaneMessage{Message: msg, Meta: msg.Meta})
+}
 
-	for {
-		_, msg, err := client.conn.ReadMessage()
-		if err != nil {
-			log.Println("read error:", err)
-			break
+// Queue message for clients of namespace without waiting on any of them, kept for SSE clients resuming later
+func (h *Hub) deliver(msg WSMessage, sender *Client) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	msg = h.replay.add(msg)
+
+	for client := range h.clients[msg.Namespace] {
+		if client != sender && (msg.Room == "" || client.rooms[msg.Room]) && (client.filter == nil || client.filter(msg)) {
+			client.enqueue(msg)
 		}
+	}
+}
 
-		var wsMsg WSMessage
-		if err := json.Unmarshal(msg, &wsMsg); err != nil {
-			log.Println("invalid message format:", err)
-			continue
+// Disconnect every client with going away close frame and wait for them to finish, new connections are refused
+func (h *Hub) Shutdown(ctx context.Context) error {
+	if h.stopBackplane != nil {
+		h.stopBackplane()
+	}
+
+	h.mutex.Lock()
+	h.closed = true
+
+	var clients []*Client
+	for _, namespaceClients := range h.clients {
+		for client := range namespaceClients {
+			clients = append(clients, client)
 		}
+	}
+
+	sessions := slices.Collect(maps.Values(h.sessions))
+	h.mutex.Unlock()
+
+	for _, client := range clients {
+		client.close()
+	}
 
-		log.Printf("[%s] %s: %s", wsMsg.Namespace, wsMsg.Event, wsMsg.Payload)
+	for _, session := range sessions {
+		session.close()
+	}
+
+	finished := make(chan struct{})
+
+	go func() {
+		h.pumps.Wait()
+		close(finished)
+	}()
 
-		// Echo back to sender
-		client.send <- WSMessage{
-			Namespace: wsMsg.Namespace,
-			Event:     "echo",
-			Payload:   fmt.Sprintf("You said: %s", wsMsg.Payload),
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("websocket hub shutdown: %w", ctx.Err())
+	}
+}
+
+// Authenticated user of client, nil for anonymous clients
+func (c *Client) User() *models.User {
+	return c.user
+}
+
+func (c *Client) enqueue(msg WSMessage) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	default:
+		if c.hub.config.SlowClientPolicy == DropMessages {
+			log.Printf("Message dropped for slow client of namespace: %s\n", c.namespace)
+			return
 		}
 
-		// Broadcast to others
-		broadcastToNamespace(wsMsg, client)
+		log.Printf("Slow client of namespace disconnected: %s\n", c.namespace)
+		c.close()
 	}
 }
 
-func writePump(client *Client) {
-	for msg := range client.send {
-		if err := client.conn.WriteJSON(msg); err != nil {
-			log.Println("write error:", err)
+// Stop pumps, writePump says goodbye with a close frame before closing the connection
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *Client) readPump() {
+	defer c.hub.pumps.Done()
+	defer func() {
+		c.hub.unregister(c)
+		c.close()
+	}()
+
+	c.conn.SetReadLimit(c.hub.config.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.config.PongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.config.PongTimeout))
+	})
+
+	for {
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("read error:", err)
+			}
 			break
 		}
+
+		c.conn.SetReadDeadline(time.Now().Add(c.hub.config.PongTimeout))
+
+		if c.readOnly {
+			continue
+		}
+
+		var wsMsg WSMessage
+		if err := json.Unmarshal(msg, &wsMsg); err != nil || wsMsg.Event == "" {
+			c.enqueue(WSMessage{Namespace: c.namespace, Event: ErrorEvent, Error: &WSError{Code: http.StatusBadRequest, Message: "Invalid message format."}})
+			continue
+		}
+
+		// Clients only talk within the namespace they connected to
+		wsMsg.Namespace = c.namespace
+
+		c.hub.dispatch(c, wsMsg)
 	}
 }
 
-func broadcastToNamespace(msg WSMessage, sender *Client) {
-	clientsMutex.Lock()
-	defer clientsMutex.Unlock()
+func (c *Client) writePump() {
+	ticker := time.NewTicker(c.hub.config.PingInterval)
 
-	for client := range clients[msg.Namespace] {
-		if client != sender {
-			client.send <- msg
+	defer c.hub.pumps.Done()
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteTimeout))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				log.Println("write error:", err)
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.hub.config.WriteTimeout)); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(c.hub.config.WriteTimeout))
+			return
 		}
 	}
 }
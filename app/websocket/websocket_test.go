@@ -0,0 +1,327 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmadfirdaus06/go-boilerplate-app/app/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const testTimeout = 2 * time.Second
+
+// User authenticated by X-User header, its id is derived from the username so connections of the same user share it
+func testUser(username string) *models.User {
+	var id bson.ObjectID
+	copy(id[:], username)
+
+	return &models.User{ID: id, Username: username}
+}
+
+// Hub with its routes served by test server, X-User header authenticates requests
+func newTestHub(t *testing.T, config HubConfig) (*Hub, *httptest.Server) {
+	t.Helper()
+
+	hub := NewHub(config)
+
+	if err := hub.ConnectRaw(); err != nil {
+		t.Fatalf("connect hub: %v", err)
+	}
+
+	e := echo.New()
+
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if username := c.Request().Header.Get("X-User"); username != "" {
+				c.Set("auth", testUser(username))
+			}
+
+			return next(c)
+		}
+	})
+
+	e.GET("/ws/:namespace", hub.Handle)
+	e.GET("/ws/:namespace/members", hub.HandleMembers)
+	e.GET("/ws/:namespace/rooms/:room/members", hub.HandleMembers)
+	e.GET("/events", hub.HandleEvents)
+	e.Match([]string{http.MethodGet, http.MethodPost}, "/socket.io/", hub.HandleSocketIO)
+
+	// Subscribers only receive broadcasts of events other than hidden
+	e.GET("/subscribe/:namespace", func(c echo.Context) error {
+		return hub.SubscribeFiltered(c, c.Param("namespace"), func(msg WSMessage) bool {
+			return msg.Event != "hidden"
+		})
+	})
+
+	server := httptest.NewServer(e)
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+		defer cancel()
+
+		hub.Shutdown(ctx)
+		server.Close()
+	})
+
+	return hub, server
+}
+
+// Websocket connection to path of server, as user when set
+func dialTest(t *testing.T, server *httptest.Server, path string, username string) *websocket.Conn {
+	t.Helper()
+
+	header := http.Header{}
+	if username != "" {
+		header.Set("X-User", username)
+	}
+
+	conn, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+path, header)
+
+	if err != nil {
+		status := 0
+		if res != nil {
+			status = res.StatusCode
+		}
+
+		t.Fatalf("dial %s: got status %d %v", path, status, err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// Next message sent to conn, fails the test when none arrives in time
+func readTest(t *testing.T, conn *websocket.Conn) WSMessage {
+	t.Helper()
+
+	var msg WSMessage
+
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	return msg
+}
+
+// Wait until namespace has count clients, connections are registered after the handshake is answered
+func waitClients(t *testing.T, hub *Hub, namespace string, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+
+	for {
+		hub.mutex.RLock()
+		got := len(hub.clients[namespace])
+		hub.mutex.RUnlock()
+
+		if got == count {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("namespace %s: got %d clients, want %d", namespace, got, count)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHubOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  func(server *httptest.Server) string
+		wantOK  bool
+	}{
+		{"not a browser", nil, func(*httptest.Server) string { return "" }, true},
+		{"same host by default", nil, func(server *httptest.Server) string { return server.URL }, true},
+		{"other host by default", nil, func(*httptest.Server) string { return "https://evil.example.com" }, false},
+		{"allowed origin", []string{"https://app.example.com"}, func(*httptest.Server) string { return "https://APP.example.com" }, true},
+		{"origin not allowed", []string{"https://app.example.com"}, func(server *httptest.Server) string { return server.URL }, false},
+		{"any origin", []string{"*"}, func(*httptest.Server) string { return "https://evil.example.com" }, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, server := newTestHub(t, HubConfig{AllowedOrigins: test.allowed})
+
+			header := http.Header{}
+			if origin := test.origin(server); origin != "" {
+				header.Set("Origin", origin)
+			}
+
+			conn, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/chat", header)
+
+			if conn != nil {
+				conn.Close()
+			}
+
+			if ok := err == nil; ok != test.wantOK {
+				t.Fatalf("got connected %v %v, want %v", ok, err, test.wantOK)
+			}
+
+			if !test.wantOK && res.StatusCode != http.StatusForbidden {
+				t.Errorf("got status %d, want 403", res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHubBroadcast(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{})
+
+	chat := dialTest(t, server, "/ws/chat", "")
+	other := dialTest(t, server, "/ws/other", "")
+	waitClients(t, hub, "chat", 1)
+	waitClients(t, hub, "other", 1)
+
+	hub.Broadcast(WSMessage{Namespace: "chat", Event: "news"})
+	hub.Broadcast(WSMessage{Namespace: "other", Event: "weather"})
+
+	if msg := readTest(t, chat); msg.Namespace != "chat" || msg.Event != "news" {
+		t.Errorf("chat: got %+v, want news of chat", msg)
+	}
+
+	if msg := readTest(t, other); msg.Event != "weather" {
+		t.Errorf("other: got %+v, want weather, broadcast of chat must not reach it", msg)
+	}
+}
+
+func TestClientEnqueue(t *testing.T) {
+	tests := []struct {
+		policy     SlowClientPolicy
+		wantClosed bool
+	}{
+		{DropMessages, false},
+		{Disconnect, true},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.policy), func(t *testing.T) {
+			hub := NewHub(HubConfig{QueueSize: 1, SlowClientPolicy: test.policy})
+			client := &Client{hub: hub, send: make(chan WSMessage, 1), done: make(chan struct{})}
+
+			client.enqueue(WSMessage{Event: "first"})
+			client.enqueue(WSMessage{Event: "second"})
+
+			select {
+			case <-client.done:
+				if !test.wantClosed {
+					t.Error("slow client was disconnected, want messages dropped")
+				}
+			default:
+				if test.wantClosed {
+					t.Error("slow client is still connected, want it disconnected")
+				}
+			}
+
+			if msg := <-client.send; msg.Event != "first" {
+				t.Errorf("queued: got %s, want first", msg.Event)
+			}
+		})
+	}
+}
+
+func TestHubHeartbeat(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{PingInterval: 20 * time.Millisecond, PongTimeout: 100 * time.Millisecond})
+
+	pings := make(chan struct{}, 10)
+
+	alive := dialTest(t, server, "/ws/chat", "")
+	alive.SetPingHandler(func(data string) error {
+		select {
+		case pings <- struct{}{}:
+		default:
+		}
+
+		return alive.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(testTimeout))
+	})
+
+	// Reading answers pings, keeping the connection alive
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	dialTest(t, server, "/ws/chat", "")
+
+	waitClients(t, hub, "chat", 2)
+
+	select {
+	case <-pings:
+	case <-time.After(testTimeout):
+		t.Fatal("no ping received")
+	}
+
+	// Connection never answering pings is dropped after PongTimeout, the one answering stays
+	waitClients(t, hub, "chat", 1)
+
+	time.Sleep(200 * time.Millisecond)
+
+	waitClients(t, hub, "chat", 1)
+}
+
+func TestHubReservedNamespaces(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{})
+	hub.Reserve("private-*")
+
+	_, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/private-1", nil)
+
+	if err == nil || res.StatusCode != http.StatusForbidden {
+		t.Fatalf("connect to reserved namespace: got %v, want 403", err)
+	}
+
+	subscriber := dialTest(t, server, "/subscribe/private-1", "")
+	waitClients(t, hub, "private-1", 1)
+
+	hub.Broadcast(WSMessage{Namespace: "private-1", Event: "hidden"})
+	hub.Broadcast(WSMessage{Namespace: "private-1", Event: "shown"})
+
+	if msg := readTest(t, subscriber); msg.Event != "shown" {
+		t.Errorf("subscriber: got %s, want shown as hidden is filtered out", msg.Event)
+	}
+
+	// Messages sent by subscribers are ignored
+	subscriber.WriteJSON(WSMessage{Event: "room.join", ID: "1", Payload: []byte(`{"room":"a"}`)})
+	hub.Broadcast(WSMessage{Namespace: "private-1", Event: "after"})
+
+	if msg := readTest(t, subscriber); msg.Event != "after" {
+		t.Errorf("subscriber: got %s, want after without ack of its own message", msg.Event)
+	}
+}
+
+func TestHubShutdown(t *testing.T) {
+	hub, server := newTestHub(t, HubConfig{})
+
+	conn := dialTest(t, server, "/ws/chat", "")
+	waitClients(t, hub, "chat", 1)
+
+	if err := hub.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("got %v, want going away close frame", err)
+	}
+
+	_, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws/chat", nil)
+
+	if err == nil || res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("connect after shutdown: got %v, want 503", err)
+	}
+}
@@ -64,9 +64,11 @@ func main() {
 
 	// basic app config including note crud routing and registered externals
 	config := &app.HttpAppConfig{
-		Routes:    InitNoteRoutes,
-		Externals: externals,
+		Routes:      InitNoteRoutes,
+		Externals:   externals,
+		SyncIndexes: true,
 	}
 
-	app.InitHttpApp(config)
+	// serve http app, or run a command e.g: `go run ./examples/note-app indexes sync`
+	app.Execute(config)
 }